@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/kanta/backend-challenge/config"
+	"github.com/kanta/backend-challenge/infrastructure"
+	"github.com/kanta/backend-challenge/internal/adapters/repositories"
+	"github.com/kanta/backend-challenge/internal/adapters/signer"
+	"github.com/kanta/backend-challenge/internal/core/ports"
+	"github.com/kanta/backend-challenge/internal/core/services"
+)
+
+// Usage: go run ./cmd/audit-verify -public-key audit-checkpoint.pub [-partition 2025-01]
+//
+// Walks the audit log hash chain of every partition (or only -partition),
+// checks each checkpoint and purge signature and reports the first broken link per
+// partition. Exits with status 1 when any chain is broken.
+func main() {
+	publicKey := flag.String("public-key", "", "PEM Ed25519 public key that signed the checkpoints")
+	partition := flag.String("partition", "", "verify only this partition (YYYY-MM)")
+	flag.Parse()
+
+	config.Load()
+
+	var verifier ports.CheckpointSigner
+	var err error
+	switch {
+	case *publicKey != "":
+		verifier, err = signer.NewEd25519Verifier(*publicKey)
+	case config.Get().Audit.CheckpointKeyFile != "":
+		verifier, err = signer.NewEd25519Signer(config.Get().Audit.CheckpointKeyFile)
+	default:
+		log.Print("no public key given, checkpoint signatures are not checked")
+	}
+	if err != nil {
+		log.Fatalf("failed to load checkpoint key: %v", err)
+	}
+
+	psqlConfig := config.Get().Psql
+	db := infrastructure.NewReadOnlyPostgresClient(psqlConfig.Host, psqlConfig.User, psqlConfig.Pass, psqlConfig.DB, psqlConfig.Port)
+	sqlDB, err := db.DB()
+	if err != nil {
+		log.Fatalf("failed to get sql.DB: %v", err)
+	}
+	defer sqlDB.Close()
+
+	repo := repositories.NewAuditRepository(db)
+	auditService := services.NewAuditService(repo, verifier, nil, 0)
+
+	partitions := []string{*partition}
+	if *partition == "" {
+		if partitions, err = repo.Partitions(); err != nil {
+			log.Fatalf("failed to list partitions: %v", err)
+		}
+	}
+
+	broken := false
+	for _, p := range partitions {
+		result, err := auditService.Verify(context.Background(), p)
+		if err != nil {
+			log.Fatalf("failed to verify partition %s: %v", p, err)
+		}
+		if result.Break == nil {
+			fmt.Printf("%s: ok, %d entries, %d checkpoints", p, result.Entries, result.Checkpoints)
+			if result.Purge != nil {
+				fmt.Printf(", purged up to %d on %s", result.Purge.Sequence, result.Purge.PurgedAt.Format(time.DateOnly))
+			}
+			fmt.Println()
+			continue
+		}
+		broken = true
+		fmt.Printf("%s: BROKEN at sequence %d", p, result.Break.Sequence)
+		if result.Break.EntryID != "" {
+			fmt.Printf(" (entry %s)", result.Break.EntryID)
+		}
+		fmt.Printf(": %s\n", result.Break.Reason)
+	}
+
+	if broken {
+		os.Exit(1)
+	}
+}
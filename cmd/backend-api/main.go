@@ -2,8 +2,12 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"log"
+	"net/netip"
 	"os"
 	"os/signal"
 	"syscall"
@@ -15,9 +19,21 @@ import (
 	"github.com/kanta/backend-challenge/config"
 	"github.com/kanta/backend-challenge/docs"
 	"github.com/kanta/backend-challenge/infrastructure"
+	"github.com/kanta/backend-challenge/internal/adapters/archive"
+	"github.com/kanta/backend-challenge/internal/adapters/breach"
 	cache "github.com/kanta/backend-challenge/internal/adapters/cache"
+	"github.com/kanta/backend-challenge/internal/adapters/challenge"
+	"github.com/kanta/backend-challenge/internal/adapters/geoip"
 	handlers "github.com/kanta/backend-challenge/internal/adapters/handlers/backend-handler"
+	"github.com/kanta/backend-challenge/internal/adapters/hasher"
+	"github.com/kanta/backend-challenge/internal/adapters/mailer"
+	"github.com/kanta/backend-challenge/internal/adapters/producers"
 	"github.com/kanta/backend-challenge/internal/adapters/repositories"
+	"github.com/kanta/backend-challenge/internal/adapters/reputation"
+	"github.com/kanta/backend-challenge/internal/adapters/siem"
+	"github.com/kanta/backend-challenge/internal/adapters/signer"
+	"github.com/kanta/backend-challenge/internal/adapters/webhook"
+	"github.com/kanta/backend-challenge/internal/core/domain"
 	"github.com/kanta/backend-challenge/internal/core/ports"
 	"github.com/kanta/backend-challenge/internal/core/services"
 	"github.com/kanta/backend-challenge/middlewares"
@@ -28,7 +44,7 @@ import (
 // @securityDefinitions.apikey BearerAuth
 // @in header
 // @name Authorization
-func newRouter(handler handlers.BackEndHandler, cache ports.CachePort) *fiber.App {
+func newRouter(handler handlers.BackEndHandler, tokens *infrastructure.TokenManager, limiter ports.RateLimiterPort, cookies middlewares.CookieConfig, dpop *infrastructure.DPoPVerifier, requireChallenge fiber.Handler) *fiber.App {
 	app := fiber.New()
 	app.Use(middlewares.Logger())
 	docs.SwaggerInfo.Schemes = []string{"http"}
@@ -40,9 +56,13 @@ func newRouter(handler handlers.BackEndHandler, cache ports.CachePort) *fiber.Ap
 		return swagHandler(c)
 	})
 
+	allowOrigins := config.Get().CORS.AllowOrigins
 	app.Use(cors.New(cors.Config{
-		AllowOrigins: "*",
-		AllowHeaders: "Origin, Content-Type, Accept, Authorization",
+		AllowOrigins:  allowOrigins,
+		AllowHeaders:  "Origin, Content-Type, Accept, Authorization, X-Device-ID, DPoP, " + cookies.CSRFHeader,
+		ExposeHeaders: "RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset, Retry-After, DPoP-Nonce, WWW-Authenticate",
+		// Browsers only send cookies cross-origin to an explicit origin list.
+		AllowCredentials: cookies.Enabled && allowOrigins != "*",
 	}))
 	app.Get("/health", func(c *fiber.Ctx) error {
 		return c.SendString("server is running")
@@ -50,16 +70,66 @@ func newRouter(handler handlers.BackEndHandler, cache ports.CachePort) *fiber.Ap
 
 	v1 := app.Group("/api/v1")
 
-	v1.Post("/auth/register", handler.Register)
-	v1.Post("/auth/login", handler.Login)
-	v1.Post("/auth/refresh", handler.RefreshToken)
+	rateLimitConfig := config.Get().RateLimit
+	authLimit := rateLimit(limiter, middlewares.RateLimitConfig{
+		Name:     "auth",
+		Limit:    rateLimitConfig.AuthLimit,
+		Window:   rateLimitConfig.AuthWindow,
+		KeyFunc:  middlewares.RateLimitByIP,
+		FailOpen: rateLimitConfig.FailOpen,
+	})
+	apiLimit := rateLimit(limiter, middlewares.RateLimitConfig{
+		Name:     "api",
+		Limit:    rateLimitConfig.APILimit,
+		Window:   rateLimitConfig.APIWindow,
+		KeyFunc:  middlewares.RateLimitByUser,
+		FailOpen: rateLimitConfig.FailOpen,
+	})
 
-	protected := v1.Group("", middlewares.JWTAuth(config.Get().JWT_Secret, cache))
+	v1.Post("/auth/register", authLimit, requireChallenge, handler.Register)
+	v1.Post("/auth/login", authLimit, requireChallenge, handler.Login)
+	v1.Post("/auth/refresh", authLimit, middlewares.CSRF(cookies), handler.RefreshToken)
+	v1.Post("/auth/token", authLimit, handler.IssueServiceToken)
+	v1.Post("/auth/password/forgot", authLimit, handler.ForgotPassword)
+	v1.Post("/auth/password/reset", authLimit, handler.ResetPassword)
+	// Downloads are authorised by the signed link instead of a token.
+	v1.Get("/exports/:id/download", apiLimit, handler.DownloadDataExport)
+
+	protected := v1.Group("", middlewares.JWTAuth(tokens, cookies, dpop), middlewares.CSRF(cookies), apiLimit)
 	protected.Get("/users/me", handler.GetMyProfile)
+	protected.Get("/users/me/devices", handler.ListDevices)
+	protected.Delete("/users/me/devices/:id", handler.ForgetDevice)
+	protected.Post("/users/me/export", handler.RequestDataExport)
+	protected.Get("/users/me/export/:id", handler.GetDataExport)
 	protected.Post("/auth/logout", handler.Logout)
+	protected.Post("/auth/logout-all", handler.LogoutEverywhere)
+	protected.Post("/auth/reauthenticate", handler.Reauthenticate)
+
+	recentAuth := middlewares.RequireRecentAuth(config.Get().StepUp.MaxAge)
+	protected.Post("/users/me/password", recentAuth, handler.ChangePassword)
+
+	admin := protected.Group("/admin", middlewares.RequireRole(domain.RoleAdmin))
+	admin.Post("/users/:id/unlock", handler.UnlockUser)
+	admin.Post("/users/:id/revoke-sessions", handler.RevokeUserSessions)
+	admin.Get("/audit", handler.QueryAuditLog)
+	admin.Get("/audit/export", handler.ExportAuditLog)
+	admin.Get("/webhooks", handler.ListWebhooks)
+	admin.Post("/webhooks", handler.CreateWebhook)
+	admin.Get("/webhooks/:id", handler.GetWebhook)
+	admin.Patch("/webhooks/:id", handler.UpdateWebhook)
+	admin.Delete("/webhooks/:id", handler.DeleteWebhook)
+	admin.Get("/webhooks/:id/deliveries", handler.ListWebhookDeliveries)
+	admin.Post("/webhooks/:id/deliveries/:deliveryId/redeliver", handler.RedeliverWebhook)
 	return app
 }
 
+func rateLimit(limiter ports.RateLimiterPort, cfg middlewares.RateLimitConfig) fiber.Handler {
+	if !config.Get().RateLimit.Enabled {
+		return func(c *fiber.Ctx) error { return c.Next() }
+	}
+	return middlewares.RateLimit(limiter, cfg)
+}
+
 func main() {
 	config.Load()
 
@@ -89,20 +159,428 @@ func main() {
 	redisClient := infrastructure.NewRedisClient(redisConfig.Addr, redisConfig.Password, redisConfig.DB)
 
 	userRepo := repositories.NewUserRepository(db)
+	deviceRepo := repositories.NewDeviceRepository(db)
+	auditRepo := repositories.NewAuditRepository(db)
 	tokenCache := cache.NewTokenCache(redisClient)
+	sessionCache := cache.NewSessionCache(redisClient)
+	loginAttempts := cache.NewLoginAttemptCache(redisClient)
+	eventProducer, closeProducer := newEventProducer(config.Get().Events)
+	defer closeProducer()
+	sinks := []ports.OutboxSink{{Name: "events", Producer: producers.NewFilterProducer(eventProducer, domain.PublishedEventTypes)}}
+
+	// forwarder stays a nil interface when syslog forwarding is off.
+	var forwarder ports.SecurityEventForwarder
+	if syslogConfig := config.Get().Syslog; syslogConfig.Enabled {
+		syslogForwarder := newSyslogForwarder(syslogConfig)
+		forwarderCtx, stopForwarder := context.WithCancel(context.Background())
+		defer stopForwarder()
+		go syslogForwarder.Run(forwarderCtx)
+
+		forwarder = syslogForwarder
+		sinks = append(sinks, ports.OutboxSink{Name: "syslog", Producer: syslogForwarder})
+	}
+
+	webhookConfig := config.Get().Webhook
+	webhookService := services.NewWebhookService(
+		repositories.NewWebhookRepository(db),
+		webhook.NewHTTPSender(webhookConfig.Timeout, parsePrefixes(webhookConfig.AllowedCIDRs)),
+		domain.WebhookPolicy{
+			AllowHTTP:      webhookConfig.AllowHTTP,
+			MaxAttempts:    webhookConfig.MaxAttempts,
+			BackoffBase:    webhookConfig.BackoffBase,
+			BackoffMax:     webhookConfig.BackoffMax,
+			PollInterval:   webhookConfig.PollInterval,
+			RequestTimeout: webhookConfig.Timeout,
+			BatchSize:      webhookConfig.BatchSize,
+		},
+	)
+	webhookCtx, stopWebhooks := context.WithCancel(context.Background())
+	defer stopWebhooks()
+	go webhookService.RunDispatcher(webhookCtx)
+	sinks = append(sinks, ports.OutboxSink{Name: "webhooks", Producer: webhookService})
+
+	challengeConfig := config.Get().Challenge
+	// The login risk check asks for solutions even with challenges off.
+	challengesInUse := challengeConfig.Enabled || config.Get().Risk.ChallengeScore > 0
+	challengeService := services.NewChallengeService(loginAttempts, tokenCache, newChallengeVerifier(challengeConfig, challengesInUse, cache.NewReplayCache(redisClient, "challenge:pow:")), domain.ChallengePolicy{
+		IPFailureThreshold: challengeConfig.IPFailureThreshold,
+		NewRange:           challengeConfig.NewRange,
+		RangeMemory:        challengeConfig.RangeMemory,
+	})
+	if challengeConfig.NewRange {
+		sinks = append(sinks, ports.OutboxSink{Name: "challenges", Producer: challengeService})
+	}
+
+	// The service writes events to the outbox; the relay hands them on to
+	// every sink.
+	outboxRepo := repositories.NewOutboxRepository(db)
+	outboxConfig := config.Get().Outbox
+	outboxRelay := services.NewOutboxRelay(outboxRepo, sinks, domain.OutboxPolicy{
+		PollInterval:    outboxConfig.PollInterval,
+		BatchSize:       outboxConfig.BatchSize,
+		Lease:           outboxConfig.Lease,
+		BackoffBase:     outboxConfig.BackoffBase,
+		BackoffMax:      outboxConfig.BackoffMax,
+		Retention:       outboxConfig.Retention,
+		CleanupInterval: outboxConfig.CleanupInterval,
+	})
+	relayCtx, stopRelay := context.WithCancel(context.Background())
+	defer stopRelay()
+	go outboxRelay.Run(relayCtx)
+	producer := producers.NewOutboxProducer(outboxRepo)
+
+	passwordConfig := config.Get().Password
+	breachedChecker := breach.NewFileChecker(passwordConfig.BreachDir)
+
+	lockoutConfig := config.Get().Lockout
+	lockoutPolicy := domain.LockoutPolicy{
+		MaxAccountFailures: lockoutConfig.MaxAccountFailures,
+		MaxIPFailures:      lockoutConfig.MaxIPFailures,
+		FailureWindow:      lockoutConfig.FailureWindow,
+		Duration:           lockoutConfig.Duration,
+		DelayBase:          lockoutConfig.DelayBase,
+		DelayMax:           lockoutConfig.DelayMax,
+	}
+	passwordPolicy := domain.PasswordPolicy{
+		MinLength:      passwordConfig.MinLength,
+		MaxBytes:       passwordConfig.MaxBytes,
+		RequireUpper:   passwordConfig.RequireUpper,
+		RequireLower:   passwordConfig.RequireLower,
+		RequireDigit:   passwordConfig.RequireDigit,
+		RequireSymbol:  passwordConfig.RequireSymbol,
+		RejectPersonal: passwordConfig.RejectPersonal,
+		CheckBreached:  passwordConfig.BreachDir != "",
+		ResetTokenTTL:  passwordConfig.ResetTokenTTL,
+	}
+
+	passwordHasher := newPasswordHasher(config.Get().Hasher)
+
+	riskConfig := config.Get().Risk
+	// There is no second factor to ask for yet.
+	if riskConfig.MFAScore > 0 {
+		log.Fatal("RISK_MFA_SCORE is not supported until MFA exists; set it to 0")
+	}
+	geo, err := geoip.NewMaxMind(riskConfig.GeoIPDatabase, riskConfig.GeoIPASNDatabase)
+	if err != nil {
+		log.Fatalf("failed to open GeoIP database: %v", err)
+	}
+	defer geo.Close()
+	reputationLists, err := reputation.ParseLists(riskConfig.ReputationLists, riskConfig.ReputationDefaultScore)
+	if err != nil {
+		log.Fatalf("invalid reputation lists: %v", err)
+	}
+	ipReputation, err := reputation.NewLists(reputationLists)
+	if err != nil {
+		log.Fatalf("failed to load reputation lists: %v", err)
+	}
+	reputationCtx, stopReputation := context.WithCancel(context.Background())
+	defer stopReputation()
+	go ipReputation.Run(reputationCtx, riskConfig.ReputationReloadInterval)
+	loginHistory := repositories.NewLoginHistoryRepository(db)
+	riskEngine := services.NewRiskEngine(geo, ipReputation, loginHistory, domain.RiskPolicy{
+		NewCountryScore:       riskConfig.NewCountryScore,
+		NewASNScore:           riskConfig.NewASNScore,
+		ImpossibleTravelScore: riskConfig.ImpossibleTravelScore,
+		ImpossibleTravelKmh:   riskConfig.ImpossibleTravelKmh,
+		ImpossibleTravelMinKm: riskConfig.ImpossibleTravelMinKm,
+		HistorySize:           riskConfig.HistorySize,
+		AlertScore:            riskConfig.AlertScore,
+		ChallengeScore:        riskConfig.ChallengeScore,
+		BlockScore:            riskConfig.BlockScore,
+	})
+
+	validationConfig := config.Get().Validation
+	revocationBus := cache.NewRevocationBus(redisClient, validationConfig.RevocationChannel)
+	service := services.NewBackEndService(userRepo, repositories.NewTransactor(db), deviceRepo, tokenCache, revocationBus, loginAttempts, producer, newMailer(config.Get().Mail, passwordConfig.ResetURL), breachedChecker, passwordHasher, riskEngine, lockoutPolicy, passwordPolicy)
+	auditConfig := config.Get().Audit
+	var checkpointSigner ports.CheckpointSigner
+	if auditConfig.CheckpointKeyFile != "" {
+		if checkpointSigner, err = signer.NewEd25519Signer(auditConfig.CheckpointKeyFile); err != nil {
+			log.Fatalf("failed to load audit checkpoint key: %v", err)
+		}
+	}
+	// A purge leaves a signed record in place of the deleted entries, so
+	// the remaining chain still verifies.
+	if auditConfig.Retention > 0 && checkpointSigner == nil {
+		log.Fatal("AUDIT_RETENTION requires AUDIT_CHECKPOINT_KEY_FILE")
+	}
+	auditService := services.NewAuditService(auditRepo, checkpointSigner, forwarder, auditConfig.QueueSize)
+	// The audit writer is stopped after the server, and waited for, so that
+	// queued entries are written before exiting.
+	auditWriterCtx, stopAuditWriter := context.WithCancel(context.Background())
+	auditWriterDone := make(chan struct{})
+	go func() {
+		defer close(auditWriterDone)
+		auditService.Run(auditWriterCtx)
+	}()
+	checkpointCtx, stopCheckpoints := context.WithCancel(context.Background())
+	defer stopCheckpoints()
+	go auditService.RunCheckpoints(checkpointCtx, auditConfig.CheckpointInterval)
+	go auditService.RunRetention(checkpointCtx, auditConfig.Retention, time.Hour)
+	sessionConfig := config.Get().Session
+	tokenPolicies, err := infrastructure.LoadTokenPolicies(sessionConfig.TokenPolicyFile, domain.SessionPolicy{
+		AccessTokenTTL:  sessionConfig.AccessTokenTTL,
+		IdleTimeout:     sessionConfig.IdleTimeout,
+		AbsoluteTimeout: sessionConfig.AbsoluteTimeout,
+	}, sessionConfig.Audience)
+	if err != nil {
+		log.Fatalf("failed to load token policies: %v", err)
+	}
+	switch validationConfig.RevocationMode {
+	case infrastructure.RevocationAllowlist, infrastructure.RevocationDenylist:
+	default:
+		log.Fatalf("unknown token revocation mode %q", validationConfig.RevocationMode)
+	}
+	tokenManager := infrastructure.NewTokenManager(
+		config.Get().JWT_Secret,
+		validationConfig.RevocationMode,
+		validationConfig.AcceptUnhashed,
+		tokenCache,
+		cache.NewTokenDenylist(redisClient),
+		sessionCache,
+		tokenPolicies,
+		service,
+		infrastructure.NewValidationCache(validationConfig.CacheSize, validationConfig.CacheTTL),
+		revocationBus,
+	)
+	revocationCtx, stopRevocations := context.WithCancel(context.Background())
+	defer stopRevocations()
+	go tokenManager.ListenForRevocations(revocationCtx)
+	cookieConfig := config.Get().Cookie
+	cookies := middlewares.CookieConfig{
+		Enabled:     cookieConfig.Enabled,
+		Domain:      cookieConfig.Domain,
+		Path:        cookieConfig.Path,
+		RefreshPath: cookieConfig.RefreshPath,
+		Secure:      cookieConfig.Secure,
+		SameSite:    cookieConfig.SameSite,
+		AccessName:  "access_token",
+		RefreshName: "refresh_token",
+		CSRFName:    "csrf_token",
+		CSRFHeader:  "X-CSRF-Token",
+	}
+	dpopConfig := config.Get().DPoP
+	dpopVerifier := infrastructure.NewDPoPVerifier(
+		cache.NewReplayCache(redisClient, "dpop:jti:"),
+		config.Get().JWT_Secret,
+		dpopConfig.RequireNonce,
+		dpopConfig.ProofMaxAge,
+		dpopConfig.NonceTTL,
+	)
+	serviceIdentities, err := infrastructure.LoadServiceIdentities(config.Get().TLS.ServiceIdentityFile)
+	if err != nil {
+		log.Fatalf("failed to load service identities: %v", err)
+	}
+	exportConfig := config.Get().Export
+	exportStore, err := archive.NewFileStore(exportConfig.Dir)
+	if err != nil {
+		log.Fatalf("failed to open export directory: %v", err)
+	}
+	exportService := services.NewExportService(
+		repositories.NewExportRepository(db),
+		exportStore,
+		userRepo,
+		deviceRepo,
+		sessionCache,
+		loginHistory,
+		auditRepo,
+		newExportSecret(exportConfig.URLSecret),
+		domain.ExportPolicy{
+			Interval:     exportConfig.Interval,
+			Retention:    exportConfig.Retention,
+			URLTTL:       exportConfig.URLTTL,
+			BaseURL:      exportConfig.BaseURL,
+			PollInterval: exportConfig.PollInterval,
+		},
+	)
+	exportCtx, stopExports := context.WithCancel(context.Background())
+	defer stopExports()
+	go exportService.Run(exportCtx)
+
+	handler := handlers.NewBackEndHandler(service, tokenManager, cookies, dpopVerifier, serviceIdentities, auditService, webhookService, challengeService, exportService)
+
+	rateLimiter := cache.NewRateLimitCache(redisClient)
 
-	service := services.NewBackEndService(userRepo)
-	handler := handlers.NewBackEndHandler(service, tokenCache)
+	// Solutions are verified even when challenges are disabled, since the
+	// login risk check may still ask for one.
+	requireChallenge := middlewares.RequireChallenge(challengeService, middlewares.ChallengeConfig{
+		TrustedNetworks: parsePrefixes(challengeConfig.TrustedCIDRs),
+		Services:        serviceIdentities,
+		VerifyOnly:      !challengeConfig.Enabled,
+	})
 
-	app := newRouter(handler, tokenCache)
+	app := newRouter(handler, tokenManager, rateLimiter, cookies, dpopVerifier, requireChallenge)
 	go func() {
-		if err := app.Listen(fmt.Sprintf("%s:%d", config.Get().App.Host, config.Get().App.Port)); err != nil {
+		if err := listen(app, fmt.Sprintf("%s:%d", config.Get().App.Host, config.Get().App.Port)); err != nil {
 			zap.L().Sugar().Fatal(err)
 		}
 	}()
 
 	gracefulShutdown(app)
+	stopAuditWriter()
+	<-auditWriterDone
+
+}
+
+// listen serves app over plain HTTP, or over TLS with optional client
+// certificate verification when TLS is enabled.
+func listen(app *fiber.App, addr string) error {
+	tlsConfig := config.Get().TLS
+	if !tlsConfig.Enabled {
+		return app.Listen(addr)
+	}
+
+	serverTLS, err := infrastructure.NewServerTLSConfig(tlsConfig.CertFile, tlsConfig.KeyFile, tlsConfig.ClientCAFile, tlsConfig.ClientAuth)
+	if err != nil {
+		return err
+	}
+	ln, err := tls.Listen("tcp", addr, serverTLS)
+	if err != nil {
+		return err
+	}
+	return app.Listener(ln)
+}
+
+func newPasswordHasher(cfg config.HasherConfig) ports.PasswordHasher {
+	passwordHasher, err := hasher.New(cfg.Algorithm, cfg.BcryptCost, hasher.Argon2idParams{
+		Memory:      cfg.Argon2Memory,
+		Iterations:  cfg.Argon2Iterations,
+		Parallelism: cfg.Argon2Parallelism,
+		SaltLength:  cfg.Argon2SaltLength,
+		KeyLength:   cfg.Argon2KeyLength,
+	})
+	if err != nil {
+		zap.L().Fatal("failed to create password hasher", zap.Error(err))
+	}
+	return passwordHasher
+}
 
+// newEventProducer builds the configured domain event producer and a function
+// that flushes and closes it.
+func newEventProducer(cfg config.EventConfig) (ports.EventProducer, func()) {
+	closeWith := func(close func() error) func() {
+		return func() {
+			if err := close(); err != nil {
+				zap.L().Warn("failed to close event producer", zap.Error(err))
+			}
+		}
+	}
+
+	switch cfg.Producer {
+	case "log":
+		return producers.NewLogProducer(), func() {}
+	case "file":
+		producer, err := producers.NewFileProducer(cfg.FilePath)
+		if err != nil {
+			zap.L().Fatal("failed to open event file", zap.Error(err))
+		}
+		return producer, closeWith(producer.Close)
+	case "kafka":
+		producer := producers.NewKafkaProducer(cfg.KafkaBrokers, cfg.KafkaTopic, cfg.PublishTimeout)
+		return producer, closeWith(producer.Close)
+	case "nats":
+		producer, err := producers.NewNATSProducer(cfg.NATSURL, cfg.NATSSubjectPrefix, cfg.NATSJetStream, cfg.PublishTimeout)
+		if err != nil {
+			zap.L().Fatal("failed to connect to NATS", zap.Error(err))
+		}
+		return producer, closeWith(producer.Close)
+	default:
+		zap.L().Fatal("unknown event producer", zap.String("producer", cfg.Producer))
+		return nil, nil
+	}
+}
+
+// newChallengeVerifier requires the provider's secrets when inUse, since a
+// verifier without them rejects every solution.
+func newChallengeVerifier(cfg config.ChallengeConfig, inUse bool, replay ports.ReplayCache) ports.ChallengeVerifier {
+	if cfg.Provider != "pow" {
+		if inUse && (cfg.CaptchaSiteKey == "" || cfg.CaptchaSecret == "") {
+			zap.L().Fatal("CHALLENGE_CAPTCHA_SITE_KEY and CHALLENGE_CAPTCHA_SECRET are required", zap.String("provider", cfg.Provider))
+		}
+		verifier, err := challenge.NewCaptcha(cfg.Provider, cfg.CaptchaSiteKey, cfg.CaptchaSecret, cfg.CaptchaTimeout)
+		if err != nil {
+			zap.L().Fatal("failed to create captcha verifier", zap.Error(err))
+		}
+		return verifier
+	}
+
+	secret := []byte(cfg.POWSecret)
+	if len(secret) == 0 {
+		if inUse {
+			zap.L().Fatal("CHALLENGE_POW_SECRET is required")
+		}
+		// Nothing issues challenges, so any key will do.
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			zap.L().Fatal("failed to generate challenge secret", zap.Error(err))
+		}
+	}
+	return challenge.NewProofOfWork(secret, cfg.POWDifficulty, cfg.POWTTL, replay)
+}
+
+func newMailer(cfg config.MailConfig, resetURL string) ports.Mailer {
+	if cfg.SMTPAddr == "" {
+		zap.L().Warn("SMTP_ADDR is not set; password reset emails are not sent")
+		return mailer.Disabled{}
+	}
+	m, err := mailer.NewSMTP(cfg.SMTPAddr, cfg.SMTPUsername, cfg.SMTPPassword, cfg.From, resetURL)
+	if err != nil {
+		zap.L().Fatal("failed to create mailer", zap.Error(err))
+	}
+	return m
+}
+
+// newExportSecret requires EXPORT_URL_SECRET, since links signed with a
+// per-instance key would only download from the instance that issued them.
+func newExportSecret(secret string) []byte {
+	if secret == "" {
+		zap.L().Fatal("EXPORT_URL_SECRET is required")
+	}
+	return []byte(secret)
+}
+
+func parsePrefixes(cidrs []string) []netip.Prefix {
+	prefixes := make([]netip.Prefix, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			zap.L().Fatal("invalid CIDR", zap.String("cidr", cidr), zap.Error(err))
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes
+}
+
+func newSyslogForwarder(cfg config.SyslogConfig) *siem.Forwarder {
+	var tlsConfig *tls.Config
+	if cfg.Network == "tls" {
+		tlsConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+		if cfg.TLSCAFile != "" {
+			pem, err := os.ReadFile(cfg.TLSCAFile)
+			if err != nil {
+				zap.L().Fatal("failed to read syslog CA file", zap.Error(err))
+			}
+			tlsConfig.RootCAs = x509.NewCertPool()
+			if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+				zap.L().Fatal("no certificates found in syslog CA file")
+			}
+		}
+	}
+
+	forwarder, err := siem.NewForwarder(siem.Config{
+		Network:    cfg.Network,
+		Address:    cfg.Address,
+		TLS:        tlsConfig,
+		AppName:    cfg.AppName,
+		Facility:   cfg.Facility,
+		Formats:    siem.ParseCategories(cfg.Categories, cfg.Format),
+		BufferSize: cfg.BufferSize,
+		MaxBackoff: cfg.MaxBackoff,
+	})
+	if err != nil {
+		zap.L().Fatal("failed to create syslog forwarder", zap.Error(err))
+	}
+	return forwarder
 }
 
 func gracefulShutdown(app *fiber.App) {
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kanta/backend-challenge/config"
+	"github.com/kanta/backend-challenge/infrastructure"
+	"github.com/kanta/backend-challenge/internal/adapters/hasher"
+	"github.com/kanta/backend-challenge/internal/adapters/repositories"
+	"github.com/kanta/backend-challenge/internal/core/domain"
+	"github.com/kanta/backend-challenge/internal/core/services"
+)
+
+// importRecord is one user exported from the previous auth provider.
+// PasswordHash must be in a format understood by the hasher package, e.g.
+// pbkdf2_sha256$..., $scrypt$..., sha1$..., bcrypt or argon2id.
+type importRecord struct {
+	ID           string    `json:"id"`
+	Name         string    `json:"name"`
+	Email        string    `json:"email"`
+	PasswordHash string    `json:"password_hash"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// Usage: go run ./cmd/user-import -file users.jsonl [-format jsonl|csv] [-dry-run]
+func main() {
+	file := flag.String("file", "", "path to a JSONL or CSV file of users")
+	format := flag.String("format", "", "jsonl or csv, defaults to the file extension")
+	dryRun := flag.Bool("dry-run", false, "validate records without writing them")
+	flag.Parse()
+
+	if *file == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *format == "" {
+		*format = strings.TrimPrefix(filepath.Ext(*file), ".")
+	}
+
+	config.Load()
+
+	hasherConfig := config.Get().Hasher
+	passwordHasher, err := hasher.New(hasherConfig.Algorithm, hasherConfig.BcryptCost, hasher.Argon2idParams{
+		Memory:      hasherConfig.Argon2Memory,
+		Iterations:  hasherConfig.Argon2Iterations,
+		Parallelism: hasherConfig.Argon2Parallelism,
+		SaltLength:  hasherConfig.Argon2SaltLength,
+		KeyLength:   hasherConfig.Argon2KeyLength,
+	})
+	if err != nil {
+		log.Fatalf("failed to create password hasher: %v", err)
+	}
+
+	f, err := os.Open(*file)
+	if err != nil {
+		log.Fatalf("failed to open %s: %v", *file, err)
+	}
+	defer f.Close()
+
+	var records []importRecord
+	switch *format {
+	case "jsonl":
+		records, err = readJSONL(f)
+	case "csv":
+		records, err = readCSV(f)
+	default:
+		log.Fatalf("unsupported format %q", *format)
+	}
+	if err != nil {
+		log.Fatalf("failed to read %s: %v", *file, err)
+	}
+
+	var imported, failed int
+	if *dryRun {
+		for i, record := range records {
+			if !passwordHasher.Supports(record.PasswordHash) {
+				log.Printf("record %d (%s): %v", i+1, record.Email, services.ErrUnsupportedHash)
+				failed++
+				continue
+			}
+			imported++
+		}
+		log.Printf("dry run: %d valid, %d invalid", imported, failed)
+		return
+	}
+
+	psqlConfig := config.Get().Psql
+	db := infrastructure.NewPostgresClient(psqlConfig.Host, psqlConfig.User, psqlConfig.Pass, psqlConfig.DB, psqlConfig.Port)
+	sqlDB, err := db.DB()
+	if err != nil {
+		log.Fatalf("failed to get sql.DB: %v", err)
+	}
+	defer sqlDB.Close()
+
+	importService := services.NewUserImportService(repositories.NewUserRepository(db), passwordHasher)
+	for i, record := range records {
+		err := importService.ImportUser(&domain.User{
+			ID:        record.ID,
+			Name:      record.Name,
+			Email:     record.Email,
+			Password:  record.PasswordHash,
+			CreatedAt: record.CreatedAt,
+		})
+		if err != nil {
+			log.Printf("record %d (%s): %v", i+1, record.Email, err)
+			failed++
+			continue
+		}
+		imported++
+	}
+	log.Printf("imported %d users, %d failed", imported, failed)
+}
+
+func readJSONL(r io.Reader) ([]importRecord, error) {
+	var records []importRecord
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var record importRecord
+		if err := json.Unmarshal([]byte(text), &record); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		records = append(records, record)
+	}
+	return records, scanner.Err()
+}
+
+// readCSV expects a header row naming at least email and password_hash;
+// name, id and created_at (RFC 3339) are optional.
+func readCSV(r io.Reader) ([]importRecord, error) {
+	reader := csv.NewReader(r)
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"email", "password_hash"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("missing %s column", required)
+		}
+	}
+
+	get := func(row []string, column string) string {
+		if i, ok := columns[column]; ok && i < len(row) {
+			return strings.TrimSpace(row[i])
+		}
+		return ""
+	}
+
+	var records []importRecord
+	for line := 2; ; line++ {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		record := importRecord{
+			ID:           get(row, "id"),
+			Name:         get(row, "name"),
+			Email:        get(row, "email"),
+			PasswordHash: get(row, "password_hash"),
+		}
+		if createdAt := get(row, "created_at"); createdAt != "" {
+			if record.CreatedAt, err = time.Parse(time.RFC3339, createdAt); err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
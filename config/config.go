@@ -1,6 +1,8 @@
 package config
 
 import (
+	"time"
+
 	"github.com/joho/godotenv"
 	"github.com/kelseyhightower/envconfig"
 	"go.uber.org/zap"
@@ -31,12 +33,286 @@ type redisConfig struct {
 	DB       int    `envconfig:"REDIS_DB" default:"0"`
 }
 
+type LockoutConfig struct {
+	MaxAccountFailures int           `envconfig:"LOCKOUT_MAX_ACCOUNT_FAILURES" default:"5"`
+	MaxIPFailures      int           `envconfig:"LOCKOUT_MAX_IP_FAILURES" default:"20"`
+	FailureWindow      time.Duration `envconfig:"LOCKOUT_FAILURE_WINDOW" default:"15m"`
+	Duration           time.Duration `envconfig:"LOCKOUT_DURATION" default:"15m"`
+	DelayBase          time.Duration `envconfig:"LOCKOUT_DELAY_BASE" default:"200ms"`
+	DelayMax           time.Duration `envconfig:"LOCKOUT_DELAY_MAX" default:"3s"`
+}
+
+type RateLimitConfig struct {
+	Enabled    bool          `envconfig:"RATE_LIMIT_ENABLED" default:"true"`
+	FailOpen   bool          `envconfig:"RATE_LIMIT_FAIL_OPEN" default:"true"`
+	AuthLimit  int           `envconfig:"RATE_LIMIT_AUTH_LIMIT" default:"10"`
+	AuthWindow time.Duration `envconfig:"RATE_LIMIT_AUTH_WINDOW" default:"1m"`
+	APILimit   int           `envconfig:"RATE_LIMIT_API_LIMIT" default:"120"`
+	APIWindow  time.Duration `envconfig:"RATE_LIMIT_API_WINDOW" default:"1m"`
+}
+
+type PasswordConfig struct {
+	MinLength      int           `envconfig:"PASSWORD_MIN_LENGTH" default:"8"`
+	MaxBytes       int           `envconfig:"PASSWORD_MAX_BYTES" default:"72"`
+	RequireUpper   bool          `envconfig:"PASSWORD_REQUIRE_UPPER" default:"true"`
+	RequireLower   bool          `envconfig:"PASSWORD_REQUIRE_LOWER" default:"true"`
+	RequireDigit   bool          `envconfig:"PASSWORD_REQUIRE_DIGIT" default:"true"`
+	RequireSymbol  bool          `envconfig:"PASSWORD_REQUIRE_SYMBOL" default:"false"`
+	RejectPersonal bool          `envconfig:"PASSWORD_REJECT_PERSONAL" default:"true"`
+	BreachDir      string        `envconfig:"PASSWORD_BREACH_DIR"`
+	ResetTokenTTL  time.Duration `envconfig:"PASSWORD_RESET_TOKEN_TTL" default:"30m"`
+	// ResetURL is the page reset emails link to, with the token added as
+	// the "token" query parameter.
+	ResetURL string `envconfig:"PASSWORD_RESET_URL" default:"http://localhost:3000/reset-password"`
+}
+
+type MailConfig struct {
+	// SMTPAddr is the relay as "host:port". Reset emails are not sent
+	// without it.
+	SMTPAddr     string `envconfig:"SMTP_ADDR"`
+	SMTPUsername string `envconfig:"SMTP_USERNAME"`
+	SMTPPassword string `envconfig:"SMTP_PASSWORD"`
+	From         string `envconfig:"MAIL_FROM" default:"no-reply@localhost"`
+}
+
+type HasherConfig struct {
+	Algorithm         string `envconfig:"PASSWORD_HASHER" default:"argon2id"`
+	BcryptCost        int    `envconfig:"BCRYPT_COST" default:"10"`
+	Argon2Memory      uint32 `envconfig:"ARGON2_MEMORY_KIB" default:"65536"`
+	Argon2Iterations  uint32 `envconfig:"ARGON2_ITERATIONS" default:"3"`
+	Argon2Parallelism uint8  `envconfig:"ARGON2_PARALLELISM" default:"2"`
+	Argon2SaltLength  uint32 `envconfig:"ARGON2_SALT_LENGTH" default:"16"`
+	Argon2KeyLength   uint32 `envconfig:"ARGON2_KEY_LENGTH" default:"32"`
+}
+
+type StepUpConfig struct {
+	MaxAge time.Duration `envconfig:"STEP_UP_MAX_AGE" default:"10m"`
+}
+
+type SessionConfig struct {
+	AccessTokenTTL  time.Duration `envconfig:"ACCESS_TOKEN_TTL" default:"15m"`
+	IdleTimeout     time.Duration `envconfig:"SESSION_IDLE_TIMEOUT" default:"24h"`
+	AbsoluteTimeout time.Duration `envconfig:"SESSION_ABSOLUTE_TIMEOUT" default:"168h"`
+	// TokenPolicyFile optionally configures per-client token policies; the
+	// values above are the defaults for every client.
+	TokenPolicyFile string `envconfig:"TOKEN_POLICY_FILE"`
+	// Audience identifies this API in the "aud" claim. Tokens without it
+	// are rejected; empty disables the check.
+	Audience string `envconfig:"TOKEN_AUDIENCE" default:"backend-api"`
+}
+
+type TokenValidationConfig struct {
+	// RevocationMode is "allowlist" to store every live token or "denylist"
+	// to store only the jti of revoked tokens.
+	RevocationMode string `envconfig:"TOKEN_REVOCATION_MODE" default:"allowlist"`
+	// CacheSize is the number of validated access tokens kept in process;
+	// 0 disables the cache.
+	CacheSize int           `envconfig:"TOKEN_VALIDATION_CACHE_SIZE" default:"10000"`
+	CacheTTL  time.Duration `envconfig:"TOKEN_VALIDATION_CACHE_TTL" default:"5s"`
+	// RevocationChannel is the Redis pub/sub channel revocations are
+	// broadcast on.
+	RevocationChannel string `envconfig:"TOKEN_REVOCATION_CHANNEL" default:"token-revocations"`
+	// AcceptUnhashed also accepts raw tokens stored in allowlist mode before
+	// tokens were hashed. Enable it for one SESSION_ABSOLUTE_TIMEOUT after
+	// upgrading; it will be removed in the next release.
+	AcceptUnhashed bool `envconfig:"TOKEN_ACCEPT_UNHASHED" default:"false"`
+}
+
+type CookieConfig struct {
+	Enabled     bool   `envconfig:"COOKIE_MODE_ENABLED" default:"false"`
+	Domain      string `envconfig:"COOKIE_DOMAIN"`
+	Path        string `envconfig:"COOKIE_PATH" default:"/"`
+	RefreshPath string `envconfig:"COOKIE_REFRESH_PATH" default:"/api/v1/auth"`
+	Secure      bool   `envconfig:"COOKIE_SECURE" default:"true"`
+	SameSite    string `envconfig:"COOKIE_SAMESITE" default:"Lax"`
+}
+
+type DPoPConfig struct {
+	RequireNonce bool `envconfig:"DPOP_REQUIRE_NONCE" default:"false"`
+	// ProofMaxAge bounds the clock skew accepted in a proof's iat claim.
+	ProofMaxAge time.Duration `envconfig:"DPOP_PROOF_MAX_AGE" default:"1m"`
+	NonceTTL    time.Duration `envconfig:"DPOP_NONCE_TTL" default:"5m"`
+}
+
+type TLSConfig struct {
+	Enabled  bool   `envconfig:"TLS_ENABLED" default:"false"`
+	CertFile string `envconfig:"TLS_CERT_FILE"`
+	KeyFile  string `envconfig:"TLS_KEY_FILE"`
+	// ClientCAFile enables client certificate verification.
+	ClientCAFile string `envconfig:"TLS_CLIENT_CA_FILE"`
+	ClientAuth   string `envconfig:"TLS_CLIENT_AUTH" default:"request"`
+	// ServiceIdentityFile maps client certificates to service identities.
+	ServiceIdentityFile string `envconfig:"MTLS_SERVICE_IDENTITY_FILE"`
+}
+
+type AuditConfig struct {
+	// CheckpointKeyFile is a PEM Ed25519 private key used to sign hash chain
+	// checkpoints; checkpoints are not written without it.
+	CheckpointKeyFile  string        `envconfig:"AUDIT_CHECKPOINT_KEY_FILE"`
+	CheckpointInterval time.Duration `envconfig:"AUDIT_CHECKPOINT_INTERVAL" default:"1h"`
+	// Retention is how long entries are kept before their partition is
+	// purged; 0 keeps them forever. Purging requires CheckpointKeyFile and
+	// the audit_purger database role.
+	Retention time.Duration `envconfig:"AUDIT_RETENTION" default:"0"`
+	// QueueSize is how many entries wait to be written in batches; 0 writes
+	// every entry within its request.
+	QueueSize int `envconfig:"AUDIT_QUEUE_SIZE" default:"10000"`
+}
+
+type SyslogConfig struct {
+	Enabled bool `envconfig:"SYSLOG_ENABLED" default:"false"`
+	// Network is "udp", "tcp" or "tls".
+	Network   string `envconfig:"SYSLOG_NETWORK" default:"udp"`
+	Address   string `envconfig:"SYSLOG_ADDRESS" default:"localhost:514"`
+	TLSCAFile string `envconfig:"SYSLOG_TLS_CA_FILE"`
+	AppName   string `envconfig:"SYSLOG_APP_NAME" default:"backend-challenge"`
+	// Facility 10 is security/authorization messages.
+	Facility int    `envconfig:"SYSLOG_FACILITY" default:"10"`
+	Format   string `envconfig:"SYSLOG_FORMAT" default:"cef"`
+	// Categories lists the forwarded event categories, each optionally
+	// with its own format, e.g. "authentication,admin:json".
+	Categories []string      `envconfig:"SYSLOG_CATEGORIES" default:"authentication,account,admin,security"`
+	BufferSize int           `envconfig:"SYSLOG_BUFFER_SIZE" default:"10000"`
+	MaxBackoff time.Duration `envconfig:"SYSLOG_RETRY_MAX_BACKOFF" default:"30s"`
+}
+
+type WebhookConfig struct {
+	// AllowHTTP permits plain http subscription URLs, e.g. for local
+	// receivers.
+	AllowHTTP bool `envconfig:"WEBHOOK_ALLOW_HTTP" default:"false"`
+	// AllowedCIDRs are non-public networks deliveries may still connect
+	// to, e.g. 127.0.0.0/8 for a local receiver.
+	AllowedCIDRs []string      `envconfig:"WEBHOOK_ALLOWED_CIDRS"`
+	MaxAttempts  int           `envconfig:"WEBHOOK_MAX_ATTEMPTS" default:"8"`
+	BackoffBase  time.Duration `envconfig:"WEBHOOK_BACKOFF_BASE" default:"30s"`
+	BackoffMax   time.Duration `envconfig:"WEBHOOK_BACKOFF_MAX" default:"6h"`
+	// PollInterval is how often due deliveries are looked up.
+	PollInterval time.Duration `envconfig:"WEBHOOK_POLL_INTERVAL" default:"1s"`
+	Timeout      time.Duration `envconfig:"WEBHOOK_TIMEOUT" default:"10s"`
+	BatchSize    int           `envconfig:"WEBHOOK_BATCH_SIZE" default:"20"`
+}
+
+type corsConfig struct {
+	AllowOrigins string `envconfig:"CORS_ALLOW_ORIGINS" default:"*"`
+}
+
+type EventConfig struct {
+	// Producer is "log", "file", "kafka" or "nats".
+	Producer string `envconfig:"EVENT_PRODUCER" default:"log"`
+	FilePath string `envconfig:"EVENT_FILE_PATH" default:"events.jsonl"`
+	// PublishTimeout bounds waiting for the broker to acknowledge an event.
+	PublishTimeout time.Duration `envconfig:"EVENT_PUBLISH_TIMEOUT" default:"5s"`
+
+	KafkaBrokers []string `envconfig:"KAFKA_BROKERS" default:"localhost:9092"`
+	KafkaTopic   string   `envconfig:"KAFKA_TOPIC" default:"auth-events"`
+
+	NATSURL           string `envconfig:"NATS_URL" default:"nats://localhost:4222"`
+	NATSSubjectPrefix string `envconfig:"NATS_SUBJECT_PREFIX" default:"auth.events"`
+	NATSJetStream     bool   `envconfig:"NATS_JETSTREAM" default:"false"`
+}
+
+type OutboxConfig struct {
+	PollInterval time.Duration `envconfig:"OUTBOX_POLL_INTERVAL" default:"500ms"`
+	BatchSize    int           `envconfig:"OUTBOX_BATCH_SIZE" default:"100"`
+	// Lease bounds how long a relay may spend publishing one batch.
+	Lease       time.Duration `envconfig:"OUTBOX_LEASE" default:"1m"`
+	BackoffBase time.Duration `envconfig:"OUTBOX_BACKOFF_BASE" default:"1s"`
+	BackoffMax  time.Duration `envconfig:"OUTBOX_BACKOFF_MAX" default:"5m"`
+	// Retention is how long published messages are kept, e.g. for
+	// debugging, before cleanup deletes them.
+	Retention       time.Duration `envconfig:"OUTBOX_RETENTION" default:"24h"`
+	CleanupInterval time.Duration `envconfig:"OUTBOX_CLEANUP_INTERVAL" default:"1h"`
+}
+
+type ChallengeConfig struct {
+	Enabled bool `envconfig:"CHALLENGE_ENABLED" default:"true"`
+	// Provider is "pow", "turnstile", "hcaptcha" or "recaptcha".
+	Provider           string        `envconfig:"CHALLENGE_PROVIDER" default:"pow"`
+	IPFailureThreshold int           `envconfig:"CHALLENGE_IP_FAILURE_THRESHOLD" default:"3"`
+	NewRange           bool          `envconfig:"CHALLENGE_NEW_RANGE" default:"false"`
+	RangeMemory        time.Duration `envconfig:"CHALLENGE_RANGE_MEMORY" default:"720h"`
+	TrustedCIDRs       []string      `envconfig:"CHALLENGE_TRUSTED_CIDRS"`
+
+	// POWSecret signs proof-of-work challenges. Instances behind one load
+	// balancer must share it. It is required unless neither
+	// CHALLENGE_ENABLED nor RISK_CHALLENGE_SCORE issues challenges; generate
+	// one with `openssl rand -hex 32`.
+	POWSecret     string        `envconfig:"CHALLENGE_POW_SECRET"`
+	POWDifficulty int           `envconfig:"CHALLENGE_POW_DIFFICULTY" default:"20"`
+	POWTTL        time.Duration `envconfig:"CHALLENGE_POW_TTL" default:"2m"`
+
+	CaptchaSiteKey string        `envconfig:"CHALLENGE_CAPTCHA_SITE_KEY"`
+	CaptchaSecret  string        `envconfig:"CHALLENGE_CAPTCHA_SECRET"`
+	CaptchaTimeout time.Duration `envconfig:"CHALLENGE_CAPTCHA_TIMEOUT" default:"5s"`
+}
+
+type RiskConfig struct {
+	// GeoIPDatabase and GeoIPASNDatabase are MaxMind .mmdb files, e.g.
+	// GeoLite2-City and GeoLite2-ASN. Geo signals are off without them.
+	GeoIPDatabase    string `envconfig:"GEOIP_DATABASE"`
+	GeoIPASNDatabase string `envconfig:"GEOIP_ASN_DATABASE"`
+
+	// ReputationLists are "name:path[:score]" entries, each a file of IPs
+	// or CIDRs.
+	ReputationLists          []string      `envconfig:"RISK_REPUTATION_LISTS"`
+	ReputationDefaultScore   int           `envconfig:"RISK_REPUTATION_DEFAULT_SCORE" default:"50"`
+	ReputationReloadInterval time.Duration `envconfig:"RISK_REPUTATION_RELOAD_INTERVAL" default:"1h"`
+
+	NewCountryScore       int     `envconfig:"RISK_NEW_COUNTRY_SCORE" default:"40"`
+	NewASNScore           int     `envconfig:"RISK_NEW_ASN_SCORE" default:"15"`
+	ImpossibleTravelScore int     `envconfig:"RISK_IMPOSSIBLE_TRAVEL_SCORE" default:"60"`
+	ImpossibleTravelKmh   float64 `envconfig:"RISK_IMPOSSIBLE_TRAVEL_KMH" default:"900"`
+	ImpossibleTravelMinKm float64 `envconfig:"RISK_IMPOSSIBLE_TRAVEL_MIN_KM" default:"300"`
+	HistorySize           int     `envconfig:"RISK_HISTORY_SIZE" default:"50"`
+
+	// Thresholds map the score to a decision; 0 disables one.
+	AlertScore     int `envconfig:"RISK_ALERT_SCORE" default:"30"`
+	ChallengeScore int `envconfig:"RISK_CHALLENGE_SCORE" default:"50"`
+	BlockScore     int `envconfig:"RISK_BLOCK_SCORE" default:"100"`
+	// MFAScore is reserved for asking for a second factor. There is no MFA
+	// yet, so the server refuses to start when it is set.
+	MFAScore int `envconfig:"RISK_MFA_SCORE" default:"0"`
+}
+
+type ExportConfig struct {
+	// Dir holds the archives. Instances serving downloads must share it.
+	Dir       string        `envconfig:"EXPORT_DIR" default:"exports"`
+	Interval  time.Duration `envconfig:"EXPORT_INTERVAL" default:"24h"`
+	Retention time.Duration `envconfig:"EXPORT_RETENTION" default:"72h"`
+	// URLSecret signs download links. Instances must share it. It is
+	// required; generate one with `openssl rand -hex 32`.
+	URLSecret    string        `envconfig:"EXPORT_URL_SECRET"`
+	URLTTL       time.Duration `envconfig:"EXPORT_URL_TTL" default:"15m"`
+	BaseURL      string        `envconfig:"EXPORT_BASE_URL"`
+	PollInterval time.Duration `envconfig:"EXPORT_POLL_INTERVAL" default:"5s"`
+}
+
 type config struct {
 	App        appConfig
 	Mongo      mongoConfig
 	JWT_Secret string `envconfig:"JWT_SECRET"`
 	Psql       PsqlConfig
 	Redis      redisConfig
+	Lockout    LockoutConfig
+	RateLimit  RateLimitConfig
+	Password   PasswordConfig
+	Mail       MailConfig
+	Hasher     HasherConfig
+	StepUp     StepUpConfig
+	Session    SessionConfig
+	Validation TokenValidationConfig
+	Cookie     CookieConfig
+	DPoP       DPoPConfig
+	TLS        TLSConfig
+	Audit      AuditConfig
+	Syslog     SyslogConfig
+	Webhook    WebhookConfig
+	Events     EventConfig
+	Outbox     OutboxConfig
+	Challenge  ChallengeConfig
+	Risk       RiskConfig
+	Export     ExportConfig
+	CORS       corsConfig
 }
 
 var c config
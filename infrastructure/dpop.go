@@ -0,0 +1,254 @@
+package infrastructure
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/kanta/backend-challenge/internal/core/domain"
+	"github.com/kanta/backend-challenge/internal/core/ports"
+)
+
+// DPoPVerifier checks DPoP proofs (RFC 9449) sent by clients that bind their
+// tokens to a key pair.
+type DPoPVerifier struct {
+	replay       ports.ReplayCache
+	nonceKey     []byte
+	requireNonce bool
+	// maxAge bounds how far a proof's iat may be from the server clock.
+	maxAge   time.Duration
+	nonceTTL time.Duration
+}
+
+func NewDPoPVerifier(replay ports.ReplayCache, secret string, requireNonce bool, maxAge, nonceTTL time.Duration) *DPoPVerifier {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("dpop-nonce"))
+	return &DPoPVerifier{
+		replay:       replay,
+		nonceKey:     mac.Sum(nil),
+		requireNonce: requireNonce,
+		maxAge:       maxAge,
+		nonceTTL:     nonceTTL,
+	}
+}
+
+var dpopAlgorithms = []string{"ES256", "ES384", "ES512", "RS256", "PS256", "EdDSA"}
+
+type dpopClaims struct {
+	HTM   string `json:"htm"`
+	HTU   string `json:"htu"`
+	ATH   string `json:"ath,omitempty"`
+	Nonce string `json:"nonce,omitempty"`
+	jwt.RegisteredClaims
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	D   string `json:"d"`
+}
+
+// Verify checks proof against the request's method and URL and returns the
+// JWK thumbprint of the key that signed it. accessToken is empty at the token
+// endpoint; on resource requests the proof must carry its hash in "ath".
+func (v *DPoPVerifier) Verify(ctx context.Context, proof, method, requestURL, accessToken string) (string, error) {
+	if proof == "" || strings.Contains(proof, ",") {
+		return "", domain.ErrInvalidDPoPProof
+	}
+
+	var thumbprint string
+	claims := &dpopClaims{}
+	_, err := jwt.ParseWithClaims(proof, claims, func(token *jwt.Token) (interface{}, error) {
+		if typ, _ := token.Header["typ"].(string); typ != "dpop+jwt" {
+			return nil, errors.New("invalid typ")
+		}
+		key, jkt, err := parseJWK(token.Header["jwk"])
+		if err != nil {
+			return nil, err
+		}
+		thumbprint = jkt
+		return key, nil
+	}, jwt.WithValidMethods(dpopAlgorithms))
+	if err != nil {
+		return "", domain.ErrInvalidDPoPProof
+	}
+
+	if claims.ID == "" || claims.IssuedAt == nil || claims.HTM != method || !sameURL(claims.HTU, requestURL) {
+		return "", domain.ErrInvalidDPoPProof
+	}
+	if age := time.Since(claims.IssuedAt.Time); age > v.maxAge || age < -v.maxAge {
+		return "", domain.ErrInvalidDPoPProof
+	}
+
+	if accessToken != "" {
+		sum := sha256.Sum256([]byte(accessToken))
+		ath := base64.RawURLEncoding.EncodeToString(sum[:])
+		if subtle.ConstantTimeCompare([]byte(claims.ATH), []byte(ath)) != 1 {
+			return "", domain.ErrInvalidDPoPProof
+		}
+	}
+
+	if v.requireNonce && !v.validNonce(claims.Nonce) {
+		return "", domain.ErrUseDPoPNonce
+	}
+
+	// A proof is only accepted once; it cannot be reused before its iat
+	// window closes.
+	first, err := v.replay.Remember(ctx, thumbprint+":"+claims.ID, 2*v.maxAge)
+	if err != nil {
+		return "", err
+	}
+	if !first {
+		return "", domain.ErrInvalidDPoPProof
+	}
+
+	return thumbprint, nil
+}
+
+// RequiresNonce reports whether proofs must carry a server-issued nonce.
+func (v *DPoPVerifier) RequiresNonce() bool {
+	return v.requireNonce
+}
+
+// Nonce returns a fresh nonce for the DPoP-Nonce response header. Nonces are
+// stateless: a timestamp authenticated with a key derived from the secret.
+func (v *DPoPVerifier) Nonce() string {
+	buf := make([]byte, 8, 8+sha256.Size)
+	binary.BigEndian.PutUint64(buf, uint64(time.Now().Unix()))
+	mac := hmac.New(sha256.New, v.nonceKey)
+	mac.Write(buf)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(buf))
+}
+
+func (v *DPoPVerifier) validNonce(nonce string) bool {
+	raw, err := base64.RawURLEncoding.DecodeString(nonce)
+	if err != nil || len(raw) != 8+sha256.Size {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, v.nonceKey)
+	mac.Write(raw[:8])
+	if !hmac.Equal(mac.Sum(nil), raw[8:]) {
+		return false
+	}
+
+	issuedAt := time.Unix(int64(binary.BigEndian.Uint64(raw[:8])), 0)
+	age := time.Since(issuedAt)
+	return age <= v.nonceTTL && age >= -v.maxAge
+}
+
+// parseJWK returns the public key in header and its RFC 7638 thumbprint.
+func parseJWK(header interface{}) (interface{}, string, error) {
+	raw, err := json.Marshal(header)
+	if err != nil {
+		return nil, "", err
+	}
+	var jwk jsonWebKey
+	if err := json.Unmarshal(raw, &jwk); err != nil {
+		return nil, "", err
+	}
+	if jwk.D != "" {
+		return nil, "", errors.New("jwk must not contain a private key")
+	}
+
+	var (
+		key       interface{}
+		canonical string
+	)
+	switch jwk.Kty {
+	case "EC":
+		var curve elliptic.Curve
+		switch jwk.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, "", errors.New("unsupported curve")
+		}
+		x, err := decodeBigInt(jwk.X)
+		if err != nil {
+			return nil, "", err
+		}
+		y, err := decodeBigInt(jwk.Y)
+		if err != nil {
+			return nil, "", err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, "", errors.New("invalid ec point")
+		}
+		key = &ecdsa.PublicKey{Curve: curve, X: x, Y: y}
+		canonical = fmt.Sprintf(`{"crv":"%s","kty":"EC","x":"%s","y":"%s"}`, jwk.Crv, jwk.X, jwk.Y)
+	case "RSA":
+		n, err := decodeBigInt(jwk.N)
+		if err != nil {
+			return nil, "", err
+		}
+		e, err := decodeBigInt(jwk.E)
+		if err != nil {
+			return nil, "", err
+		}
+		if n.BitLen() < 2048 || !e.IsInt64() || e.Int64() < 3 || e.Int64() > 1<<31-1 {
+			return nil, "", errors.New("unsupported rsa key")
+		}
+		key = &rsa.PublicKey{N: n, E: int(e.Int64())}
+		canonical = fmt.Sprintf(`{"e":"%s","kty":"RSA","n":"%s"}`, jwk.E, jwk.N)
+	case "OKP":
+		x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		if err != nil || jwk.Crv != "Ed25519" || len(x) != ed25519.PublicKeySize {
+			return nil, "", errors.New("unsupported okp key")
+		}
+		key = ed25519.PublicKey(x)
+		canonical = fmt.Sprintf(`{"crv":"Ed25519","kty":"OKP","x":"%s"}`, jwk.X)
+	default:
+		return nil, "", errors.New("unsupported key type")
+	}
+
+	sum := sha256.Sum256([]byte(canonical))
+	return key, base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(raw) == 0 {
+		return nil, errors.New("invalid jwk member")
+	}
+	return new(big.Int).SetBytes(raw), nil
+}
+
+// sameURL compares htu with the request URL, ignoring query and fragment
+// (RFC 9449 section 4.3).
+func sameURL(htu, requestURL string) bool {
+	a, err := url.Parse(htu)
+	if err != nil {
+		return false
+	}
+	b, err := url.Parse(requestURL)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(a.Scheme, b.Scheme) &&
+		strings.EqualFold(a.Host, b.Host) &&
+		a.EscapedPath() == b.EscapedPath()
+}
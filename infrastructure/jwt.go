@@ -2,33 +2,178 @@ package infrastructure
 
 import (
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 	"github.com/kanta/backend-challenge/internal/core/domain"
 	"github.com/kanta/backend-challenge/internal/core/ports"
+	"go.uber.org/zap"
 )
 
-func GenerateTokenPairWithCache(ctx context.Context, userID string, secret string, cache ports.CachePort) (*domain.TokenPair, error) {
-	accessToken, err := generateToken(userID, secret, "access", time.Minute*15)
+// Revocation modes.
+const (
+	// RevocationAllowlist stores every live token in Redis and accepts only
+	// the stored ones, so issuing a token replaces the previous one.
+	RevocationAllowlist = "allowlist"
+	// RevocationDenylist stores only the jti of revoked tokens until they
+	// expire; any other unexpired token is accepted.
+	RevocationDenylist = "denylist"
+)
+
+type TokenManager struct {
+	secret string
+	// hashKey is the HMAC key for the token hashes stored in Redis.
+	hashKey  []byte
+	mode     string
+	cache    ports.CachePort
+	denylist ports.TokenDenylist
+	sessions ports.SessionStore
+	policies *domain.TokenPolicies
+	versions ports.TokenVersionPort
+	// validated caches access token validations in process; nil disables
+	// it. revocations tells the other instances to drop their entries.
+	validated   *ValidationCache
+	revocations ports.RevocationBus
+
+	// acceptUnhashed also accepts raw tokens stored before tokens were
+	// hashed. It will be removed in the next release.
+	acceptUnhashed bool
+}
+
+func NewTokenManager(
+	secret string,
+	mode string,
+	acceptUnhashed bool,
+	cache ports.CachePort,
+	denylist ports.TokenDenylist,
+	sessions ports.SessionStore,
+	policies *domain.TokenPolicies,
+	versions ports.TokenVersionPort,
+	validated *ValidationCache,
+	revocations ports.RevocationBus,
+) *TokenManager {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("token-at-rest"))
+
+	return &TokenManager{
+		secret:      secret,
+		hashKey:     mac.Sum(nil),
+		mode:        mode,
+		cache:       cache,
+		denylist:    denylist,
+		sessions:    sessions,
+		policies:    policies,
+		versions:    versions,
+		validated:   validated,
+		revocations: revocations,
+
+		acceptUnhashed: acceptUnhashed,
+	}
+}
+
+// ListenForRevocations evicts tokens revoked on other instances from the
+// validation cache until ctx is done.
+func (m *TokenManager) ListenForRevocations(ctx context.Context) {
+	if m.validated == nil || m.revocations == nil {
+		return
+	}
+	err := m.revocations.SubscribeRevocations(ctx, m.validated.ForgetUser, m.validated.Clear)
+	if err != nil && ctx.Err() == nil {
+		zap.L().Error("token revocation subscription stopped", zap.Error(err))
+	}
+}
+
+// invalidate drops the user's cached validations here and on every other
+// instance. It is called whenever the user's stored tokens change.
+func (m *TokenManager) invalidate(ctx context.Context, userID string) {
+	if m.validated == nil {
+		return
+	}
+	m.validated.ForgetUser(userID)
+	if m.revocations == nil {
+		return
+	}
+	if err := m.revocations.PublishRevocation(ctx, userID); err != nil {
+		zap.L().Warn("failed to publish token revocation", zap.String("user_id", userID), zap.Error(err))
+	}
+}
+
+// Policy returns the token policy of clientID, or of the default client when
+// clientID is empty.
+func (m *TokenManager) Policy(clientID string) (*domain.TokenPolicy, error) {
+	return m.policies.Get(clientID)
+}
+
+// GenerateTokenPair starts a new session for subject under the policy of
+// subject.ClientID. The refresh token lives until the session's absolute
+// expiry, but the session record only survives the policy's idle timeout
+// without a refresh.
+func (m *TokenManager) GenerateTokenPair(ctx context.Context, subject domain.TokenSubject, grant string) (*domain.TokenPair, error) {
+	policy, err := m.policies.Get(subject.ClientID)
 	if err != nil {
 		return nil, err
 	}
+	if !policy.AllowsGrant(grant) {
+		return nil, domain.ErrGrantNotAllowed
+	}
+	subject.ClientID = policy.ClientID
+
+	now := time.Now()
+	session := &domain.Session{
+		UserID:     subject.UserID,
+		ClientID:   policy.ClientID,
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(policy.AbsoluteTimeout),
+	}
+	sessionTTL := policy.SessionTTL(session, now)
+	accessTTL := min(policy.AccessTokenTTL, sessionTTL)
 
-	refreshToken, err := generateToken(userID, secret, "refresh", time.Hour*24*7)
+	session.AccessTokenID = uuid.NewString()
+	session.AccessExpiresAt = now.Add(accessTTL)
+	accessToken, err := generateToken(subject, policy, m.secret, "access", session.AccessTokenID, session.AccessExpiresAt)
 	if err != nil {
 		return nil, err
 	}
 
-	accessKey := fmt.Sprintf("access:%s", userID)
-	if err := cache.SetToken(ctx, accessKey, accessToken, time.Minute*15); err != nil {
-		return nil, err
+	userID := subject.UserID
+	if m.mode == RevocationAllowlist {
+		accessKey := accessTokenKey(userID, subject.Role, session.AccessTokenID)
+		if err := m.cache.SetToken(ctx, accessKey, m.hashToken(accessToken), accessTTL); err != nil {
+			return nil, err
+		}
+		if subject.Role != domain.RoleService {
+			m.invalidate(ctx, userID)
+		}
 	}
 
-	refreshKey := fmt.Sprintf("refresh:%s", userID)
-	if err := cache.SetToken(ctx, refreshKey, refreshToken, time.Hour*24*7); err != nil {
+	// Client credentials grants never get a refresh token (RFC 6749 4.4.3).
+	var refreshToken string
+	if grant != domain.GrantClientCredentials && policy.Refresh != domain.RefreshDisabled && policy.AllowsGrant(domain.GrantRefreshToken) {
+		session.RefreshTokenID = uuid.NewString()
+		refreshToken, err = generateToken(subject, policy, m.secret, "refresh", session.RefreshTokenID, session.ExpiresAt)
+		if err != nil {
+			return nil, err
+		}
+
+		if m.mode == RevocationAllowlist {
+			refreshKey := fmt.Sprintf("refresh:%s", userID)
+			if err := m.cache.SetToken(ctx, refreshKey, m.hashToken(refreshToken), sessionTTL); err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		sessionTTL = accessTTL
+	}
+
+	if err := m.sessions.Save(ctx, session, sessionTTL); err != nil {
 		return nil, err
 	}
 
@@ -38,27 +183,46 @@ func GenerateTokenPairWithCache(ctx context.Context, userID string, secret strin
 	}, nil
 }
 
-func generateToken(userID, secret, tokenType string, duration time.Duration) (string, error) {
+func generateToken(subject domain.TokenSubject, policy *domain.TokenPolicy, secret, tokenType, id string, expiresAt time.Time) (string, error) {
 	claims := domain.Claims{
-		UserID: userID,
-		Type:   tokenType,
+		UserID:       subject.UserID,
+		Type:         tokenType,
+		Role:         subject.Role,
+		ClientID:     subject.ClientID,
+		AMR:          subject.AMR,
+		ACR:          subject.ACR,
+		TokenVersion: subject.TokenVersion,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(duration)),
+			ID:        id,
+			Audience:  policy.Audience,
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
+	if !subject.AuthTime.IsZero() {
+		claims.AuthTime = jwt.NewNumericDate(subject.AuthTime)
+	}
+	if subject.Confirmation != (domain.Confirmation{}) {
+		claims.Confirmation = &subject.Confirmation
+	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString([]byte(secret))
 }
 
-func ParseToken(tokenStr, secret string) (*domain.Claims, error) {
+// ParseToken verifies the signature and expiry of tokenStr and, unless
+// audience is empty, that it was issued for audience.
+func ParseToken(tokenStr, secret, audience string) (*domain.Claims, error) {
+	var opts []jwt.ParserOption
+	if audience != "" {
+		opts = append(opts, jwt.WithAudience(audience))
+	}
 	token, err := jwt.ParseWithClaims(tokenStr, &domain.Claims{}, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, errors.New("invalid signing method")
 		}
 		return []byte(secret), nil
-	})
+	}, opts...)
 
 	if err != nil {
 		return nil, err
@@ -71,121 +235,263 @@ func ParseToken(tokenStr, secret string) (*domain.Claims, error) {
 	return nil, errors.New("invalid token")
 }
 
-func RefreshAccessTokenWithCache(ctx context.Context, refreshToken, secret string, cache ports.CachePort) (string, error) {
-	claims, err := ParseToken(refreshToken, secret)
+// RefreshAccessToken issues a new access token. Under a sliding policy it also
+// moves the session's idle expiry forward, never past its absolute expiry.
+// jkt is the thumbprint of the DPoP proof sent with the request, if any; it
+// must match the key a bound refresh token was issued to. The refresh token's
+// claims are returned once it has been validated, even if refreshing fails.
+func (m *TokenManager) RefreshAccessToken(ctx context.Context, refreshToken, jkt string) (string, *domain.Claims, error) {
+	claims, err := m.ValidateRefreshToken(ctx, refreshToken)
 	if err != nil {
-		return "", err
+		return "", nil, err
 	}
-
-	if claims.Type != "refresh" {
-		return "", errors.New("invalid token type")
+	var boundJKT string
+	if claims.Confirmation != nil {
+		boundJKT = claims.Confirmation.JKT
+	}
+	if boundJKT != jkt {
+		return "", claims, domain.ErrInvalidDPoPProof
 	}
 
-	refreshKey := fmt.Sprintf("refresh:%s", claims.UserID)
-	storedToken, err := cache.GetToken(ctx, refreshKey)
+	policy, err := m.policies.Get(claims.ClientID)
 	if err != nil {
-		return "", errors.New("refresh token expired or not found")
+		return "", claims, err
 	}
-
-	if storedToken != refreshToken {
-		return "", errors.New("invalid refresh token")
+	if policy.Refresh == domain.RefreshDisabled || !policy.AllowsGrant(domain.GrantRefreshToken) {
+		return "", claims, domain.ErrRefreshNotAllowed
 	}
 
-	accessToken, err := generateToken(claims.UserID, secret, "access", time.Minute*15)
+	now := time.Now()
+	session, err := m.sessions.Get(ctx, claims.UserID)
 	if err != nil {
-		return "", err
+		return "", claims, errors.New("session expired or not found")
 	}
-
-	accessKey := fmt.Sprintf("access:%s", claims.UserID)
-	if err := cache.SetToken(ctx, accessKey, accessToken, time.Minute*15); err != nil {
-		return "", err
+	if !now.Before(session.ExpiresAt) {
+		m.RevokeToken(ctx, claims.UserID)
+		return "", claims, errors.New("session expired")
+	}
+	// Only the refresh token of the user's current session may be used; in
+	// allowlist mode the stored token comparison already ensures this.
+	if m.mode == RevocationDenylist && session.RefreshTokenID != claims.ID {
+		return "", claims, errors.New("invalid refresh token")
 	}
 
-	return accessToken, nil
-}
+	session.LastSeenAt = now
+	sessionTTL := policy.SessionTTL(session, now)
+	accessTTL := min(policy.AccessTokenTTL, sessionTTL)
 
-func ValidateAccessToken(tokenStr, secret string) (string, error) {
-	claims, err := ParseToken(tokenStr, secret)
+	session.AccessTokenID = uuid.NewString()
+	session.AccessExpiresAt = now.Add(accessTTL)
+	accessToken, err := generateToken(claims.TokenSubject(), policy, m.secret, "access", session.AccessTokenID, session.AccessExpiresAt)
 	if err != nil {
-		return "", err
+		return "", claims, err
 	}
 
-	if claims.Type != "access" {
-		return "", errors.New("invalid token type")
+	if m.mode == RevocationAllowlist {
+		accessKey := accessTokenKey(claims.UserID, claims.Role, session.AccessTokenID)
+		if err := m.cache.SetToken(ctx, accessKey, m.hashToken(accessToken), accessTTL); err != nil {
+			return "", claims, err
+		}
+		m.invalidate(ctx, claims.UserID)
+	}
+
+	if m.mode == RevocationAllowlist && policy.Refresh == domain.RefreshSliding {
+		refreshKey := fmt.Sprintf("refresh:%s", claims.UserID)
+		if err := m.cache.SetToken(ctx, refreshKey, m.hashToken(refreshToken), sessionTTL); err != nil {
+			return "", claims, err
+		}
+	}
+
+	if err := m.sessions.Save(ctx, session, sessionTTL); err != nil {
+		return "", claims, err
 	}
 
-	return claims.UserID, nil
+	return accessToken, claims, nil
 }
-func ValidateAccessTokenWithCache(ctx context.Context, tokenStr, secret string, cache ports.CachePort) (string, error) {
-	claims, err := ParseToken(tokenStr, secret)
+
+// ValidateAccessToken parses and checks tokenStr against the token cache.
+// Successful validations are remembered in process for a short time, so hot
+// tokens skip both the parse and the Redis round trips.
+func (m *TokenManager) ValidateAccessToken(ctx context.Context, tokenStr string) (*domain.Claims, error) {
+	if claims, ok := m.validated.Get(tokenStr); ok {
+		return claims, nil
+	}
+	generation := m.validated.Generation()
+
+	claims, err := ParseToken(tokenStr, m.secret, m.policies.Audience)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	if claims.Type != "access" {
-		return "", errors.New("invalid token type")
+		return nil, errors.New("invalid token type")
 	}
 
-	accessKey := fmt.Sprintf("access:%s", claims.UserID)
-	storedToken, err := cache.GetToken(ctx, accessKey)
-	if err != nil {
-		return "", errors.New("access token expired or not found")
+	if err := m.checkNotRevoked(ctx, claims, tokenStr); err != nil {
+		return nil, err
 	}
 
-	if storedToken != tokenStr {
-		return "", errors.New("invalid access token")
+	if err := m.checkTokenVersion(ctx, claims); err != nil {
+		return nil, err
 	}
 
-	return claims.UserID, nil
+	m.validated.Add(tokenStr, claims, generation)
+	return claims, nil
 }
 
-func ValidateRefreshToken(tokenStr, secret string) (string, error) {
-	claims, err := ParseToken(tokenStr, secret)
+func (m *TokenManager) ValidateRefreshToken(ctx context.Context, tokenStr string) (*domain.Claims, error) {
+	claims, err := ParseToken(tokenStr, m.secret, m.policies.Audience)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	if claims.Type != "refresh" {
-		return "", errors.New("invalid token type")
+		return nil, errors.New("invalid token type")
+	}
+
+	if err := m.checkNotRevoked(ctx, claims, tokenStr); err != nil {
+		return nil, err
 	}
 
-	return claims.UserID, nil
+	if err := m.checkTokenVersion(ctx, claims); err != nil {
+		return nil, err
+	}
+
+	return claims, nil
 }
 
-func ValidateRefreshTokenWithCache(ctx context.Context, tokenStr, secret string, cache ports.CachePort) (string, error) {
-	claims, err := ParseToken(tokenStr, secret)
+// checkNotRevoked applies the revocation mode: in allowlist mode the token
+// must be the one stored for its user, in denylist mode its jti must not have
+// been revoked.
+func (m *TokenManager) checkNotRevoked(ctx context.Context, claims *domain.Claims, tokenStr string) error {
+	if m.mode == RevocationDenylist {
+		revoked, err := m.denylist.IsRevoked(ctx, claims.ID)
+		if err != nil {
+			return errors.New("token revocation lookup failed")
+		}
+		if revoked {
+			return errors.New("token has been revoked")
+		}
+		return nil
+	}
+
+	key := fmt.Sprintf("refresh:%s", claims.UserID)
+	if claims.Type == "access" {
+		key = accessTokenKey(claims.UserID, claims.Role, claims.ID)
+	}
+	storedToken, err := m.cache.GetToken(ctx, key)
 	if err != nil {
-		return "", err
+		return fmt.Errorf("%s token expired or not found", claims.Type)
+	}
+	if !m.matchesStored(storedToken, tokenStr) {
+		return fmt.Errorf("invalid %s token", claims.Type)
 	}
+	return nil
+}
 
-	if claims.Type != "refresh" {
-		return "", errors.New("invalid token type")
+// accessTokenKey is where the allowlist stores an access token. A user has
+// one access token at a time, but every replica of a service gets its own,
+// so service tokens are stored by jti.
+func accessTokenKey(userID, role, jti string) string {
+	if role == domain.RoleService {
+		return fmt.Sprintf("access:%s:%s", userID, jti)
 	}
+	return fmt.Sprintf("access:%s", userID)
+}
 
-	refreshKey := fmt.Sprintf("refresh:%s", claims.UserID)
-	storedToken, err := cache.GetToken(ctx, refreshKey)
-	if err != nil {
-		return "", errors.New("refresh token expired or not found")
+// hashToken returns the keyed hash stored in Redis in place of tokenStr, so
+// that Redis contents and backups cannot be replayed as tokens.
+func (m *TokenManager) hashToken(tokenStr string) string {
+	mac := hmac.New(sha256.New, m.hashKey)
+	mac.Write([]byte(tokenStr))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// matchesStored compares tokenStr with a stored hash. Entries written before
+// tokens were hashed hold the raw token; they are only accepted while
+// acceptUnhashed is set, and a sliding refresh rewrites them hashed.
+func (m *TokenManager) matchesStored(stored, tokenStr string) bool {
+	if hmac.Equal([]byte(stored), []byte(m.hashToken(tokenStr))) {
+		return true
 	}
+	// TODO: remove together with TOKEN_ACCEPT_UNHASHED in the next release.
+	return m.acceptUnhashed && subtle.ConstantTimeCompare([]byte(stored), []byte(tokenStr)) == 1
+}
 
-	if storedToken != tokenStr {
-		return "", errors.New("invalid refresh token")
+// checkTokenVersion rejects tokens issued before the user's token version was
+// last bumped. Service tokens have no user record and are not versioned.
+func (m *TokenManager) checkTokenVersion(ctx context.Context, claims *domain.Claims) error {
+	if m.versions == nil || claims.Role == domain.RoleService {
+		return nil
 	}
 
-	return claims.UserID, nil
+	current, err := m.versions.TokenVersion(ctx, claims.UserID)
+	if err != nil {
+		return errors.New("token version lookup failed")
+	}
+	if claims.TokenVersion < current {
+		return errors.New("token has been revoked")
+	}
+	return nil
 }
 
-func RevokeToken(ctx context.Context, userID string, cache ports.CachePort) error {
+// RevokeToken ends the user's session. In denylist mode the session's
+// current tokens are added to the denylist.
+func (m *TokenManager) RevokeToken(ctx context.Context, userID string) error {
+	if m.mode == RevocationDenylist {
+		if session, err := m.sessions.Get(ctx, userID); err == nil {
+			if err := m.deny(ctx, session.AccessTokenID, session.AccessExpiresAt); err != nil {
+				return err
+			}
+			if err := m.deny(ctx, session.RefreshTokenID, session.ExpiresAt); err != nil {
+				return err
+			}
+		}
+		m.invalidate(ctx, userID)
+		return m.sessions.Delete(ctx, userID)
+	}
+
 	accessKey := fmt.Sprintf("access:%s", userID)
 	refreshKey := fmt.Sprintf("refresh:%s", userID)
 
-	if err := cache.DeleteToken(ctx, accessKey); err != nil {
+	if err := m.cache.DeleteToken(ctx, accessKey); err != nil {
 		return err
 	}
+	m.invalidate(ctx, userID)
 
-	if err := cache.DeleteToken(ctx, refreshKey); err != nil {
+	if err := m.cache.DeleteToken(ctx, refreshKey); err != nil {
 		return err
 	}
 
+	return m.sessions.Delete(ctx, userID)
+}
+
+// RevokeAccessToken revokes a single access token, such as the one presented
+// on logout, which a refresh may already have replaced in the session. In
+// allowlist mode only the stored token is ever valid, so RevokeToken covers
+// it, except for service tokens, which are stored one per jti.
+func (m *TokenManager) RevokeAccessToken(ctx context.Context, claims *domain.Claims) error {
+	if m.mode == RevocationAllowlist && claims.Role == domain.RoleService {
+		if err := m.cache.DeleteToken(ctx, accessTokenKey(claims.UserID, claims.Role, claims.ID)); err != nil {
+			return err
+		}
+		m.invalidate(ctx, claims.UserID)
+		return nil
+	}
+	if m.mode != RevocationDenylist || claims.ExpiresAt == nil {
+		return nil
+	}
+	if err := m.deny(ctx, claims.ID, claims.ExpiresAt.Time); err != nil {
+		return err
+	}
+	m.invalidate(ctx, claims.UserID)
 	return nil
 }
+
+func (m *TokenManager) deny(ctx context.Context, jti string, expiresAt time.Time) error {
+	ttl := time.Until(expiresAt)
+	if jti == "" || ttl <= 0 {
+		return nil
+	}
+	return m.denylist.Revoke(ctx, jti, ttl)
+}
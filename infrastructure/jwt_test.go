@@ -0,0 +1,252 @@
+package infrastructure
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kanta/backend-challenge/internal/core/domain"
+)
+
+// memoryStore stands in for the Redis adapters: it is the token cache, the
+// denylist, the session store and the token version lookup at once.
+type memoryStore struct {
+	mu       sync.Mutex
+	values   map[string]string
+	sessions map[string]*domain.Session
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{values: map[string]string{}, sessions: map[string]*domain.Session{}}
+}
+
+func (s *memoryStore) SetToken(_ context.Context, key, value string, _ time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values[key] = value
+	return nil
+}
+
+func (s *memoryStore) GetToken(_ context.Context, key string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	value, ok := s.values[key]
+	if !ok {
+		return "", errors.New("not found")
+	}
+	return value, nil
+}
+
+func (s *memoryStore) DeleteToken(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.values, key)
+	return nil
+}
+
+func (s *memoryStore) TakeToken(ctx context.Context, key string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	value, ok := s.values[key]
+	if !ok {
+		return "", errors.New("not found")
+	}
+	delete(s.values, key)
+	return value, nil
+}
+
+func (s *memoryStore) Revoke(ctx context.Context, jti string, ttl time.Duration) error {
+	return s.SetToken(ctx, "denylist:"+jti, "1", ttl)
+}
+
+func (s *memoryStore) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	_, err := s.GetToken(ctx, "denylist:"+jti)
+	return err == nil, nil
+}
+
+func (s *memoryStore) Save(_ context.Context, session *domain.Session, _ time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored := *session
+	s.sessions[session.UserID] = &stored
+	return nil
+}
+
+func (s *memoryStore) Get(_ context.Context, userID string) (*domain.Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session, ok := s.sessions[userID]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	stored := *session
+	return &stored, nil
+}
+
+func (s *memoryStore) Delete(_ context.Context, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, userID)
+	return nil
+}
+
+// TokenVersion reads the version from the token cache like the user
+// repository does on a cache hit.
+func (s *memoryStore) TokenVersion(ctx context.Context, userID string) (int, error) {
+	cached, err := s.GetToken(ctx, "token_version:"+userID)
+	if err != nil {
+		return 0, nil
+	}
+	return strconv.Atoi(cached)
+}
+
+func newTestTokenManager(tb testing.TB, mode string, validated *ValidationCache) *TokenManager {
+	tb.Helper()
+	policies, err := LoadTokenPolicies("", domain.SessionPolicy{
+		AccessTokenTTL:  15 * time.Minute,
+		IdleTimeout:     time.Hour,
+		AbsoluteTimeout: time.Hour,
+	}, "backend-api")
+	if err != nil {
+		tb.Fatal(err)
+	}
+	store := newMemoryStore()
+	return NewTokenManager("test-secret", mode, false, store, store, store, policies, store, validated, nil)
+}
+
+// BenchmarkValidateAccessToken measures the per-request cost of access token
+// validation with and without the in-process validation cache. The stores
+// are in memory, so uncached results exclude Redis round trips.
+func BenchmarkValidateAccessToken(b *testing.B) {
+	for _, mode := range []string{RevocationAllowlist, RevocationDenylist} {
+		for _, cache := range []struct {
+			name      string
+			validated func() *ValidationCache
+		}{
+			{"uncached", func() *ValidationCache { return nil }},
+			{"cached", func() *ValidationCache { return NewValidationCache(10000, 30*time.Second) }},
+		} {
+			b.Run(mode+"/"+cache.name, func(b *testing.B) {
+				ctx := context.Background()
+				tokens := newTestTokenManager(b, mode, cache.validated())
+				pair, err := tokens.GenerateTokenPair(ctx, domain.TokenSubject{
+					UserID:   "user-1",
+					Role:     domain.RoleUser,
+					AuthTime: time.Now(),
+				}, domain.GrantPassword)
+				if err != nil {
+					b.Fatal(err)
+				}
+
+				b.ReportAllocs()
+				b.ResetTimer()
+				b.RunParallel(func(pb *testing.PB) {
+					for pb.Next() {
+						if _, err := tokens.ValidateAccessToken(ctx, pair.AccessToken); err != nil {
+							b.Error(err)
+							return
+						}
+					}
+				})
+			})
+		}
+	}
+}
+
+// BenchmarkValidateAccessTokenBaseline measures the validation path the
+// cache replaced: a Redis GET of the stored access token, then a second parse
+// of the same JWT by the middleware. The GET hits the in-memory store, so
+// like the uncached results above it excludes the Redis round trip.
+func BenchmarkValidateAccessTokenBaseline(b *testing.B) {
+	ctx := context.Background()
+	tokens := newTestTokenManager(b, RevocationAllowlist, nil)
+	pair, err := tokens.GenerateTokenPair(ctx, domain.TokenSubject{
+		UserID:   "user-1",
+		Role:     domain.RoleUser,
+		AuthTime: time.Now(),
+	}, domain.GrantPassword)
+	if err != nil {
+		b.Fatal(err)
+	}
+	// The old allowlist stored the token itself rather than its hash.
+	if err := tokens.cache.SetToken(ctx, "access:user-1", pair.AccessToken, time.Hour); err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			claims, err := ParseToken(pair.AccessToken, tokens.secret, tokens.policies.Audience)
+			if err != nil {
+				b.Error(err)
+				return
+			}
+			stored, err := tokens.cache.GetToken(ctx, "access:"+claims.UserID)
+			if err != nil || stored != pair.AccessToken {
+				b.Error("stored access token does not match")
+				return
+			}
+			if _, err := ParseToken(pair.AccessToken, tokens.secret, tokens.policies.Audience); err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+}
+
+func TestAllowlistKeepsEveryServiceReplicaToken(t *testing.T) {
+	ctx := context.Background()
+	tokens := newTestTokenManager(t, RevocationAllowlist, nil)
+	tokens.policies.Clients["billing"] = &domain.TokenPolicy{
+		ClientID:      "billing",
+		ClientType:    domain.ClientTypeService,
+		GrantTypes:    []string{domain.GrantClientCredentials},
+		Audience:      []string{tokens.policies.Audience},
+		SessionPolicy: tokens.policies.Clients[domain.ClientTypeWeb].SessionPolicy,
+	}
+
+	issue := func(subject domain.TokenSubject, grant string) string {
+		t.Helper()
+		pair, err := tokens.GenerateTokenPair(ctx, subject, grant)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return pair.AccessToken
+	}
+	service := domain.TokenSubject{UserID: "service:billing", Role: domain.RoleService, ClientID: "billing"}
+	replicaA := issue(service, domain.GrantClientCredentials)
+	replicaB := issue(service, domain.GrantClientCredentials)
+
+	for name, token := range map[string]string{"replica a": replicaA, "replica b": replicaB} {
+		if _, err := tokens.ValidateAccessToken(ctx, token); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+
+	// Revoking one replica's token leaves the other one valid.
+	claims, err := tokens.ValidateAccessToken(ctx, replicaA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tokens.RevokeAccessToken(ctx, claims); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tokens.ValidateAccessToken(ctx, replicaA); err == nil {
+		t.Error("revoked service token is still valid")
+	}
+	if _, err := tokens.ValidateAccessToken(ctx, replicaB); err != nil {
+		t.Errorf("replica b: %v", err)
+	}
+
+	// A user still has one access token at a time.
+	user := domain.TokenSubject{UserID: "user-1", Role: domain.RoleUser}
+	first := issue(user, domain.GrantPassword)
+	issue(user, domain.GrantPassword)
+	if _, err := tokens.ValidateAccessToken(ctx, first); err == nil {
+		t.Error("replaced user token is still valid")
+	}
+}
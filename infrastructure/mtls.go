@@ -0,0 +1,113 @@
+package infrastructure
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/kanta/backend-challenge/internal/core/domain"
+)
+
+// NewServerTLSConfig loads the server key pair and, when clientCAFile is set,
+// verifies client certificates against it. clientAuth is "request" to verify
+// certificates only when presented, or "require" to reject connections
+// without one.
+func NewServerTLSConfig(certFile, keyFile, clientCAFile, clientAuth string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	cfg := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if clientCAFile == "" {
+		return cfg, nil
+	}
+
+	pem, err := os.ReadFile(clientCAFile)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in %s", clientCAFile)
+	}
+	cfg.ClientCAs = pool
+
+	switch clientAuth {
+	case "", "request":
+		cfg.ClientAuth = tls.VerifyClientCertIfGiven
+	case "require":
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	default:
+		return nil, fmt.Errorf("unknown client auth mode %q", clientAuth)
+	}
+	return cfg, nil
+}
+
+// CertificateThumbprint returns the x5t#S256 value of cert.
+func CertificateThumbprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+type ServiceIdentities struct {
+	identities []domain.ServiceIdentity
+}
+
+type serviceIdentityFile struct {
+	Services []domain.ServiceIdentity `json:"services"`
+}
+
+// LoadServiceIdentities reads the certificate to service mapping from a JSON
+// file. An empty path configures no services.
+func LoadServiceIdentities(path string) (*ServiceIdentities, error) {
+	if path == "" {
+		return &ServiceIdentities{}, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file serviceIdentityFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	for _, identity := range file.Services {
+		if identity.Name == "" || identity.ClientID == "" {
+			return nil, errors.New("service identity needs a name and client_id")
+		}
+		if identity.Subject == "" && identity.CommonName == "" && identity.DNSName == "" {
+			return nil, fmt.Errorf("service %q: no certificate matcher configured", identity.Name)
+		}
+	}
+	return &ServiceIdentities{identities: file.Services}, nil
+}
+
+// Identify returns the service the verified certificate belongs to.
+func (s *ServiceIdentities) Identify(cert *x509.Certificate) (*domain.ServiceIdentity, error) {
+	for i := range s.identities {
+		identity := &s.identities[i]
+		if identity.Subject != "" && identity.Subject != cert.Subject.String() {
+			continue
+		}
+		if identity.CommonName != "" && identity.CommonName != cert.Subject.CommonName {
+			continue
+		}
+		if identity.DNSName != "" && !slices.Contains(cert.DNSNames, identity.DNSName) {
+			continue
+		}
+		return identity, nil
+	}
+	return nil, domain.ErrUnknownServiceIdentity
+}
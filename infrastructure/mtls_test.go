@@ -0,0 +1,169 @@
+package infrastructure
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/kanta/backend-challenge/internal/core/domain"
+)
+
+type testCA struct {
+	cert   *x509.Certificate
+	key    *ecdsa.PrivateKey
+	serial int64
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCA{cert: cert, key: key, serial: 1}
+}
+
+// issue signs a client certificate and returns it after verifying it chains
+// to the CA, as the TLS handshake would.
+func (ca *testCA) issue(t *testing.T, subject pkix.Name, dnsNames ...string) *x509.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca.serial++
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(ca.serial),
+		Subject:      subject,
+		DNSNames:     dnsNames,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	if _, err := cert.Verify(x509.VerifyOptions{
+		Roots:     roots,
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}); err != nil {
+		t.Fatalf("issued certificate does not verify: %v", err)
+	}
+	return cert
+}
+
+func TestServiceIdentitiesIdentify(t *testing.T) {
+	ca := newTestCA(t)
+	identities := &ServiceIdentities{identities: []domain.ServiceIdentity{
+		{Name: "billing", Subject: "CN=billing,O=Example", ClientID: "billing"},
+		{Name: "reports", CommonName: "reports", ClientID: "reports"},
+		{Name: "search", DNSName: "search.internal", ClientID: "search"},
+		{Name: "audit", CommonName: "audit", DNSName: "audit.internal", ClientID: "audit"},
+	}}
+
+	tests := []struct {
+		name     string
+		subject  pkix.Name
+		dnsNames []string
+		want     string
+		wantErr  error
+	}{
+		{
+			name:    "full subject",
+			subject: pkix.Name{CommonName: "billing", Organization: []string{"Example"}},
+			want:    "billing",
+		},
+		{
+			name:    "subject with another organization",
+			subject: pkix.Name{CommonName: "billing", Organization: []string{"Other"}},
+			wantErr: domain.ErrUnknownServiceIdentity,
+		},
+		{
+			name:    "common name",
+			subject: pkix.Name{CommonName: "reports", Organization: []string{"Anything"}},
+			want:    "reports",
+		},
+		{
+			name:     "dns name",
+			subject:  pkix.Name{CommonName: "search-1"},
+			dnsNames: []string{"search-1.internal", "search.internal"},
+			want:     "search",
+		},
+		{
+			name:     "all matchers must match",
+			subject:  pkix.Name{CommonName: "audit"},
+			dnsNames: []string{"audit.internal"},
+			want:     "audit",
+		},
+		{
+			name:     "common name without its dns name",
+			subject:  pkix.Name{CommonName: "audit"},
+			dnsNames: []string{"other.internal"},
+			wantErr:  domain.ErrUnknownServiceIdentity,
+		},
+		{
+			name:    "unknown certificate",
+			subject: pkix.Name{CommonName: "intruder"},
+			wantErr: domain.ErrUnknownServiceIdentity,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cert := ca.issue(t, tt.subject, tt.dnsNames...)
+			identity, err := identities.Identify(cert)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Identify() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if identity.Name != tt.want {
+				t.Fatalf("Identify() = %q, want %q", identity.Name, tt.want)
+			}
+		})
+	}
+}
+
+func TestServiceIdentitiesIdentifyNoServices(t *testing.T) {
+	identities, err := LoadServiceIdentities("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert := newTestCA(t).issue(t, pkix.Name{CommonName: "billing"})
+	if _, err := identities.Identify(cert); !errors.Is(err, domain.ErrUnknownServiceIdentity) {
+		t.Fatalf("Identify() error = %v, want %v", err, domain.ErrUnknownServiceIdentity)
+	}
+}
@@ -45,7 +45,27 @@ func NewPostgresClient(host, user, password, dbname, port string) *gorm.DB {
 	if err := db.AutoMigrate(models.GetAllModels()...); err != nil {
 		log.Fatalf("failed to migrate database: %v", err)
 	}
+	for _, statement := range models.GetPostMigrations() {
+		if err := db.Exec(statement).Error; err != nil {
+			log.Fatalf("failed to migrate database: %v", err)
+		}
+	}
 
 	log.Println("Connected to PostgreSQL successfully")
 	return db
 }
+
+// NewReadOnlyPostgresClient connects without migrating, in sessions that
+// reject writes, for tools that only inspect the database.
+func NewReadOnlyPostgresClient(host, user, password, dbname, port string) *gorm.DB {
+	dsn := fmt.Sprintf(
+		"host=%s user=%s password=%s dbname=%s port=%s sslmode=disable default_transaction_read_only=on",
+		host, user, password, dbname, port,
+	)
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	if err != nil {
+		log.Fatalf("failed to connect to postgres: %v", err)
+	}
+	return db
+}
@@ -0,0 +1,145 @@
+package infrastructure
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/kanta/backend-challenge/internal/core/domain"
+)
+
+type tokenPolicyFile struct {
+	DefaultClient string                     `json:"default_client"`
+	Clients       map[string]tokenPolicyJSON `json:"clients"`
+}
+
+type tokenPolicyJSON struct {
+	Type            string   `json:"type"`
+	Audience        []string `json:"audience"`
+	GrantTypes      []string `json:"grant_types"`
+	Refresh         string   `json:"refresh"`
+	AccessTokenTTL  string   `json:"access_token_ttl"`
+	IdleTimeout     string   `json:"idle_timeout"`
+	AbsoluteTimeout string   `json:"absolute_timeout"`
+}
+
+// LoadTokenPolicies reads per-client token policies from a JSON file. Values
+// a client leaves out are taken from defaults. Without a file a single "web"
+// client using defaults is configured. Clients without an audience get
+// audience, the one this API accepts, and every client's audience must
+// include it.
+func LoadTokenPolicies(path string, defaults domain.SessionPolicy, audience string) (*domain.TokenPolicies, error) {
+	policies, err := loadTokenPolicies(path, defaults)
+	if err != nil {
+		return nil, err
+	}
+	if audience == "" {
+		return policies, nil
+	}
+
+	policies.Audience = audience
+	for clientID, policy := range policies.Clients {
+		if len(policy.Audience) == 0 {
+			policy.Audience = []string{audience}
+		}
+		if !slices.Contains(policy.Audience, audience) {
+			return nil, fmt.Errorf("client %q: audience must include %q, or its tokens would be rejected", clientID, audience)
+		}
+	}
+	return policies, nil
+}
+
+func loadTokenPolicies(path string, defaults domain.SessionPolicy) (*domain.TokenPolicies, error) {
+	policies := &domain.TokenPolicies{
+		DefaultClient: domain.ClientTypeWeb,
+		Clients: map[string]*domain.TokenPolicy{
+			domain.ClientTypeWeb: {
+				ClientID:      domain.ClientTypeWeb,
+				ClientType:    domain.ClientTypeWeb,
+				GrantTypes:    []string{domain.GrantPassword, domain.GrantRefreshToken},
+				Refresh:       domain.RefreshSliding,
+				SessionPolicy: defaults,
+			},
+		},
+	}
+	if path == "" {
+		return policies, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file tokenPolicyFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	for clientID, raw := range file.Clients {
+		policy, err := raw.toDomain(clientID, defaults)
+		if err != nil {
+			return nil, fmt.Errorf("client %q: %w", clientID, err)
+		}
+		policies.Clients[clientID] = policy
+	}
+
+	if file.DefaultClient != "" {
+		policies.DefaultClient = file.DefaultClient
+	}
+	if _, ok := policies.Clients[policies.DefaultClient]; !ok {
+		return nil, fmt.Errorf("default client %q is not configured", policies.DefaultClient)
+	}
+	return policies, nil
+}
+
+func (p tokenPolicyJSON) toDomain(clientID string, defaults domain.SessionPolicy) (*domain.TokenPolicy, error) {
+	policy := &domain.TokenPolicy{
+		ClientID:      clientID,
+		ClientType:    p.Type,
+		Audience:      p.Audience,
+		GrantTypes:    p.GrantTypes,
+		Refresh:       p.Refresh,
+		SessionPolicy: defaults,
+	}
+
+	switch policy.ClientType {
+	case domain.ClientTypeWeb, domain.ClientTypeMobile, domain.ClientTypeCLI, domain.ClientTypeService:
+	default:
+		return nil, fmt.Errorf("unknown client type %q", policy.ClientType)
+	}
+
+	switch policy.Refresh {
+	case "":
+		policy.Refresh = domain.RefreshSliding
+	case domain.RefreshSliding, domain.RefreshFixed, domain.RefreshDisabled:
+	default:
+		return nil, fmt.Errorf("unknown refresh behaviour %q", policy.Refresh)
+	}
+
+	if len(policy.GrantTypes) == 0 {
+		policy.GrantTypes = []string{domain.GrantPassword, domain.GrantRefreshToken}
+	}
+
+	for _, d := range []struct {
+		value  string
+		target *time.Duration
+	}{
+		{p.AccessTokenTTL, &policy.AccessTokenTTL},
+		{p.IdleTimeout, &policy.IdleTimeout},
+		{p.AbsoluteTimeout, &policy.AbsoluteTimeout},
+	} {
+		if d.value == "" {
+			continue
+		}
+		parsed, err := time.ParseDuration(d.value)
+		if err != nil {
+			return nil, err
+		}
+		*d.target = parsed
+	}
+
+	return policy, nil
+}
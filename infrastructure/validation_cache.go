@@ -0,0 +1,147 @@
+package infrastructure
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"sync"
+	"time"
+
+	"github.com/kanta/backend-challenge/internal/core/domain"
+)
+
+// ValidationCache is an in-process LRU of recently validated access tokens.
+// Entries live for at most ttl, so a revocation that is not delivered to this
+// instance still takes effect within that bound.
+type ValidationCache struct {
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration
+	entries map[[sha256.Size]byte]*list.Element
+	order   *list.List
+	// generation changes on every invalidation so that validations which
+	// started before it are not cached afterwards.
+	generation uint64
+}
+
+type validationEntry struct {
+	key       [sha256.Size]byte
+	claims    domain.Claims
+	expiresAt time.Time
+}
+
+// NewValidationCache returns nil, which disables caching, when size or ttl is
+// not positive.
+func NewValidationCache(size int, ttl time.Duration) *ValidationCache {
+	if size <= 0 || ttl <= 0 {
+		return nil
+	}
+	return &ValidationCache{
+		size:    size,
+		ttl:     ttl,
+		entries: make(map[[sha256.Size]byte]*list.Element, size),
+		order:   list.New(),
+	}
+}
+
+// Get returns a copy of the claims cached for token.
+func (v *ValidationCache) Get(token string) (*domain.Claims, bool) {
+	if v == nil {
+		return nil, false
+	}
+	key := sha256.Sum256([]byte(token))
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	elem, ok := v.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*validationEntry)
+	if !time.Now().Before(entry.expiresAt) {
+		v.remove(elem)
+		return nil, false
+	}
+	v.order.MoveToFront(elem)
+
+	claims := entry.claims
+	return &claims, true
+}
+
+// Generation returns the current invalidation generation, to be passed to Add
+// once validation finishes.
+func (v *ValidationCache) Generation() uint64 {
+	if v == nil {
+		return 0
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.generation
+}
+
+// Add caches claims for token unless an invalidation happened since
+// generation was read.
+func (v *ValidationCache) Add(token string, claims *domain.Claims, generation uint64) {
+	if v == nil {
+		return
+	}
+	expiresAt := time.Now().Add(v.ttl)
+	if claims.ExpiresAt != nil && claims.ExpiresAt.Before(expiresAt) {
+		expiresAt = claims.ExpiresAt.Time
+	}
+	key := sha256.Sum256([]byte(token))
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.generation != generation {
+		return
+	}
+	if elem, ok := v.entries[key]; ok {
+		v.remove(elem)
+	}
+	v.entries[key] = v.order.PushFront(&validationEntry{
+		key:       key,
+		claims:    *claims,
+		expiresAt: expiresAt,
+	})
+	for v.order.Len() > v.size {
+		v.remove(v.order.Back())
+	}
+}
+
+// ForgetUser drops every cached token of userID.
+func (v *ValidationCache) ForgetUser(userID string) {
+	if v == nil {
+		return
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.generation++
+	for elem := v.order.Front(); elem != nil; {
+		next := elem.Next()
+		if elem.Value.(*validationEntry).claims.UserID == userID {
+			v.remove(elem)
+		}
+		elem = next
+	}
+}
+
+// Clear drops every cached token, e.g. after revocations may have been missed.
+func (v *ValidationCache) Clear() {
+	if v == nil {
+		return
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.generation++
+	clear(v.entries)
+	v.order.Init()
+}
+
+func (v *ValidationCache) remove(elem *list.Element) {
+	delete(v.entries, elem.Value.(*validationEntry).key)
+	v.order.Remove(elem)
+}
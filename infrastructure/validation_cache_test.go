@@ -0,0 +1,139 @@
+package infrastructure
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kanta/backend-challenge/internal/core/domain"
+)
+
+// memoryBus stands in for the Redis revocation channel. Publishing calls
+// every subscriber before returning.
+type memoryBus struct {
+	mu          sync.Mutex
+	subscribers []func(userID string)
+	subscribed  chan struct{}
+}
+
+func newMemoryBus() *memoryBus {
+	return &memoryBus{subscribed: make(chan struct{}, 1)}
+}
+
+func (b *memoryBus) PublishRevocation(_ context.Context, userID string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, onRevoke := range b.subscribers {
+		onRevoke(userID)
+	}
+	return nil
+}
+
+func (b *memoryBus) SubscribeRevocations(ctx context.Context, onRevoke func(userID string), onReset func()) error {
+	b.mu.Lock()
+	b.subscribers = append(b.subscribers, onRevoke)
+	b.mu.Unlock()
+	onReset()
+	b.subscribed <- struct{}{}
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestRevocationBusEvictsCachedTokens(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	bus := newMemoryBus()
+	tokens := newTestTokenManager(t, RevocationAllowlist, NewValidationCache(100, time.Minute))
+	tokens.revocations = bus
+	go tokens.ListenForRevocations(ctx)
+	<-bus.subscribed
+
+	issue := func(userID string) string {
+		t.Helper()
+		pair, err := tokens.GenerateTokenPair(ctx, domain.TokenSubject{UserID: userID, Role: domain.RoleUser}, domain.GrantPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tokens.ValidateAccessToken(ctx, pair.AccessToken); err != nil {
+			t.Fatal(err)
+		}
+		return pair.AccessToken
+	}
+	revoked, kept := issue("user-1"), issue("user-2")
+
+	// Another instance revokes user-1, e.g. by bumping their token version.
+	if err := bus.PublishRevocation(ctx, "user-1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := tokens.validated.Get(revoked); ok {
+		t.Error("revoked user's token is still cached")
+	}
+	if _, ok := tokens.validated.Get(kept); !ok {
+		t.Error("other user's token was evicted")
+	}
+}
+
+func TestValidationCacheRejectsStaleGeneration(t *testing.T) {
+	cache := NewValidationCache(100, time.Minute)
+	claims := &domain.Claims{UserID: "user-1"}
+
+	// A validation that started before the revocation must not be cached
+	// after it.
+	stale := cache.Generation()
+	cache.ForgetUser("user-1")
+	cache.Add("token-1", claims, stale)
+	if _, ok := cache.Get("token-1"); ok {
+		t.Error("token validated before a revocation was cached")
+	}
+
+	stale = cache.Generation()
+	cache.Clear()
+	cache.Add("token-1", claims, stale)
+	if _, ok := cache.Get("token-1"); ok {
+		t.Error("token validated before a reset was cached")
+	}
+
+	cache.Add("token-1", claims, cache.Generation())
+	if _, ok := cache.Get("token-1"); !ok {
+		t.Error("token validated at the current generation was not cached")
+	}
+}
+
+// BenchmarkValidationCacheGet measures a cache hit, the cost every request
+// with a hot token pays instead of parsing and Redis lookups.
+func BenchmarkValidationCacheGet(b *testing.B) {
+	cache := NewValidationCache(10000, time.Minute)
+	tokens := make([]string, 1000)
+	for i := range tokens {
+		tokens[i] = "token-" + strconv.Itoa(i)
+		cache.Add(tokens[i], &domain.Claims{UserID: "user-" + strconv.Itoa(i)}, cache.Generation())
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			if _, ok := cache.Get(tokens[i%len(tokens)]); !ok {
+				b.Error("cache miss")
+				return
+			}
+			i++
+		}
+	})
+}
+
+// BenchmarkValidationCacheAdd measures inserting into a full cache, which
+// evicts the least recently used entry every time.
+func BenchmarkValidationCacheAdd(b *testing.B) {
+	cache := NewValidationCache(1000, time.Minute)
+	claims := &domain.Claims{UserID: "user-1"}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		cache.Add("token-"+strconv.Itoa(i), claims, cache.Generation())
+	}
+}
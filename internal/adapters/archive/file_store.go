@@ -0,0 +1,92 @@
+package archive
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/kanta/backend-challenge/internal/core/domain"
+	"github.com/kanta/backend-challenge/internal/core/ports"
+)
+
+// FileStore keeps data export archives as files in a directory. Instances
+// that serve downloads must share it, e.g. over a network volume.
+type FileStore struct {
+	dir string
+}
+
+func NewFileStore(dir string) (*FileStore, error) {
+	// Archives hold personal data, so only the service may read them.
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+var _ ports.ExportStore = (*FileStore)(nil)
+
+// Save writes to a temporary file first, so that a failed export never
+// leaves a partial archive behind.
+func (s *FileStore) Save(id string, fn func(w io.Writer) error) (int64, error) {
+	path, err := s.path(id)
+	if err != nil {
+		return 0, err
+	}
+
+	file, err := os.CreateTemp(s.dir, ".export-*")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(file.Name())
+
+	if err := fn(file); err != nil {
+		file.Close()
+		return 0, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return 0, err
+	}
+	if err := file.Close(); err != nil {
+		return 0, err
+	}
+	if err := os.Rename(file.Name(), path); err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+func (s *FileStore) Open(id string) (io.ReadCloser, error) {
+	path, err := s.path(id)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, domain.ErrExportNotFound
+	}
+	return file, err
+}
+
+func (s *FileStore) Delete(id string) error {
+	path, err := s.path(id)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (s *FileStore) path(id string) (string, error) {
+	if id == "" || filepath.Base(id) != id {
+		return "", fmt.Errorf("invalid export id %q", id)
+	}
+	return filepath.Join(s.dir, id+".json"), nil
+}
@@ -0,0 +1,63 @@
+package breach
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kanta/backend-challenge/internal/core/ports"
+)
+
+const prefixLength = 5
+
+type fileChecker struct {
+	dir string
+}
+
+// NewFileChecker checks passwords against a local copy of a k-anonymity
+// breached-password list. dir holds one file per 5 character SHA-1 prefix,
+// named <PREFIX>.txt, where every line is "<SUFFIX>:<COUNT>" as served by the
+// Pwned Passwords range API. An empty dir disables the check.
+func NewFileChecker(dir string) ports.BreachedPasswordChecker {
+	return &fileChecker{
+		dir: dir,
+	}
+}
+
+func (f *fileChecker) IsBreached(ctx context.Context, password string) (bool, error) {
+	if f.dir == "" {
+		return false, nil
+	}
+
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:prefixLength], hash[prefixLength:]
+
+	file, err := os.Open(filepath.Join(f.dir, prefix+".txt"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if err := ctx.Err(); err != nil {
+			return false, err
+		}
+		line := strings.TrimSpace(scanner.Text())
+		candidate, count, _ := strings.Cut(line, ":")
+		if strings.EqualFold(candidate, suffix) && count != "0" {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
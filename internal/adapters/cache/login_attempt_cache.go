@@ -0,0 +1,66 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/kanta/backend-challenge/internal/core/ports"
+	"github.com/redis/go-redis/v9"
+)
+
+type loginAttemptCache struct {
+	client redis.Cmdable
+}
+
+func NewLoginAttemptCache(client redis.Cmdable) ports.LoginAttemptPort {
+	return &loginAttemptCache{
+		client: client,
+	}
+}
+
+func (r *loginAttemptCache) RegisterFailure(ctx context.Context, key string, window time.Duration) (int64, error) {
+	failKey := "login:fail:" + key
+
+	pipe := r.client.TxPipeline()
+	incr := pipe.Incr(ctx, failKey)
+	// NX keeps the window fixed from the first failure instead of sliding it
+	// forward on every attempt.
+	pipe.ExpireNX(ctx, failKey, window)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return incr.Val(), nil
+}
+
+func (r *loginAttemptCache) GetFailures(ctx context.Context, key string) (int64, error) {
+	n, err := r.client.Get(ctx, "login:fail:"+key).Int64()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	return n, err
+}
+
+func (r *loginAttemptCache) ResetFailures(ctx context.Context, key string) error {
+	return r.client.Del(ctx, "login:fail:"+key).Err()
+}
+
+func (r *loginAttemptCache) Lock(ctx context.Context, key string, duration time.Duration) error {
+	return r.client.Set(ctx, "login:lock:"+key, time.Now().Unix(), duration).Err()
+}
+
+func (r *loginAttemptCache) LockedFor(ctx context.Context, key string) (time.Duration, error) {
+	ttl, err := r.client.PTTL(ctx, "login:lock:"+key).Result()
+	if err != nil {
+		return 0, err
+	}
+	// PTTL returns negative values when the key is missing or has no expiry.
+	if ttl < 0 {
+		return 0, nil
+	}
+	return ttl, nil
+}
+
+func (r *loginAttemptCache) Unlock(ctx context.Context, key string) error {
+	return r.client.Del(ctx, "login:lock:"+key, "login:fail:"+key).Err()
+}
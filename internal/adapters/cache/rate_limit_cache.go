@@ -0,0 +1,76 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/kanta/backend-challenge/internal/core/domain"
+	"github.com/kanta/backend-challenge/internal/core/ports"
+	"github.com/redis/go-redis/v9"
+)
+
+// slidingWindowScript implements a sliding window counter: the previous
+// window's count is weighted by how much of it still overlaps the sliding
+// window and added to the current window's count.
+var slidingWindowScript = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local elapsed = tonumber(ARGV[3])
+
+local current = tonumber(redis.call('GET', KEYS[1]) or '0')
+local previous = tonumber(redis.call('GET', KEYS[2]) or '0')
+local weighted = math.floor(previous * (window - elapsed) / window)
+
+if weighted + current >= limit then
+	return {0, weighted + current}
+end
+
+current = redis.call('INCR', KEYS[1])
+if current == 1 then
+	redis.call('PEXPIRE', KEYS[1], window * 2)
+end
+return {1, weighted + current}
+`)
+
+type rateLimitCache struct {
+	client redis.Scripter
+}
+
+func NewRateLimitCache(client redis.Scripter) ports.RateLimiterPort {
+	return &rateLimitCache{
+		client: client,
+	}
+}
+
+func (r *rateLimitCache) Allow(ctx context.Context, key string, limit int, window time.Duration) (*domain.RateLimitResult, error) {
+	windowMs := window.Milliseconds()
+	nowMs := time.Now().UnixMilli()
+	index := nowMs / windowMs
+	elapsed := nowMs % windowMs
+
+	// The hash tag keeps both windows in the same cluster slot.
+	keys := []string{
+		fmt.Sprintf("ratelimit:{%s}:%d", key, index),
+		fmt.Sprintf("ratelimit:{%s}:%d", key, index-1),
+	}
+
+	res, err := slidingWindowScript.Run(ctx, r.client, keys, limit, windowMs, elapsed).Int64Slice()
+	if err != nil {
+		return nil, err
+	}
+
+	allowed, count := res[0] == 1, int(res[1])
+	resetAfter := time.Duration(windowMs-elapsed) * time.Millisecond
+
+	result := &domain.RateLimitResult{
+		Allowed:    allowed,
+		Limit:      limit,
+		Remaining:  max(limit-count, 0),
+		ResetAfter: resetAfter,
+	}
+	if !allowed {
+		result.RetryAfter = resetAfter
+	}
+	return result, nil
+}
@@ -0,0 +1,25 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/kanta/backend-challenge/internal/core/ports"
+	"github.com/redis/go-redis/v9"
+)
+
+type replayCache struct {
+	client redis.Cmdable
+	prefix string
+}
+
+func NewReplayCache(client redis.Cmdable, prefix string) ports.ReplayCache {
+	return &replayCache{
+		client: client,
+		prefix: prefix,
+	}
+}
+
+func (r *replayCache) Remember(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	return r.client.SetNX(ctx, r.prefix+key, 1, ttl).Result()
+}
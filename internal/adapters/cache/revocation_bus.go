@@ -0,0 +1,59 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/kanta/backend-challenge/internal/core/ports"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+type revocationBus struct {
+	client  *redis.Client
+	channel string
+}
+
+func NewRevocationBus(client *redis.Client, channel string) ports.RevocationBus {
+	return &revocationBus{
+		client:  client,
+		channel: channel,
+	}
+}
+
+func (r *revocationBus) PublishRevocation(ctx context.Context, userID string) error {
+	return r.client.Publish(ctx, r.channel, userID).Err()
+}
+
+func (r *revocationBus) SubscribeRevocations(ctx context.Context, onRevoke func(userID string), onReset func()) error {
+	pubsub := r.client.Subscribe(ctx, r.channel)
+	defer pubsub.Close()
+
+	for {
+		msg, err := pubsub.Receive(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			// The connection is re-established on the next Receive;
+			// anything published meanwhile is lost.
+			zap.L().Warn("token revocation subscription interrupted", zap.Error(err))
+			onReset()
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+
+		switch msg := msg.(type) {
+		case *redis.Subscription:
+			// (Re)subscribed: drop whatever may have been revoked while
+			// the subscription was down.
+			onReset()
+		case *redis.Message:
+			onRevoke(msg.Payload)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/kanta/backend-challenge/internal/core/domain"
+	"github.com/kanta/backend-challenge/internal/core/ports"
+	"github.com/redis/go-redis/v9"
+)
+
+type sessionCache struct {
+	client redis.Cmdable
+}
+
+func NewSessionCache(client redis.Cmdable) ports.SessionStore {
+	return &sessionCache{
+		client: client,
+	}
+}
+
+func (r *sessionCache) Save(ctx context.Context, session *domain.Session, ttl time.Duration) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+	return r.client.Set(ctx, "session:"+session.UserID, data, ttl).Err()
+}
+
+func (r *sessionCache) Get(ctx context.Context, userID string) (*domain.Session, error) {
+	data, err := r.client.Get(ctx, "session:"+userID).Bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	var session domain.Session
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r *sessionCache) Delete(ctx context.Context, userID string) error {
+	return r.client.Del(ctx, "session:"+userID).Err()
+}
@@ -29,3 +29,7 @@ func (r *tokenCache) GetToken(ctx context.Context, key string) (string, error) {
 func (r *tokenCache) DeleteToken(ctx context.Context, key string) error {
 	return r.client.Del(ctx, key).Err()
 }
+
+func (r *tokenCache) TakeToken(ctx context.Context, key string) (string, error) {
+	return r.client.GetDel(ctx, key).Result()
+}
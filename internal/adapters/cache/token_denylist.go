@@ -0,0 +1,31 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/kanta/backend-challenge/internal/core/ports"
+	"github.com/redis/go-redis/v9"
+)
+
+type tokenDenylist struct {
+	client redis.Cmdable
+}
+
+func NewTokenDenylist(client redis.Cmdable) ports.TokenDenylist {
+	return &tokenDenylist{
+		client: client,
+	}
+}
+
+func (r *tokenDenylist) Revoke(ctx context.Context, jti string, ttl time.Duration) error {
+	return r.client.Set(ctx, "revoked:"+jti, 1, ttl).Err()
+}
+
+func (r *tokenDenylist) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	n, err := r.client.Exists(ctx, "revoked:"+jti).Result()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
@@ -0,0 +1,93 @@
+package challenge
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/kanta/backend-challenge/internal/core/domain"
+	"github.com/kanta/backend-challenge/internal/core/ports"
+)
+
+// captchaVerifyURLs are the siteverify endpoints of the supported providers.
+// They share one request and response format.
+var captchaVerifyURLs = map[string]string{
+	"turnstile": "https://challenges.cloudflare.com/turnstile/v0/siteverify",
+	"hcaptcha":  "https://api.hcaptcha.com/siteverify",
+	"recaptcha": "https://www.google.com/recaptcha/api/siteverify",
+}
+
+type captcha struct {
+	provider  string
+	siteKey   string
+	secret    string
+	verifyURL string
+	client    *http.Client
+}
+
+// NewCaptcha verifies CAPTCHA responses with provider, one of "turnstile",
+// "hcaptcha" or "recaptcha".
+func NewCaptcha(provider, siteKey, secret string, timeout time.Duration) (ports.ChallengeVerifier, error) {
+	verifyURL, ok := captchaVerifyURLs[provider]
+	if !ok {
+		return nil, fmt.Errorf("unknown captcha provider %q", provider)
+	}
+	return &captcha{
+		provider:  provider,
+		siteKey:   siteKey,
+		secret:    secret,
+		verifyURL: verifyURL,
+		client:    &http.Client{Timeout: timeout},
+	}, nil
+}
+
+func (c *captcha) Issue(ctx context.Context, client domain.ClientInfo) (*domain.Challenge, error) {
+	return &domain.Challenge{
+		Type:     domain.ChallengeCaptcha,
+		Provider: c.provider,
+		SiteKey:  c.siteKey,
+	}, nil
+}
+
+type siteVerifyResponse struct {
+	Success    bool     `json:"success"`
+	ErrorCodes []string `json:"error-codes"`
+}
+
+func (c *captcha) Verify(ctx context.Context, solution string, client domain.ClientInfo) error {
+	form := url.Values{
+		"secret":   {c.secret},
+		"response": {solution},
+	}
+	if client.IP != "" {
+		form.Set("remoteip", client.IP)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.verifyURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s siteverify returned %s", c.provider, res.Status)
+	}
+
+	var body siteVerifyResponse
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return err
+	}
+	if !body.Success {
+		return domain.ErrChallengeFailed
+	}
+	return nil
+}
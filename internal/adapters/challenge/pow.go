@@ -0,0 +1,131 @@
+package challenge
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kanta/backend-challenge/internal/core/domain"
+	"github.com/kanta/backend-challenge/internal/core/ports"
+)
+
+const powVersion = "v1"
+
+type proofOfWork struct {
+	secret     []byte
+	difficulty int
+	ttl        time.Duration
+	replay     ports.ReplayCache
+}
+
+// NewProofOfWork issues stateless proof-of-work challenges. Tokens are
+// signed with secret and bound to the client IP; replay makes each one
+// single-use. difficulty is the number of leading zero bits required, so
+// each step doubles the expected work.
+func NewProofOfWork(secret []byte, difficulty int, ttl time.Duration, replay ports.ReplayCache) ports.ChallengeVerifier {
+	return &proofOfWork{
+		secret:     secret,
+		difficulty: difficulty,
+		ttl:        ttl,
+		replay:     replay,
+	}
+}
+
+func (p *proofOfWork) Issue(ctx context.Context, client domain.ClientInfo) (*domain.Challenge, error) {
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return nil, err
+	}
+
+	expiresAt := time.Now().Add(p.ttl).UTC().Truncate(time.Second)
+	body := strings.Join([]string{
+		powVersion,
+		strconv.FormatInt(expiresAt.Unix(), 10),
+		strconv.Itoa(p.difficulty),
+		base64.RawURLEncoding.EncodeToString(random),
+	}, ".")
+
+	return &domain.Challenge{
+		Type:       domain.ChallengeProofOfWork,
+		Algorithm:  "sha256",
+		Token:      body + "." + p.sign(body, client.IP),
+		Difficulty: p.difficulty,
+		ExpiresAt:  &expiresAt,
+	}, nil
+}
+
+func (p *proofOfWork) Verify(ctx context.Context, solution string, client domain.ClientInfo) error {
+	i := strings.LastIndexByte(solution, ':')
+	if i < 0 || len(solution)-i > 65 {
+		return domain.ErrChallengeFailed
+	}
+	token := solution[:i]
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 5 || parts[0] != powVersion {
+		return domain.ErrChallengeFailed
+	}
+	body := strings.Join(parts[:4], ".")
+	if !hmac.Equal([]byte(parts[4]), []byte(p.sign(body, client.IP))) {
+		return domain.ErrChallengeFailed
+	}
+
+	expires, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return domain.ErrChallengeFailed
+	}
+	ttl := time.Until(time.Unix(expires, 0))
+	if ttl <= 0 {
+		return domain.ErrChallengeFailed
+	}
+	// The token carries the difficulty it was issued with, so lowering
+	// the setting does not reject challenges already being solved.
+	difficulty, err := strconv.Atoi(parts[2])
+	if err != nil || leadingZeroBits(sha256.Sum256([]byte(solution))) < difficulty {
+		return domain.ErrChallengeFailed
+	}
+
+	fresh, err := p.replay.Remember(ctx, parts[3], ttl)
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		return domain.ErrChallengeFailed
+	}
+	return nil
+}
+
+func (p *proofOfWork) sign(body, ip string) string {
+	mac := hmac.New(sha256.New, p.secret)
+	mac.Write([]byte(body + "|" + ip))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// SolveProofOfWork finds a solution for a proof-of-work challenge, as a
+// client would.
+func SolveProofOfWork(token string, difficulty int) string {
+	for nonce := 0; ; nonce++ {
+		solution := fmt.Sprintf("%s:%d", token, nonce)
+		if leadingZeroBits(sha256.Sum256([]byte(solution))) >= difficulty {
+			return solution
+		}
+	}
+}
+
+func leadingZeroBits(sum [sha256.Size]byte) int {
+	n := 0
+	for _, b := range sum {
+		n += bits.LeadingZeros8(b)
+		if b != 0 {
+			break
+		}
+	}
+	return n
+}
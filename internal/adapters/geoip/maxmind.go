@@ -0,0 +1,94 @@
+package geoip
+
+import (
+	"errors"
+	"net"
+
+	"github.com/kanta/backend-challenge/internal/core/domain"
+	"github.com/kanta/backend-challenge/internal/core/ports"
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// locationRecord reads both City and Country databases; the latter have
+// no location.
+type locationRecord struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	Location struct {
+		Latitude  *float64 `maxminddb:"latitude"`
+		Longitude *float64 `maxminddb:"longitude"`
+	} `maxminddb:"location"`
+}
+
+type asnRecord struct {
+	Number       uint   `maxminddb:"autonomous_system_number"`
+	Organization string `maxminddb:"autonomous_system_organization"`
+}
+
+type MaxMind struct {
+	location *maxminddb.Reader
+	asn      *maxminddb.Reader
+}
+
+// NewMaxMind opens MaxMind-format databases: locationPath a City or Country
+// database and asnPath an ASN database. Either path may be empty, leaving
+// those fields unknown.
+func NewMaxMind(locationPath, asnPath string) (*MaxMind, error) {
+	m := &MaxMind{}
+	var err error
+	if locationPath != "" {
+		if m.location, err = maxminddb.Open(locationPath); err != nil {
+			return nil, err
+		}
+	}
+	if asnPath != "" {
+		if m.asn, err = maxminddb.Open(asnPath); err != nil {
+			m.Close()
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+var _ ports.GeoIPLookup = (*MaxMind)(nil)
+
+func (m *MaxMind) Lookup(ip string) (domain.GeoLocation, error) {
+	var location domain.GeoLocation
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return location, nil
+	}
+
+	if m.location != nil {
+		var record locationRecord
+		if err := m.location.Lookup(addr, &record); err != nil {
+			return location, err
+		}
+		location.Country = record.Country.ISOCode
+		if record.Location.Latitude != nil && record.Location.Longitude != nil {
+			location.HasCoordinates = true
+			location.Latitude = *record.Location.Latitude
+			location.Longitude = *record.Location.Longitude
+		}
+	}
+	if m.asn != nil {
+		var record asnRecord
+		if err := m.asn.Lookup(addr, &record); err != nil {
+			return location, err
+		}
+		location.ASN = record.Number
+		location.ASOrganization = record.Organization
+	}
+	return location, nil
+}
+
+func (m *MaxMind) Close() error {
+	var errs []error
+	for _, reader := range []*maxminddb.Reader{m.location, m.asn} {
+		if reader != nil {
+			errs = append(errs, reader.Close())
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/kanta/backend-challenge/internal/core/domain"
+	"github.com/kanta/backend-challenge/middlewares/meta"
+	"go.uber.org/zap"
+)
+
+var auditCSVHeader = []string{
+	"id", "occurred_at", "action", "outcome", "actor_id", "subject_id",
+	"email", "ip", "user_agent", "reason", "details",
+}
+
+// QueryAuditLog godoc
+// @Summary Query the audit log
+// @Description List audit entries, newest first, filtered by actor, subject, email, action, outcome, IP and time range
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param actor_id query string false "Actor ID"
+// @Param subject_id query string false "Subject user ID"
+// @Param email query string false "Email"
+// @Param action query string false "Action"
+// @Param outcome query string false "success or failure"
+// @Param ip query string false "Client IP"
+// @Param from query string false "Start time (RFC 3339, inclusive)"
+// @Param to query string false "End time (RFC 3339, exclusive)"
+// @Param page query int false "Page number"
+// @Param page_size query int false "Page size"
+// @Router /admin/audit [get]
+func (h *backEndHandler) QueryAuditLog(c *fiber.Ctx) error {
+	filter, err := auditFilter(c)
+	if err != nil {
+		return c.JSON(meta.NewMetaError(http.StatusBadRequest, err.Error()))
+	}
+
+	page, err := h.audit.Query(c.Context(), filter)
+	if err != nil {
+		return c.JSON(meta.NewMetaError(http.StatusInternalServerError, "failed to query audit log"))
+	}
+
+	resOk := meta.NewMetaOK("get audit log successfully", page.Entries, meta.WithMetaOKOptionsPagination(meta.MetaPagination{
+		TotalItems:  int(page.Total),
+		TotalPages:  int((page.Total + int64(page.PageSize) - 1) / int64(page.PageSize)),
+		CurrentPage: page.Page,
+		PageSize:    page.PageSize,
+	}))
+	return c.JSON(resOk)
+}
+
+// ExportAuditLog godoc
+// @Summary Export the audit log
+// @Description Stream every matching audit entry, oldest first, as JSON lines or CSV
+// @Tags Admin
+// @Produce plain
+// @Security BearerAuth
+// @Param format query string false "jsonl (default) or csv"
+// @Param actor_id query string false "Actor ID"
+// @Param subject_id query string false "Subject user ID"
+// @Param email query string false "Email"
+// @Param action query string false "Action"
+// @Param outcome query string false "success or failure"
+// @Param ip query string false "Client IP"
+// @Param from query string false "Start time (RFC 3339, inclusive)"
+// @Param to query string false "End time (RFC 3339, exclusive)"
+// @Router /admin/audit/export [get]
+func (h *backEndHandler) ExportAuditLog(c *fiber.Ctx) error {
+	filter, err := auditFilter(c)
+	if err != nil {
+		return c.JSON(meta.NewMetaError(http.StatusBadRequest, err.Error()))
+	}
+
+	format := c.Query("format", "jsonl")
+	var write func(ctx context.Context, w *bufio.Writer) error
+	switch format {
+	case "jsonl":
+		c.Set(fiber.HeaderContentType, "application/x-ndjson")
+		write = func(ctx context.Context, w *bufio.Writer) error {
+			enc := json.NewEncoder(w)
+			return h.audit.Export(ctx, filter, func(entry *domain.AuditEntry) error {
+				return enc.Encode(entry)
+			})
+		}
+	case "csv":
+		c.Set(fiber.HeaderContentType, "text/csv")
+		write = func(ctx context.Context, w *bufio.Writer) error {
+			cw := csv.NewWriter(w)
+			if err := cw.Write(auditCSVHeader); err != nil {
+				return err
+			}
+			err := h.audit.Export(ctx, filter, func(entry *domain.AuditEntry) error {
+				return cw.Write(auditCSVRecord(entry))
+			})
+			cw.Flush()
+			if err != nil {
+				return err
+			}
+			return cw.Error()
+		}
+	default:
+		return c.JSON(meta.NewMetaError(http.StatusBadRequest, "format must be jsonl or csv"))
+	}
+
+	c.Attachment("audit-log." + format)
+	// The stream writer runs after the handler returned. Its context is done
+	// when the server shuts down or the writer returns; a failed write to the
+	// client ends the export through the returned error.
+	reqCtx := c.Context()
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		ctx, cancel := context.WithCancel(reqCtx)
+		defer cancel()
+
+		// The status line has been sent already, so a failure can only
+		// truncate the export.
+		if err := write(ctx, w); err != nil {
+			zap.L().Error("failed to export audit log", zap.Error(err))
+		}
+		w.Flush()
+	})
+	return nil
+}
+
+// recordAudit completes entry with the request's client details and, unless
+// set, the authenticated user as actor.
+func (h *backEndHandler) recordAudit(c *fiber.Ctx, entry domain.AuditEntry) {
+	client := clientInfo(c)
+	entry.IP = client.IP
+	entry.UserAgent = client.UserAgent
+	if entry.ActorID == "" {
+		entry.ActorID, _ = c.Locals("user_id").(string)
+	}
+	h.audit.Record(c.Context(), entry)
+}
+
+func auditFilter(c *fiber.Ctx) (domain.AuditFilter, error) {
+	filter := domain.AuditFilter{
+		ActorID:   c.Query("actor_id"),
+		SubjectID: c.Query("subject_id"),
+		Email:     c.Query("email"),
+		Action:    c.Query("action"),
+		Outcome:   c.Query("outcome"),
+		IP:        c.Query("ip"),
+		Page:      c.QueryInt("page", 1),
+		PageSize:  c.QueryInt("page_size", 50),
+	}
+
+	for _, bound := range []struct {
+		param  string
+		target *time.Time
+	}{
+		{"from", &filter.From},
+		{"to", &filter.To},
+	} {
+		value := c.Query(bound.param)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return filter, fiber.NewError(http.StatusBadRequest, bound.param+" must be an RFC 3339 time")
+		}
+		*bound.target = parsed
+	}
+	return filter, nil
+}
+
+func auditCSVRecord(entry *domain.AuditEntry) []string {
+	var details string
+	if len(entry.Details) > 0 {
+		raw, _ := json.Marshal(entry.Details)
+		details = string(raw)
+	}
+	return []string{
+		entry.ID,
+		entry.OccurredAt.UTC().Format(time.RFC3339Nano),
+		entry.Action,
+		entry.Outcome,
+		entry.ActorID,
+		entry.SubjectID,
+		entry.Email,
+		entry.IP,
+		entry.UserAgent,
+		entry.Reason,
+		details,
+	}
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/kanta/backend-challenge/internal/core/domain"
+	"github.com/kanta/backend-challenge/middlewares/meta"
+)
+
+// RequestDataExport godoc
+// @Summary Export my data
+// @Description Queue an archive of everything stored about the authenticated user. Poll the returned export for a download link. One export per day.
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Router /users/me/export [post]
+func (h *backEndHandler) RequestDataExport(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(string)
+	if !ok || userID == "" {
+		return c.JSON(meta.NewMetaError(http.StatusUnauthorized, "unauthorized"))
+	}
+
+	export, err := h.exports.Request(c.Context(), userID)
+	if err != nil {
+		h.recordAudit(c, domain.AuditEntry{Action: domain.AuditDataExport, Outcome: domain.AuditFailure, Reason: err.Error()})
+		return c.JSON(exportError(err))
+	}
+	h.recordAudit(c, domain.AuditEntry{Action: domain.AuditDataExport, Outcome: domain.AuditSuccess, Details: map[string]any{"export_id": export.ID}})
+
+	resOk := meta.NewMetaOK("data export requested", export)
+	return c.JSON(resOk)
+}
+
+// GetDataExport godoc
+// @Summary Get a data export
+// @Description Status of one of the authenticated user's exports. Once ready it carries a short-lived download link.
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Export ID"
+// @Router /users/me/export/{id} [get]
+func (h *backEndHandler) GetDataExport(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(string)
+	if !ok || userID == "" {
+		return c.JSON(meta.NewMetaError(http.StatusUnauthorized, "unauthorized"))
+	}
+
+	export, err := h.exports.Get(c.Context(), userID, c.Params("id"))
+	if err != nil {
+		return c.JSON(exportError(err))
+	}
+
+	resOk := meta.NewMetaOK("get data export successfully", export)
+	return c.JSON(resOk)
+}
+
+// DownloadDataExport godoc
+// @Summary Download a data export
+// @Description Fetch the archive through the signed link from GET /users/me/export/{id}. No bearer token is needed.
+// @Tags users
+// @Produce json
+// @Param id path string true "Export ID"
+// @Param expires query int true "Link expiry (Unix time)"
+// @Param signature query string true "Link signature"
+// @Router /exports/{id}/download [get]
+func (h *backEndHandler) DownloadDataExport(c *fiber.Ctx) error {
+	expires, err := strconv.ParseInt(c.Query("expires"), 10, 64)
+	if err != nil {
+		return c.JSON(exportError(domain.ErrInvalidExportLink))
+	}
+
+	id := c.Params("id")
+	archive, err := h.exports.Open(c.Context(), id, expires, c.Query("signature"))
+	if err != nil {
+		return c.JSON(exportError(err))
+	}
+
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	c.Attachment("data-export-" + id + ".json")
+	// The body stream is closed once it has been sent.
+	return c.SendStream(archive)
+}
+
+func exportError(err error) *meta.MetaError {
+	switch {
+	case errors.Is(err, domain.ErrExportNotFound):
+		return meta.NewMetaError(http.StatusNotFound, err.Error())
+	case errors.Is(err, domain.ErrExportRateLimited):
+		return meta.NewMetaError(http.StatusTooManyRequests, err.Error())
+	case errors.Is(err, domain.ErrInvalidExportLink):
+		return meta.NewMetaError(http.StatusForbidden, err.Error())
+	case errors.Is(err, domain.ErrExportExpired):
+		return meta.NewMetaError(http.StatusGone, err.Error())
+	default:
+		return meta.NewMetaError(http.StatusInternalServerError, "failed to process data export request")
+	}
+}
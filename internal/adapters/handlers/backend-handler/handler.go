@@ -1,13 +1,15 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/kanta/backend-challenge/config"
 	jwt "github.com/kanta/backend-challenge/infrastructure"
 	"github.com/kanta/backend-challenge/internal/core/domain"
 	"github.com/kanta/backend-challenge/internal/core/ports"
+	"github.com/kanta/backend-challenge/middlewares"
 	"github.com/kanta/backend-challenge/middlewares/meta"
 )
 
@@ -21,22 +23,66 @@ type BackEndHandler interface {
 	Register(c *fiber.Ctx) error
 	Login(c *fiber.Ctx) error
 	RefreshToken(c *fiber.Ctx) error
+	IssueServiceToken(c *fiber.Ctx) error
 	GetMyProfile(c *fiber.Ctx) error
 	Logout(c *fiber.Ctx) error
+	LogoutEverywhere(c *fiber.Ctx) error
+	RevokeUserSessions(c *fiber.Ctx) error
+	UnlockUser(c *fiber.Ctx) error
+	ChangePassword(c *fiber.Ctx) error
+	ForgotPassword(c *fiber.Ctx) error
+	ResetPassword(c *fiber.Ctx) error
+	Reauthenticate(c *fiber.Ctx) error
+	ListDevices(c *fiber.Ctx) error
+	ForgetDevice(c *fiber.Ctx) error
+	RequestDataExport(c *fiber.Ctx) error
+	GetDataExport(c *fiber.Ctx) error
+	DownloadDataExport(c *fiber.Ctx) error
+	QueryAuditLog(c *fiber.Ctx) error
+	ExportAuditLog(c *fiber.Ctx) error
+	CreateWebhook(c *fiber.Ctx) error
+	ListWebhooks(c *fiber.Ctx) error
+	GetWebhook(c *fiber.Ctx) error
+	UpdateWebhook(c *fiber.Ctx) error
+	DeleteWebhook(c *fiber.Ctx) error
+	ListWebhookDeliveries(c *fiber.Ctx) error
+	RedeliverWebhook(c *fiber.Ctx) error
 }
 
 type backEndHandler struct {
 	service ports.Service
-	cache   ports.CachePort
+	tokens  *jwt.TokenManager
+	cookies middlewares.CookieConfig
+	dpop    *jwt.DPoPVerifier
+	// services maps TLS client certificates to service identities.
+	services   *jwt.ServiceIdentities
+	audit      ports.AuditService
+	webhooks   ports.WebhookService
+	challenges ports.ChallengeService
+	exports    ports.ExportService
 }
 
 func NewBackEndHandler(
 	service ports.Service,
-	cache ports.CachePort,
+	tokens *jwt.TokenManager,
+	cookies middlewares.CookieConfig,
+	dpop *jwt.DPoPVerifier,
+	services *jwt.ServiceIdentities,
+	audit ports.AuditService,
+	webhooks ports.WebhookService,
+	challenges ports.ChallengeService,
+	exports ports.ExportService,
 ) BackEndHandler {
 	return &backEndHandler{
 		service,
-		cache,
+		tokens,
+		cookies,
+		dpop,
+		services,
+		audit,
+		webhooks,
+		challenges,
+		exports,
 	}
 }
 
@@ -46,71 +92,140 @@ func NewBackEndHandler(
 // @Accept json
 // @Produce json
 // @Param body body domain.User true "User info"
+// @Param X-Challenge-Response header string false "Solution to the challenge of a 428 response"
 // @Router /auth/register [post]
 func (h *backEndHandler) Register(c *fiber.Ctx) error {
 	var req domain.User
 	if err := c.BodyParser(&req); err != nil {
 		return c.JSON(meta.NewMetaError(http.StatusBadRequest, "invalid request"))
 	}
-	err := h.service.Register(req.Name, req.Email, req.Password)
+	err := h.service.Register(c.Context(), req.Name, req.Email, req.Password)
 	if err != nil {
+		h.recordAudit(c, domain.AuditEntry{Action: domain.AuditRegister, Outcome: domain.AuditFailure, Email: req.Email, Reason: err.Error()})
+		if metaErr, ok := validationError(err); ok {
+			return c.JSON(metaErr)
+		}
 		return c.JSON(meta.NewMetaError(http.StatusBadRequest, err.Error()))
 	}
+	h.recordAudit(c, domain.AuditEntry{Action: domain.AuditRegister, Outcome: domain.AuditSuccess, Email: req.Email})
+
 	ok := meta.NewMetaOK("registered", nil)
 	return c.JSON(ok)
 }
 
 // Login godoc
 // @Summary Login and get JWT
+// @Description Send a DPoP proof in the DPoP header to bind the tokens to a key
 // @Tags Auth
 // @Accept json
 // @Produce json
 // @Param body body domain.Login true "Login info"
+// @Param DPoP header string false "DPoP proof"
+// @Param X-Challenge-Response header string false "Solution to the challenge of a 428 response"
 // @Router /auth/login [post]
 func (h *backEndHandler) Login(c *fiber.Ctx) error {
 	var (
 		ctx = c.Context()
-		req domain.User
+		req domain.Login
 	)
 	if err := c.BodyParser(&req); err != nil {
 		return c.JSON(meta.NewMetaError(http.StatusBadRequest, "invalid request"))
 	}
-	user, err := h.service.Authenticate(req.Email, req.Password)
+
+	policy, err := h.tokens.Policy(req.ClientID)
+	if err != nil {
+		return c.JSON(meta.NewMetaError(http.StatusBadRequest, err.Error()))
+	}
+	if !policy.AllowsGrant(domain.GrantPassword) {
+		return c.JSON(meta.NewMetaError(http.StatusBadRequest, domain.ErrGrantNotAllowed.Error()))
+	}
+
+	jkt, err := h.dpopProof(c)
 	if err != nil {
+		return h.dpopError(c, err)
+	}
 
+	client := clientInfo(c)
+	user, risk, err := h.service.Authenticate(ctx, req.Email, req.Password, client)
+	if err != nil {
+		entry := domain.AuditEntry{Action: domain.AuditLogin, Outcome: domain.AuditFailure, Email: req.Email, Reason: err.Error()}
+		if user != nil {
+			entry.SubjectID = user.ID
+		}
+		if risk != nil {
+			entry.Details = map[string]any{"risk": risk}
+		}
+		h.recordAudit(c, entry)
+		switch {
+		case errors.Is(err, domain.ErrLoginLocked):
+			return c.JSON(meta.NewMetaError(http.StatusTooManyRequests, domain.ErrLoginLocked.Error()))
+		case errors.Is(err, domain.ErrChallengeRequired):
+			return middlewares.RespondWithChallenge(c, h.challenges, client)
+		}
 		return c.JSON(meta.NewMetaError(http.StatusUnauthorized, "invalid credentials"))
 	}
 
-	tokenPair, _ := jwt.GenerateTokenPairWithCache(ctx, user.ID, config.Get().JWT_Secret, h.cache)
-	ok := meta.NewMetaOK("login successfully", map[string]interface{}{
-		"access_token":  tokenPair.AccessToken,
-		"refresh_token": tokenPair.RefreshToken,
+	subject := domain.NewTokenSubject(user, domain.AMRPassword)
+	subject.ClientID = policy.ClientID
+	subject.Confirmation.JKT = jkt
+	tokenPair, err := h.tokens.GenerateTokenPair(ctx, subject, domain.GrantPassword)
+	if err != nil {
+		return c.JSON(meta.NewMetaError(http.StatusInternalServerError, "failed to issue tokens"))
+	}
+
+	h.recordAudit(c, domain.AuditEntry{
+		Action:  domain.AuditLogin,
+		Outcome: domain.AuditSuccess,
+		ActorID: user.ID,
+		Email:   user.Email,
+		Details: map[string]any{"client_id": policy.ClientID, "token_type": tokenType(jkt), "risk": risk},
+	})
+
+	data := map[string]interface{}{
 		"user": fiber.Map{
 			"id":    user.ID,
 			"name":  user.Name,
 			"email": user.Email,
 		},
-	})
+	}
+	if h.cookies.UsesCookies(policy) {
+		if err := middlewares.SetAuthCookies(c, h.cookies, tokenPair, policy); err != nil {
+			return c.JSON(meta.NewMetaError(http.StatusInternalServerError, "failed to issue tokens"))
+		}
+	} else {
+		data["access_token"] = tokenPair.AccessToken
+		data["refresh_token"] = tokenPair.RefreshToken
+		data["token_type"] = tokenType(jkt)
+	}
 
+	ok := meta.NewMetaOK("login successfully", data)
 	return c.JSON(ok)
 }
 
 // RefreshToken godoc
 // @Summary Refresh access token
-// @Description Get a new access token using refresh token
+// @Description Get a new access token using refresh token from the body or, in cookie mode, the refresh cookie
 // @Tags Auth
 // @Accept json
 // @Produce json
-// @Param refresh body domain.RefreshTokenRequest true "Refresh token"
+// @Param refresh body domain.RefreshTokenRequest false "Refresh token"
+// @Param DPoP header string false "DPoP proof, required for DPoP-bound refresh tokens"
 // @Router /auth/refresh [post]
 func (h *backEndHandler) RefreshToken(c *fiber.Ctx) error {
 	var (
-		ctx = c.Context()
-		req domain.RefreshTokenRequest
+		ctx        = c.Context()
+		req        domain.RefreshTokenRequest
+		fromCookie bool
 	)
-	if err := c.BodyParser(&req); err != nil {
-		return c.JSON(meta.NewMetaError(http.StatusBadRequest, "invalid request"))
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.JSON(meta.NewMetaError(http.StatusBadRequest, "invalid request"))
+		}
+	}
 
+	if req.RefreshToken == "" && h.cookies.Enabled {
+		req.RefreshToken = c.Cookies(h.cookies.RefreshName)
+		fromCookie = req.RefreshToken != ""
 	}
 
 	if req.RefreshToken == "" {
@@ -118,15 +233,90 @@ func (h *backEndHandler) RefreshToken(c *fiber.Ctx) error {
 
 	}
 
-	accessToken, err := jwt.RefreshAccessTokenWithCache(ctx, req.RefreshToken, config.Get().JWT_Secret, h.cache)
+	jkt, err := h.dpopProof(c)
 	if err != nil {
+		return h.dpopError(c, err)
+	}
+
+	accessToken, claims, err := h.tokens.RefreshAccessToken(ctx, req.RefreshToken, jkt)
+	if err != nil {
+		h.recordAudit(c, domain.AuditEntry{Action: domain.AuditTokenRefresh, Outcome: domain.AuditFailure, Reason: err.Error()})
+		if errors.Is(err, domain.ErrInvalidDPoPProof) {
+			return h.dpopError(c, err)
+		}
 		return c.JSON(meta.NewMetaError(http.StatusUnauthorized, "invalid or expired refresh token"))
 
 	}
-	ok := meta.NewMetaOK("refresh token successfully", map[string]interface{}{"access_token": accessToken})
+
+	h.recordAudit(c, domain.AuditEntry{Action: domain.AuditTokenRefresh, Outcome: domain.AuditSuccess, ActorID: claims.UserID})
+
+	if fromCookie {
+		middlewares.SetAccessCookie(c, h.cookies, accessToken)
+		return c.JSON(meta.NewMetaOK("refresh token successfully", nil))
+	}
+	ok := meta.NewMetaOK("refresh token successfully", map[string]interface{}{
+		"access_token": accessToken,
+		"token_type":   tokenType(jkt),
+	})
 	return c.JSON(ok)
 }
 
+// IssueServiceToken godoc
+// @Summary Issue a service access token
+// @Description Authenticate an internal service by its TLS client certificate (client_credentials grant) and issue an access token bound to that certificate
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param body body domain.TokenRequest true "Grant type"
+// @Router /auth/token [post]
+func (h *backEndHandler) IssueServiceToken(c *fiber.Ctx) error {
+	var req domain.TokenRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.JSON(meta.NewMetaError(http.StatusBadRequest, "invalid request"))
+	}
+	if req.GrantType != domain.GrantClientCredentials {
+		return c.JSON(meta.NewMetaError(http.StatusBadRequest, domain.ErrUnsupportedGrantType.Error()))
+	}
+
+	cert := middlewares.ClientCertificate(c)
+	if cert == nil {
+		return c.JSON(meta.NewMetaError(http.StatusUnauthorized, domain.ErrClientCertificateRequired.Error()))
+	}
+	identity, err := h.services.Identify(cert)
+	if err != nil {
+		h.recordAudit(c, domain.AuditEntry{
+			Action:  domain.AuditServiceToken,
+			Outcome: domain.AuditFailure,
+			Reason:  err.Error(),
+			Details: map[string]any{"subject": cert.Subject.String()},
+		})
+		return c.JSON(meta.NewMetaError(http.StatusUnauthorized, err.Error()))
+	}
+
+	subject := domain.TokenSubject{
+		UserID:       "service:" + identity.Name,
+		Role:         domain.RoleService,
+		ClientID:     identity.ClientID,
+		AuthTime:     time.Now(),
+		Confirmation: domain.Confirmation{X5TS256: jwt.CertificateThumbprint(cert)},
+	}
+	tokenPair, err := h.tokens.GenerateTokenPair(c.Context(), subject, domain.GrantClientCredentials)
+	if err != nil {
+		if errors.Is(err, domain.ErrUnknownClient) || errors.Is(err, domain.ErrGrantNotAllowed) {
+			return c.JSON(meta.NewMetaError(http.StatusBadRequest, err.Error()))
+		}
+		return c.JSON(meta.NewMetaError(http.StatusInternalServerError, "failed to issue tokens"))
+	}
+
+	h.recordAudit(c, domain.AuditEntry{Action: domain.AuditServiceToken, Outcome: domain.AuditSuccess, ActorID: subject.UserID})
+
+	resOk := meta.NewMetaOK("token issued successfully", map[string]interface{}{
+		"access_token": tokenPair.AccessToken,
+		"token_type":   "Bearer",
+	})
+	return c.JSON(resOk)
+}
+
 // GetMyProfile godoc
 // @Summary Get current user profile
 // @Description Get authenticated user's profile information
@@ -173,11 +363,354 @@ func (h *backEndHandler) Logout(c *fiber.Ctx) error {
 
 	}
 
-	if err := jwt.RevokeToken(ctx, userID, h.cache); err != nil {
+	var tokenID string
+	if claims, ok := c.Locals("claims").(*domain.Claims); ok {
+		tokenID = claims.ID
+		if err := h.tokens.RevokeAccessToken(ctx, claims); err != nil {
+			return c.JSON(meta.NewMetaError(http.StatusInternalServerError, "unauthorized"))
+		}
+	}
+
+	if err := h.tokens.RevokeToken(ctx, userID); err != nil {
 		return c.JSON(meta.NewMetaError(http.StatusInternalServerError, "unauthorized"))
 
 	}
 
+	if h.cookies.Enabled {
+		middlewares.ClearAuthCookies(c, h.cookies)
+	}
+
+	h.service.RecordLogout(ctx, userID, tokenID)
+	h.recordAudit(c, domain.AuditEntry{Action: domain.AuditLogout, Outcome: domain.AuditSuccess})
+
 	resOk := meta.NewMetaOK("logged out successfully", nil)
 	return c.JSON(resOk)
 }
+
+// LogoutEverywhere godoc
+// @Summary Sign out everywhere
+// @Description Invalidate every access and refresh token issued to the current user, on all devices
+// @Tags Logout
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Router /auth/logout-all [post]
+func (h *backEndHandler) LogoutEverywhere(c *fiber.Ctx) error {
+	ctx := c.Context()
+	userID, ok := c.Locals("user_id").(string)
+	if !ok || userID == "" {
+		return c.JSON(meta.NewMetaError(http.StatusUnauthorized, "unauthorized"))
+	}
+
+	if err := h.service.RevokeAllSessions(ctx, userID, "user"); err != nil {
+		return c.JSON(meta.NewMetaError(http.StatusInternalServerError, "failed to revoke sessions"))
+	}
+	if err := h.tokens.RevokeToken(ctx, userID); err != nil {
+		return c.JSON(meta.NewMetaError(http.StatusInternalServerError, "failed to revoke sessions"))
+	}
+
+	if h.cookies.Enabled {
+		middlewares.ClearAuthCookies(c, h.cookies)
+	}
+
+	h.recordAudit(c, domain.AuditEntry{Action: domain.AuditLogoutEverywhere, Outcome: domain.AuditSuccess})
+
+	resOk := meta.NewMetaOK("signed out everywhere", nil)
+	return c.JSON(resOk)
+}
+
+// RevokeUserSessions godoc
+// @Summary Revoke all sessions of a user
+// @Description Invalidate every access and refresh token issued to a user
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Router /admin/users/{id}/revoke-sessions [post]
+func (h *backEndHandler) RevokeUserSessions(c *fiber.Ctx) error {
+	ctx := c.Context()
+	userID := c.Params("id")
+	if err := h.service.RevokeAllSessions(ctx, userID, "admin"); err != nil {
+		return c.JSON(meta.NewMetaError(http.StatusNotFound, "user not found"))
+	}
+	if err := h.tokens.RevokeToken(ctx, userID); err != nil {
+		return c.JSON(meta.NewMetaError(http.StatusInternalServerError, "failed to revoke sessions"))
+	}
+
+	h.recordAudit(c, domain.AuditEntry{Action: domain.AuditSessionsRevoke, Outcome: domain.AuditSuccess, SubjectID: userID})
+
+	resOk := meta.NewMetaOK("sessions revoked successfully", nil)
+	return c.JSON(resOk)
+}
+
+// UnlockUser godoc
+// @Summary Unlock a locked out user
+// @Description Clear the login lockout and failure counter for a user account
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Router /admin/users/{id}/unlock [post]
+func (h *backEndHandler) UnlockUser(c *fiber.Ctx) error {
+	if err := h.service.UnlockAccount(c.Context(), c.Params("id")); err != nil {
+		return c.JSON(meta.NewMetaError(http.StatusNotFound, "user not found"))
+	}
+	h.recordAudit(c, domain.AuditEntry{Action: domain.AuditAccountUnlock, Outcome: domain.AuditSuccess, SubjectID: c.Params("id")})
+
+	resOk := meta.NewMetaOK("user unlocked successfully", nil)
+	return c.JSON(resOk)
+}
+
+// ChangePassword godoc
+// @Summary Change password
+// @Description Change the authenticated user's password
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body domain.ChangePasswordRequest true "Current and new password"
+// @Router /users/me/password [post]
+func (h *backEndHandler) ChangePassword(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(string)
+	if !ok || userID == "" {
+		return c.JSON(meta.NewMetaError(http.StatusUnauthorized, "unauthorized"))
+	}
+
+	var req domain.ChangePasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.JSON(meta.NewMetaError(http.StatusBadRequest, "invalid request"))
+	}
+
+	err := h.service.ChangePassword(c.Context(), userID, req.CurrentPassword, req.NewPassword)
+	if err != nil {
+		h.recordAudit(c, domain.AuditEntry{Action: domain.AuditPasswordChange, Outcome: domain.AuditFailure, Reason: err.Error()})
+		if metaErr, ok := validationError(err); ok {
+			return c.JSON(metaErr)
+		}
+		if errors.Is(err, domain.ErrInvalidCredentials) {
+			return c.JSON(meta.NewMetaError(http.StatusUnauthorized, "current password is incorrect"))
+		}
+		return c.JSON(meta.NewMetaError(http.StatusInternalServerError, "failed to change password"))
+	}
+
+	h.recordAudit(c, domain.AuditEntry{Action: domain.AuditPasswordChange, Outcome: domain.AuditSuccess})
+
+	resOk := meta.NewMetaOK("password changed successfully", nil)
+	return c.JSON(resOk)
+}
+
+// ForgotPassword godoc
+// @Summary Request a password reset
+// @Description Send a password reset token to the account's email if it exists
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param body body domain.ForgotPasswordRequest true "Account email"
+// @Router /auth/password/forgot [post]
+func (h *backEndHandler) ForgotPassword(c *fiber.Ctx) error {
+	var req domain.ForgotPasswordRequest
+	if err := c.BodyParser(&req); err != nil || req.Email == "" {
+		return c.JSON(meta.NewMetaError(http.StatusBadRequest, "invalid request"))
+	}
+
+	if err := h.service.RequestPasswordReset(c.Context(), req.Email); err != nil {
+		return c.JSON(meta.NewMetaError(http.StatusInternalServerError, "failed to request password reset"))
+	}
+
+	h.recordAudit(c, domain.AuditEntry{Action: domain.AuditPasswordResetRequest, Outcome: domain.AuditSuccess, Email: req.Email})
+
+	resOk := meta.NewMetaOK("if the account exists, a reset link has been sent", nil)
+	return c.JSON(resOk)
+}
+
+// ResetPassword godoc
+// @Summary Reset password
+// @Description Set a new password using a reset token
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param body body domain.ResetPasswordRequest true "Reset token and new password"
+// @Router /auth/password/reset [post]
+func (h *backEndHandler) ResetPassword(c *fiber.Ctx) error {
+	var req domain.ResetPasswordRequest
+	if err := c.BodyParser(&req); err != nil || req.Token == "" {
+		return c.JSON(meta.NewMetaError(http.StatusBadRequest, "invalid request"))
+	}
+
+	err := h.service.ResetPassword(c.Context(), req.Token, req.NewPassword)
+	if err != nil {
+		h.recordAudit(c, domain.AuditEntry{Action: domain.AuditPasswordReset, Outcome: domain.AuditFailure, Reason: err.Error()})
+		if metaErr, ok := validationError(err); ok {
+			return c.JSON(metaErr)
+		}
+		if errors.Is(err, domain.ErrInvalidResetToken) {
+			return c.JSON(meta.NewMetaError(http.StatusBadRequest, err.Error()))
+		}
+		return c.JSON(meta.NewMetaError(http.StatusInternalServerError, "failed to reset password"))
+	}
+
+	h.recordAudit(c, domain.AuditEntry{Action: domain.AuditPasswordReset, Outcome: domain.AuditSuccess})
+
+	resOk := meta.NewMetaOK("password reset successfully", nil)
+	return c.JSON(resOk)
+}
+
+// Reauthenticate godoc
+// @Summary Re-authenticate the current session
+// @Description Confirm the password again to refresh auth_time before a sensitive operation
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body domain.ReauthenticateRequest true "Current password"
+// @Router /auth/reauthenticate [post]
+func (h *backEndHandler) Reauthenticate(c *fiber.Ctx) error {
+	ctx := c.Context()
+	userID, ok := c.Locals("user_id").(string)
+	if !ok || userID == "" {
+		return c.JSON(meta.NewMetaError(http.StatusUnauthorized, "unauthorized"))
+	}
+
+	var req domain.ReauthenticateRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.JSON(meta.NewMetaError(http.StatusBadRequest, "invalid request"))
+	}
+
+	user, err := h.service.Reauthenticate(ctx, userID, req.Password, clientInfo(c))
+	if err != nil {
+		h.recordAudit(c, domain.AuditEntry{Action: domain.AuditReauthenticate, Outcome: domain.AuditFailure, Reason: err.Error()})
+		if errors.Is(err, domain.ErrLoginLocked) {
+			return c.JSON(meta.NewMetaError(http.StatusTooManyRequests, domain.ErrLoginLocked.Error()))
+		}
+		return c.JSON(meta.NewMetaError(http.StatusUnauthorized, "invalid credentials"))
+	}
+
+	subject := domain.NewTokenSubject(user, domain.AMRPassword)
+	if claims, ok := c.Locals("claims").(*domain.Claims); ok {
+		subject.ClientID = claims.ClientID
+		// JWTAuth has verified the proof for a bound token, so the new
+		// tokens stay bound to the same key.
+		if claims.Confirmation != nil {
+			subject.Confirmation = *claims.Confirmation
+		}
+	}
+	policy, err := h.tokens.Policy(subject.ClientID)
+	if err != nil {
+		return c.JSON(meta.NewMetaError(http.StatusBadRequest, err.Error()))
+	}
+	tokenPair, err := h.tokens.GenerateTokenPair(ctx, subject, domain.GrantPassword)
+	if err != nil {
+		return c.JSON(meta.NewMetaError(http.StatusInternalServerError, "failed to issue tokens"))
+	}
+
+	h.recordAudit(c, domain.AuditEntry{Action: domain.AuditReauthenticate, Outcome: domain.AuditSuccess})
+
+	if h.cookies.UsesCookies(policy) {
+		if err := middlewares.SetAuthCookies(c, h.cookies, tokenPair, policy); err != nil {
+			return c.JSON(meta.NewMetaError(http.StatusInternalServerError, "failed to issue tokens"))
+		}
+		return c.JSON(meta.NewMetaOK("re-authenticated successfully", nil))
+	}
+
+	resOk := meta.NewMetaOK("re-authenticated successfully", map[string]interface{}{
+		"access_token":  tokenPair.AccessToken,
+		"refresh_token": tokenPair.RefreshToken,
+		"token_type":    tokenType(subject.Confirmation.JKT),
+	})
+	return c.JSON(resOk)
+}
+
+// ListDevices godoc
+// @Summary List known devices
+// @Description List the devices the authenticated user has logged in from
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Router /users/me/devices [get]
+func (h *backEndHandler) ListDevices(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(string)
+	if !ok || userID == "" {
+		return c.JSON(meta.NewMetaError(http.StatusUnauthorized, "unauthorized"))
+	}
+
+	devices, err := h.service.ListDevices(userID)
+	if err != nil {
+		return c.JSON(meta.NewMetaError(http.StatusInternalServerError, "failed to list devices"))
+	}
+
+	resOk := meta.NewMetaOK("get devices successfully", devices)
+	return c.JSON(resOk)
+}
+
+// ForgetDevice godoc
+// @Summary Forget a device
+// @Description Remove a device from the authenticated user's known devices
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Device ID"
+// @Router /users/me/devices/{id} [delete]
+func (h *backEndHandler) ForgetDevice(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(string)
+	if !ok || userID == "" {
+		return c.JSON(meta.NewMetaError(http.StatusUnauthorized, "unauthorized"))
+	}
+
+	if err := h.service.ForgetDevice(userID, c.Params("id")); err != nil {
+		return c.JSON(meta.NewMetaError(http.StatusNotFound, "device not found"))
+	}
+
+	resOk := meta.NewMetaOK("device removed successfully", nil)
+	return c.JSON(resOk)
+}
+
+func validationError(err error) (*meta.MetaError, bool) {
+	var ve *domain.ValidationError
+	if !errors.As(err, &ve) {
+		return nil, false
+	}
+	return meta.NewMetaError(http.StatusUnprocessableEntity, "password does not meet the policy",
+		meta.WithMetaErrorOptionsDetails(ve.Errors)), true
+}
+
+func clientInfo(c *fiber.Ctx) domain.ClientInfo {
+	return domain.ClientInfo{
+		IP:        c.IP(),
+		UserAgent: c.Get(fiber.HeaderUserAgent),
+		DeviceID:  c.Get("X-Device-ID"),
+		// Set by the challenge middleware.
+		ChallengePassed: c.Locals("challenge_passed") == true,
+	}
+}
+
+// dpopProof verifies the DPoP proof sent to a token endpoint and returns the
+// thumbprint of its key, or "" when the client did not send one.
+func (h *backEndHandler) dpopProof(c *fiber.Ctx) (string, error) {
+	proof := c.Get(middlewares.DPoPHeader)
+	if proof == "" {
+		return "", nil
+	}
+	middlewares.SetDPoPNonce(c, h.dpop)
+	return h.dpop.Verify(c.Context(), proof, c.Method(), middlewares.RequestURL(c), "")
+}
+
+func (h *backEndHandler) dpopError(c *fiber.Ctx, err error) error {
+	if errors.Is(err, domain.ErrUseDPoPNonce) {
+		return c.JSON(meta.NewMetaError(http.StatusBadRequest, "use_dpop_nonce"))
+	}
+	if errors.Is(err, domain.ErrInvalidDPoPProof) {
+		return c.JSON(meta.NewMetaError(http.StatusBadRequest, "invalid_dpop_proof"))
+	}
+	return c.JSON(meta.NewMetaError(http.StatusInternalServerError, "failed to verify DPoP proof"))
+}
+
+func tokenType(jkt string) string {
+	if jkt != "" {
+		return "DPoP"
+	}
+	return "Bearer"
+}
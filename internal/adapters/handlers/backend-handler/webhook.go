@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/kanta/backend-challenge/internal/core/domain"
+	"github.com/kanta/backend-challenge/middlewares/meta"
+)
+
+// CreateWebhook godoc
+// @Summary Create a webhook subscription
+// @Description Subscribe a URL to account lifecycle events. The signing secret is generated unless given and is only returned here.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body domain.WebhookSubscriptionRequest true "URL, event types and optional secret"
+// @Router /admin/webhooks [post]
+func (h *backEndHandler) CreateWebhook(c *fiber.Ctx) error {
+	var req domain.WebhookSubscriptionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.JSON(meta.NewMetaError(http.StatusBadRequest, "invalid request"))
+	}
+
+	subscription, err := h.webhooks.CreateSubscription(c.Context(), req)
+	if err != nil {
+		return c.JSON(webhookError(err))
+	}
+
+	resOk := meta.NewMetaOK("webhook created successfully", subscription)
+	return c.JSON(resOk)
+}
+
+// ListWebhooks godoc
+// @Summary List webhook subscriptions
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Router /admin/webhooks [get]
+func (h *backEndHandler) ListWebhooks(c *fiber.Ctx) error {
+	subscriptions, err := h.webhooks.ListSubscriptions(c.Context())
+	if err != nil {
+		return c.JSON(meta.NewMetaError(http.StatusInternalServerError, "failed to list webhooks"))
+	}
+
+	resOk := meta.NewMetaOK("get webhooks successfully", subscriptions)
+	return c.JSON(resOk)
+}
+
+// GetWebhook godoc
+// @Summary Get a webhook subscription
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Subscription ID"
+// @Router /admin/webhooks/{id} [get]
+func (h *backEndHandler) GetWebhook(c *fiber.Ctx) error {
+	subscription, err := h.webhooks.GetSubscription(c.Context(), c.Params("id"))
+	if err != nil {
+		return c.JSON(webhookError(err))
+	}
+
+	resOk := meta.NewMetaOK("get webhook successfully", subscription)
+	return c.JSON(resOk)
+}
+
+// UpdateWebhook godoc
+// @Summary Update a webhook subscription
+// @Description Change the URL, event types or active flag, or rotate the secret. Omitted fields are kept.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Subscription ID"
+// @Param body body domain.WebhookSubscriptionRequest true "Fields to change"
+// @Router /admin/webhooks/{id} [patch]
+func (h *backEndHandler) UpdateWebhook(c *fiber.Ctx) error {
+	var req domain.WebhookSubscriptionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.JSON(meta.NewMetaError(http.StatusBadRequest, "invalid request"))
+	}
+
+	subscription, err := h.webhooks.UpdateSubscription(c.Context(), c.Params("id"), req)
+	if err != nil {
+		return c.JSON(webhookError(err))
+	}
+
+	resOk := meta.NewMetaOK("webhook updated successfully", subscription)
+	return c.JSON(resOk)
+}
+
+// DeleteWebhook godoc
+// @Summary Delete a webhook subscription
+// @Description Delete the subscription together with its delivery log
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Subscription ID"
+// @Router /admin/webhooks/{id} [delete]
+func (h *backEndHandler) DeleteWebhook(c *fiber.Ctx) error {
+	if err := h.webhooks.DeleteSubscription(c.Context(), c.Params("id")); err != nil {
+		return c.JSON(webhookError(err))
+	}
+
+	resOk := meta.NewMetaOK("webhook deleted successfully", nil)
+	return c.JSON(resOk)
+}
+
+// ListWebhookDeliveries godoc
+// @Summary List webhook deliveries
+// @Description Delivery log of a subscription, newest first. Filter by status=dead for the dead-letter list.
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Subscription ID"
+// @Param status query string false "pending, succeeded or dead"
+// @Param page query int false "Page number"
+// @Param page_size query int false "Page size"
+// @Router /admin/webhooks/{id}/deliveries [get]
+func (h *backEndHandler) ListWebhookDeliveries(c *fiber.Ctx) error {
+	page, err := h.webhooks.ListDeliveries(c.Context(), domain.WebhookDeliveryFilter{
+		SubscriptionID: c.Params("id"),
+		Status:         c.Query("status"),
+		Page:           c.QueryInt("page", 1),
+		PageSize:       c.QueryInt("page_size", 50),
+	})
+	if err != nil {
+		return c.JSON(webhookError(err))
+	}
+
+	resOk := meta.NewMetaOK("get webhook deliveries successfully", page.Deliveries, meta.WithMetaOKOptionsPagination(meta.MetaPagination{
+		TotalItems:  int(page.Total),
+		TotalPages:  int((page.Total + int64(page.PageSize) - 1) / int64(page.PageSize)),
+		CurrentPage: page.Page,
+		PageSize:    page.PageSize,
+	}))
+	return c.JSON(resOk)
+}
+
+// RedeliverWebhook godoc
+// @Summary Redeliver a dead-lettered webhook
+// @Description Queue a delivery that ran out of attempts for another round of retries
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Subscription ID"
+// @Param deliveryId path string true "Delivery ID"
+// @Router /admin/webhooks/{id}/deliveries/{deliveryId}/redeliver [post]
+func (h *backEndHandler) RedeliverWebhook(c *fiber.Ctx) error {
+	delivery, err := h.webhooks.Redeliver(c.Context(), c.Params("id"), c.Params("deliveryId"))
+	if err != nil {
+		return c.JSON(webhookError(err))
+	}
+
+	resOk := meta.NewMetaOK("webhook delivery queued", delivery)
+	return c.JSON(resOk)
+}
+
+func webhookError(err error) *meta.MetaError {
+	switch {
+	case errors.Is(err, domain.ErrWebhookNotFound), errors.Is(err, domain.ErrWebhookDeliveryNotFound):
+		return meta.NewMetaError(http.StatusNotFound, err.Error())
+	case errors.Is(err, domain.ErrInvalidWebhookURL), errors.Is(err, domain.ErrUnknownWebhookEvent):
+		return meta.NewMetaError(http.StatusBadRequest, err.Error())
+	case errors.Is(err, domain.ErrWebhookNotDead):
+		return meta.NewMetaError(http.StatusConflict, err.Error())
+	default:
+		return meta.NewMetaError(http.StatusInternalServerError, "failed to process webhook request")
+	}
+}
@@ -0,0 +1,88 @@
+package hasher
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/kanta/backend-challenge/internal/core/ports"
+)
+
+var ErrUnknownHashFormat = errors.New("unknown password hash format")
+
+// Algorithm is a single hashing scheme that can recognise its own encoded
+// hashes.
+type Algorithm interface {
+	Identifies(encoded string) bool
+	Hash(password string) (string, error)
+	Verify(encoded, password string) (bool, error)
+	NeedsRehash(encoded string) bool
+}
+
+type passwordHasher struct {
+	current Algorithm
+	others  []Algorithm
+}
+
+// NewPasswordHasher hashes new passwords with current and verifies hashes
+// produced by current or any of others. Hashes that were not produced by
+// current with its present parameters are reported by NeedsRehash.
+func NewPasswordHasher(current Algorithm, others ...Algorithm) ports.PasswordHasher {
+	return &passwordHasher{
+		current: current,
+		others:  others,
+	}
+}
+
+// New builds the application's hasher: algorithm ("argon2id" or "bcrypt")
+// hashes new passwords, the other one and the legacy formats imported from
+// previous auth providers are accepted for verification only.
+func New(algorithm string, bcryptCost int, argon2idParams Argon2idParams) (ports.PasswordHasher, error) {
+	bcryptHasher := NewBcrypt(bcryptCost)
+	argon2idHasher := NewArgon2id(argon2idParams)
+	legacy := []Algorithm{NewPBKDF2SHA256(), NewScrypt(), NewSaltedSHA1()}
+
+	switch algorithm {
+	case "bcrypt":
+		return NewPasswordHasher(bcryptHasher, append([]Algorithm{argon2idHasher}, legacy...)...), nil
+	case "argon2id":
+		return NewPasswordHasher(argon2idHasher, append([]Algorithm{bcryptHasher}, legacy...)...), nil
+	default:
+		return nil, fmt.Errorf("unknown password hasher %q", algorithm)
+	}
+}
+
+func (h *passwordHasher) Hash(password string) (string, error) {
+	return h.current.Hash(password)
+}
+
+func (h *passwordHasher) Verify(encoded, password string) (bool, error) {
+	algorithm, err := h.algorithmFor(encoded)
+	if err != nil {
+		return false, err
+	}
+	return algorithm.Verify(encoded, password)
+}
+
+func (h *passwordHasher) Supports(encoded string) bool {
+	_, err := h.algorithmFor(encoded)
+	return err == nil
+}
+
+func (h *passwordHasher) NeedsRehash(encoded string) bool {
+	if !h.current.Identifies(encoded) {
+		return true
+	}
+	return h.current.NeedsRehash(encoded)
+}
+
+func (h *passwordHasher) algorithmFor(encoded string) (Algorithm, error) {
+	if h.current.Identifies(encoded) {
+		return h.current, nil
+	}
+	for _, algorithm := range h.others {
+		if algorithm.Identifies(encoded) {
+			return algorithm, nil
+		}
+	}
+	return nil, ErrUnknownHashFormat
+}
@@ -0,0 +1,110 @@
+package hasher
+
+import (
+	"testing"
+)
+
+var testArgon2idParams = Argon2idParams{
+	Memory:      8 * 1024,
+	Iterations:  2,
+	Parallelism: 1,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+func TestArgon2idNeedsRehash(t *testing.T) {
+	encoded, err := NewArgon2id(testArgon2idParams).Hash("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	with := func(change func(p *Argon2idParams)) Argon2idParams {
+		p := testArgon2idParams
+		change(&p)
+		return p
+	}
+	tests := []struct {
+		name   string
+		params Argon2idParams
+		want   bool
+	}{
+		{"same parameters", testArgon2idParams, false},
+		{"weaker parameters", with(func(p *Argon2idParams) { p.Memory /= 2; p.Iterations = 1 }), false},
+		{"more memory", with(func(p *Argon2idParams) { p.Memory *= 2 }), true},
+		{"more iterations", with(func(p *Argon2idParams) { p.Iterations++ }), true},
+		{"more parallelism", with(func(p *Argon2idParams) { p.Parallelism++ }), true},
+		{"longer salt", with(func(p *Argon2idParams) { p.SaltLength = 32 }), true},
+		{"longer key", with(func(p *Argon2idParams) { p.KeyLength = 64 }), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewArgon2id(tt.params).NeedsRehash(encoded); got != tt.want {
+				t.Fatalf("NeedsRehash() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if !NewArgon2id(testArgon2idParams).NeedsRehash("$argon2id$v=19$m=8192") {
+		t.Fatal("NeedsRehash() = false for a malformed hash")
+	}
+}
+
+func TestBcryptNeedsRehash(t *testing.T) {
+	encoded, err := NewBcrypt(5).Hash("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		cost int
+		want bool
+	}{
+		{4, false},
+		{5, false},
+		{6, true},
+	}
+	for _, tt := range tests {
+		if got := NewBcrypt(tt.cost).NeedsRehash(encoded); got != tt.want {
+			t.Errorf("cost %d: NeedsRehash() = %v, want %v", tt.cost, got, tt.want)
+		}
+	}
+}
+
+func TestPasswordHasherMigratesToCurrentAlgorithm(t *testing.T) {
+	bcryptHash, err := NewBcrypt(4).Hash("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	argon2idHash, err := NewArgon2id(testArgon2idParams).Hash("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := NewPasswordHasher(NewArgon2id(testArgon2idParams), NewBcrypt(4), NewSaltedSHA1())
+	tests := []struct {
+		name    string
+		encoded string
+		rehash  bool
+	}{
+		{"current", argon2idHash, false},
+		{"bcrypt", bcryptHash, true},
+		{"legacy", "sha1$seasalt$cff36ea83f5706ce9aa7454e63e431fc726b2dc8", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !h.Supports(tt.encoded) {
+				t.Fatal("Supports() = false")
+			}
+			if got := h.NeedsRehash(tt.encoded); got != tt.rehash {
+				t.Fatalf("NeedsRehash() = %v, want %v", got, tt.rehash)
+			}
+		})
+	}
+
+	if ok, err := h.Verify(bcryptHash, "correct horse"); !ok || err != nil {
+		t.Fatalf("Verify() of a bcrypt hash = %v, %v", ok, err)
+	}
+	if h.Supports("md5$abc$def") {
+		t.Fatal("Supports() = true for an unknown format")
+	}
+}
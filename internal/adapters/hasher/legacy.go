@@ -0,0 +1,153 @@
+package hasher
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+)
+
+// The hashers in this file only verify hashes imported from other systems.
+// Users are moved to the current algorithm on their next successful login,
+// since NeedsRehash always reports true for them.
+
+var errVerifyOnly = errors.New("legacy password hashers can only verify")
+
+type pbkdf2SHA256Hasher struct{}
+
+// NewPBKDF2SHA256 verifies Django style hashes:
+// pbkdf2_sha256$<iterations>$<salt>$<base64 hash>
+func NewPBKDF2SHA256() Algorithm {
+	return &pbkdf2SHA256Hasher{}
+}
+
+func (p *pbkdf2SHA256Hasher) Identifies(encoded string) bool {
+	return strings.HasPrefix(encoded, "pbkdf2_sha256$")
+}
+
+func (p *pbkdf2SHA256Hasher) Hash(password string) (string, error) {
+	return "", errVerifyOnly
+}
+
+func (p *pbkdf2SHA256Hasher) Verify(encoded, password string) (bool, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 4 {
+		return false, ErrUnknownHashFormat
+	}
+
+	iterations, err := strconv.Atoi(parts[1])
+	if err != nil || iterations <= 0 {
+		return false, fmt.Errorf("invalid pbkdf2 iterations %q", parts[1])
+	}
+	key, err := base64.StdEncoding.DecodeString(parts[3])
+	if err != nil {
+		return false, err
+	}
+
+	other := pbkdf2.Key([]byte(password), []byte(parts[2]), iterations, len(key), sha256.New)
+	return subtle.ConstantTimeCompare(key, other) == 1, nil
+}
+
+func (p *pbkdf2SHA256Hasher) NeedsRehash(encoded string) bool {
+	return true
+}
+
+type scryptHasher struct{}
+
+// NewScrypt verifies hashes in the PHC style used by passlib:
+// $scrypt$ln=<log2 N>,r=<r>,p=<p>$<base64 salt>$<base64 hash>
+func NewScrypt() Algorithm {
+	return &scryptHasher{}
+}
+
+func (s *scryptHasher) Identifies(encoded string) bool {
+	return strings.HasPrefix(encoded, "$scrypt$")
+}
+
+func (s *scryptHasher) Hash(password string) (string, error) {
+	return "", errVerifyOnly
+}
+
+func (s *scryptHasher) Verify(encoded, password string) (bool, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 5 {
+		return false, ErrUnknownHashFormat
+	}
+
+	var ln, r, p int
+	if _, err := fmt.Sscanf(parts[2], "ln=%d,r=%d,p=%d", &ln, &r, &p); err != nil {
+		return false, err
+	}
+	if ln <= 0 || ln >= 32 {
+		return false, fmt.Errorf("invalid scrypt cost ln=%d", ln)
+	}
+
+	salt, err := decodeBase64(parts[3])
+	if err != nil {
+		return false, err
+	}
+	key, err := decodeBase64(parts[4])
+	if err != nil {
+		return false, err
+	}
+
+	other, err := scrypt.Key([]byte(password), salt, 1<<ln, r, p, len(key))
+	if err != nil {
+		return false, err
+	}
+	return subtle.ConstantTimeCompare(key, other) == 1, nil
+}
+
+func (s *scryptHasher) NeedsRehash(encoded string) bool {
+	return true
+}
+
+type saltedSHA1Hasher struct{}
+
+// NewSaltedSHA1 verifies hashes of the form sha1$<salt>$<hex sha1(salt+password)>.
+func NewSaltedSHA1() Algorithm {
+	return &saltedSHA1Hasher{}
+}
+
+func (s *saltedSHA1Hasher) Identifies(encoded string) bool {
+	return strings.HasPrefix(encoded, "sha1$")
+}
+
+func (s *saltedSHA1Hasher) Hash(password string) (string, error) {
+	return "", errVerifyOnly
+}
+
+func (s *saltedSHA1Hasher) Verify(encoded, password string) (bool, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 3 {
+		return false, ErrUnknownHashFormat
+	}
+
+	key, err := hex.DecodeString(parts[2])
+	if err != nil {
+		return false, err
+	}
+
+	sum := sha1.Sum([]byte(parts[1] + password))
+	return subtle.ConstantTimeCompare(key, sum[:]) == 1, nil
+}
+
+func (s *saltedSHA1Hasher) NeedsRehash(encoded string) bool {
+	return true
+}
+
+// decodeBase64 accepts standard base64 with or without padding, as both
+// appear in PHC strings produced by different libraries, and passlib's
+// "adapted" base64, which has "." in place of "+".
+func decodeBase64(s string) ([]byte, error) {
+	s = strings.ReplaceAll(strings.TrimRight(s, "="), ".", "+")
+	return base64.RawStdEncoding.DecodeString(s)
+}
@@ -0,0 +1,101 @@
+package hasher
+
+import (
+	"strings"
+	"testing"
+)
+
+// The vectors come from the systems the hashes are imported from: Django's
+// own hasher tests for pbkdf2_sha256 and sha1, and the passlib documentation
+// for scrypt.
+func TestLegacyVerify(t *testing.T) {
+	const passlibScrypt = "$scrypt$ln=16,r=8,p=1$aM15713r3Xsvxbi31lqr1Q$nFNh2CVHVjNldFVKDHDlm4CbdRSCdEBsjjJxD+iCs5E"
+
+	tests := []struct {
+		name      string
+		algorithm Algorithm
+		encoded   string
+		password  string
+		want      bool
+		wantErr   bool
+	}{
+		{
+			name:      "django pbkdf2_sha256",
+			algorithm: NewPBKDF2SHA256(),
+			encoded:   "pbkdf2_sha256$10000$seasalt$CWWFdHOWwPnki7HvkcqN9iA2T3KLW1cf2uZ5kvArtVY=",
+			password:  "lètmein",
+			want:      true,
+		},
+		{
+			name:      "django pbkdf2_sha256 wrong password",
+			algorithm: NewPBKDF2SHA256(),
+			encoded:   "pbkdf2_sha256$10000$seasalt$CWWFdHOWwPnki7HvkcqN9iA2T3KLW1cf2uZ5kvArtVY=",
+			password:  "letmein",
+		},
+		{
+			name:      "django pbkdf2_sha256 bad iterations",
+			algorithm: NewPBKDF2SHA256(),
+			encoded:   "pbkdf2_sha256$0$seasalt$CWWFdHOWwPnki7HvkcqN9iA2T3KLW1cf2uZ5kvArtVY=",
+			password:  "lètmein",
+			wantErr:   true,
+		},
+		{
+			name:      "passlib scrypt",
+			algorithm: NewScrypt(),
+			encoded:   passlibScrypt,
+			password:  "password",
+			want:      true,
+		},
+		{
+			name:      "passlib scrypt adapted base64",
+			algorithm: NewScrypt(),
+			encoded:   strings.ReplaceAll(passlibScrypt, "+", "."),
+			password:  "password",
+			want:      true,
+		},
+		{
+			name:      "passlib scrypt wrong password",
+			algorithm: NewScrypt(),
+			encoded:   passlibScrypt,
+			password:  "Password",
+		},
+		{
+			name:      "passlib scrypt truncated",
+			algorithm: NewScrypt(),
+			encoded:   "$scrypt$ln=16,r=8,p=1$aM15713r3Xsvxbi31lqr1Q",
+			password:  "password",
+			wantErr:   true,
+		},
+		{
+			name:      "django sha1",
+			algorithm: NewSaltedSHA1(),
+			encoded:   "sha1$seasalt$cff36ea83f5706ce9aa7454e63e431fc726b2dc8",
+			password:  "lètmein",
+			want:      true,
+		},
+		{
+			name:      "django sha1 wrong password",
+			algorithm: NewSaltedSHA1(),
+			encoded:   "sha1$seasalt$cff36ea83f5706ce9aa7454e63e431fc726b2dc8",
+			password:  "lètmeout",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !tt.algorithm.Identifies(tt.encoded) {
+				t.Fatalf("Identifies(%q) = false", tt.encoded)
+			}
+			ok, err := tt.algorithm.Verify(tt.encoded, tt.password)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Verify() error = %v, want error %v", err, tt.wantErr)
+			}
+			if ok != tt.want {
+				t.Fatalf("Verify() = %v, want %v", ok, tt.want)
+			}
+			if !tt.algorithm.NeedsRehash(tt.encoded) {
+				t.Fatal("NeedsRehash() = false for a legacy hash")
+			}
+		})
+	}
+}
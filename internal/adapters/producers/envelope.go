@@ -0,0 +1,29 @@
+package producers
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/kanta/backend-challenge/internal/core/domain"
+)
+
+// Headers set on brokers that support them, so consumers can route events
+// without decoding the body.
+const (
+	HeaderEventID      = "event-id"
+	HeaderEventType    = "event-type"
+	HeaderEventVersion = "event-version"
+)
+
+// encodeEvent returns the JSON envelope every adapter publishes.
+func encodeEvent(event domain.Event) ([]byte, error) {
+	return json.Marshal(event)
+}
+
+func eventHeaders(event domain.Event) map[string]string {
+	return map[string]string{
+		HeaderEventID:      event.ID,
+		HeaderEventType:    event.Type,
+		HeaderEventVersion: strconv.Itoa(event.Version),
+	}
+}
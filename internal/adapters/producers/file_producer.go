@@ -0,0 +1,40 @@
+package producers
+
+import (
+	"context"
+	"os"
+	"sync"
+
+	"github.com/kanta/backend-challenge/internal/core/domain"
+)
+
+// FileProducer appends events as JSON lines to a file, for local
+// development. A new file is readable by its owner only.
+type FileProducer struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+func NewFileProducer(path string) (*FileProducer, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return &FileProducer{file: file}, nil
+}
+
+func (p *FileProducer) Publish(ctx context.Context, event domain.Event) error {
+	line, err := encodeEvent(event)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, err = p.file.Write(append(line, '\n'))
+	return err
+}
+
+func (p *FileProducer) Close() error {
+	return p.file.Close()
+}
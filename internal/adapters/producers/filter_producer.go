@@ -0,0 +1,30 @@
+package producers
+
+import (
+	"context"
+	"slices"
+
+	"github.com/kanta/backend-challenge/internal/core/domain"
+	"github.com/kanta/backend-challenge/internal/core/ports"
+)
+
+type filterProducer struct {
+	producer ports.EventProducer
+	types    []string
+}
+
+// NewFilterProducer passes only events of the given types on to producer and
+// silently drops the rest.
+func NewFilterProducer(producer ports.EventProducer, types []string) ports.EventProducer {
+	return &filterProducer{
+		producer: producer,
+		types:    types,
+	}
+}
+
+func (p *filterProducer) Publish(ctx context.Context, event domain.Event) error {
+	if !slices.Contains(p.types, event.Type) {
+		return nil
+	}
+	return p.producer.Publish(ctx, event)
+}
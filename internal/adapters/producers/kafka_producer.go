@@ -0,0 +1,52 @@
+package producers
+
+import (
+	"context"
+	"time"
+
+	"github.com/kanta/backend-challenge/internal/core/domain"
+	"github.com/segmentio/kafka-go"
+)
+
+// KafkaProducer writes events to a single topic, keyed by aggregate so that
+// the events of one user land on one partition in order.
+type KafkaProducer struct {
+	writer *kafka.Writer
+}
+
+func NewKafkaProducer(brokers []string, topic string, timeout time.Duration) *KafkaProducer {
+	return &KafkaProducer{
+		writer: &kafka.Writer{
+			Addr:         kafka.TCP(brokers...),
+			Topic:        topic,
+			Balancer:     &kafka.Hash{},
+			RequiredAcks: kafka.RequireAll,
+			// Publish waits for the write, so don't hold it for a batch.
+			BatchTimeout: 10 * time.Millisecond,
+			WriteTimeout: timeout,
+			ReadTimeout:  timeout,
+		},
+	}
+}
+
+func (p *KafkaProducer) Publish(ctx context.Context, event domain.Event) error {
+	value, err := encodeEvent(event)
+	if err != nil {
+		return err
+	}
+
+	var headers []kafka.Header
+	for key, value := range eventHeaders(event) {
+		headers = append(headers, kafka.Header{Key: key, Value: []byte(value)})
+	}
+	return p.writer.WriteMessages(ctx, kafka.Message{
+		Key:     []byte(event.AggregateID),
+		Value:   value,
+		Headers: headers,
+		Time:    event.OccurredAt,
+	})
+}
+
+func (p *KafkaProducer) Close() error {
+	return p.writer.Close()
+}
@@ -0,0 +1,29 @@
+package producers
+
+import (
+	"context"
+
+	"github.com/kanta/backend-challenge/internal/core/domain"
+	"github.com/kanta/backend-challenge/internal/core/ports"
+	"go.uber.org/zap"
+)
+
+type logProducer struct{}
+
+// NewLogProducer returns an EventProducer that writes events to the
+// application log.
+func NewLogProducer() ports.EventProducer {
+	return &logProducer{}
+}
+
+func (p *logProducer) Publish(ctx context.Context, event domain.Event) error {
+	zap.L().Info("event",
+		zap.String("id", event.ID),
+		zap.String("type", event.Type),
+		zap.Int("version", event.Version),
+		zap.String("aggregate_id", event.AggregateID),
+		zap.Time("occurred_at", event.OccurredAt),
+		zap.Any("data", event.Data),
+	)
+	return nil
+}
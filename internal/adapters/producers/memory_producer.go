@@ -0,0 +1,38 @@
+package producers
+
+import (
+	"context"
+	"sync"
+
+	"github.com/kanta/backend-challenge/internal/core/domain"
+)
+
+// MemoryProducer keeps published events in memory, for tests.
+type MemoryProducer struct {
+	mu     sync.Mutex
+	events []domain.Event
+}
+
+func NewMemoryProducer() *MemoryProducer {
+	return &MemoryProducer{}
+}
+
+func (p *MemoryProducer) Publish(ctx context.Context, event domain.Event) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.events = append(p.events, event)
+	return nil
+}
+
+// Events returns a copy of the events published so far, oldest first.
+func (p *MemoryProducer) Events() []domain.Event {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]domain.Event(nil), p.events...)
+}
+
+func (p *MemoryProducer) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.events = nil
+}
@@ -0,0 +1,67 @@
+package producers
+
+import (
+	"context"
+	"time"
+
+	"github.com/kanta/backend-challenge/internal/core/domain"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// NATSProducer publishes each event on subjectPrefix.<event type>. With
+// JetStream the publish is acknowledged by the stream and deduplicated by
+// event ID; plain NATS is fire and forget.
+type NATSProducer struct {
+	conn          *nats.Conn
+	js            jetstream.JetStream
+	subjectPrefix string
+	timeout       time.Duration
+}
+
+func NewNATSProducer(url, subjectPrefix string, useJetStream bool, timeout time.Duration) (*NATSProducer, error) {
+	conn, err := nats.Connect(url, nats.Name("backend-challenge"), nats.MaxReconnects(-1))
+	if err != nil {
+		return nil, err
+	}
+
+	p := &NATSProducer{
+		conn:          conn,
+		subjectPrefix: subjectPrefix,
+		timeout:       timeout,
+	}
+	if useJetStream {
+		p.js, err = jetstream.New(conn)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+func (p *NATSProducer) Publish(ctx context.Context, event domain.Event) error {
+	data, err := encodeEvent(event)
+	if err != nil {
+		return err
+	}
+
+	msg := nats.NewMsg(p.subjectPrefix + "." + event.Type)
+	msg.Data = data
+	for key, value := range eventHeaders(event) {
+		msg.Header.Set(key, value)
+	}
+
+	if p.js == nil {
+		return p.conn.PublishMsg(msg)
+	}
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+	_, err = p.js.PublishMsg(ctx, msg, jetstream.WithMsgID(event.ID))
+	return err
+}
+
+// Close flushes pending messages and closes the connection.
+func (p *NATSProducer) Close() error {
+	return p.conn.Drain()
+}
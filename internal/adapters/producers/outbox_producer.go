@@ -0,0 +1,25 @@
+package producers
+
+import (
+	"context"
+
+	"github.com/kanta/backend-challenge/internal/core/domain"
+	"github.com/kanta/backend-challenge/internal/core/ports"
+)
+
+type outboxProducer struct {
+	outbox ports.OutboxRepository
+}
+
+// NewOutboxProducer queues events in the outbox, from where the relay
+// publishes them. Use it for events that do not accompany a database change;
+// the others should be appended in the change's transaction.
+func NewOutboxProducer(outbox ports.OutboxRepository) ports.EventProducer {
+	return &outboxProducer{
+		outbox: outbox,
+	}
+}
+
+func (p *outboxProducer) Publish(ctx context.Context, event domain.Event) error {
+	return p.outbox.Append(event)
+}
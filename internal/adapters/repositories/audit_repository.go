@@ -0,0 +1,318 @@
+package repositories
+
+import (
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/kanta/backend-challenge/internal/adapters/repositories/models"
+	"github.com/kanta/backend-challenge/internal/core/domain"
+	"github.com/kanta/backend-challenge/internal/core/ports"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type auditRepository struct {
+	db *gorm.DB
+}
+
+func NewAuditRepository(db *gorm.DB) ports.AuditRepository {
+	return &auditRepository{
+		db: db,
+	}
+}
+
+func (r *auditRepository) Append(entries ...*domain.AuditEntry) error {
+	byPartition := make(map[string][]*domain.AuditEntry)
+	var partitions []string
+	for _, entry := range entries {
+		// Postgres keeps microseconds; hash exactly what will be read back.
+		entry.OccurredAt = entry.OccurredAt.UTC().Truncate(time.Microsecond)
+		entry.Partition = domain.AuditPartition(entry.OccurredAt)
+		if _, ok := byPartition[entry.Partition]; !ok {
+			partitions = append(partitions, entry.Partition)
+		}
+		byPartition[entry.Partition] = append(byPartition[entry.Partition], entry)
+	}
+	// Lock heads in a fixed order so that concurrent batches spanning a
+	// month boundary cannot deadlock.
+	slices.Sort(partitions)
+
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for _, partition := range partitions {
+			if err := r.appendToChain(tx, partition, byPartition[partition]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// appendToChain locks the head of partition once and chains entries after it
+// in order.
+func (r *auditRepository) appendToChain(tx *gorm.DB, partition string, entries []*domain.AuditEntry) error {
+	head := models.AuditChainHead{Partition: partition}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&head).Error; err != nil {
+		return err
+	}
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&head, "partition_key = ?", partition).Error; err != nil {
+		return err
+	}
+
+	ms := make([]*models.AuditLog, 0, len(entries))
+	for _, entry := range entries {
+		m := models.ToAuditLogModels(entry)
+		entry.ID = m.ID
+		entry.Sequence = head.Sequence + 1
+		entry.PrevHash = head.Hash
+		hash, err := domain.AuditChainHash(entry)
+		if err != nil {
+			return err
+		}
+		entry.Hash = hash
+
+		m.Sequence, m.PrevHash, m.Hash = entry.Sequence, entry.PrevHash, entry.Hash
+		ms = append(ms, m)
+		head.Sequence, head.Hash = entry.Sequence, entry.Hash
+	}
+
+	if err := tx.Create(ms).Error; err != nil {
+		return err
+	}
+	return tx.Model(&head).Updates(map[string]interface{}{
+		"sequence": head.Sequence,
+		"hash":     head.Hash,
+	}).Error
+}
+
+func (r *auditRepository) Find(filter domain.AuditFilter) ([]*domain.AuditEntry, int64, error) {
+	query := r.filter(filter)
+
+	var total int64
+	if err := query.Model(&models.AuditLog{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var ms []models.AuditLog
+	result := query.
+		Order("occurred_at DESC, id DESC").
+		Offset((filter.Page - 1) * filter.PageSize).
+		Limit(filter.PageSize).
+		Find(&ms)
+	if result.Error != nil {
+		return nil, 0, result.Error
+	}
+
+	entries := make([]*domain.AuditEntry, 0, len(ms))
+	for i := range ms {
+		entries = append(entries, models.ToAuditLogDomain(&ms[i]))
+	}
+	return entries, total, nil
+}
+
+func (r *auditRepository) Each(filter domain.AuditFilter, fn func(*domain.AuditEntry) error) error {
+	rows, err := r.filter(filter).Model(&models.AuditLog{}).Order("occurred_at ASC, id ASC").Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var m models.AuditLog
+		if err := r.db.ScanRows(rows, &m); err != nil {
+			return err
+		}
+		if err := fn(models.ToAuditLogDomain(&m)); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (r *auditRepository) Partitions() ([]string, error) {
+	var partitions []string
+	result := r.db.Raw(`SELECT partition_key FROM audit_logs WHERE sequence > 0
+		UNION SELECT partition_key FROM audit_checkpoints
+		UNION SELECT partition_key FROM audit_purges
+		UNION SELECT partition_key FROM audit_chain_heads WHERE sequence > 0
+		ORDER BY partition_key`).Scan(&partitions)
+	return partitions, result.Error
+}
+
+func (r *auditRepository) Heads() ([]*domain.AuditChainHead, error) {
+	var ms []models.AuditChainHead
+	if err := r.db.Where("sequence > 0").Order("partition_key").Find(&ms).Error; err != nil {
+		return nil, err
+	}
+
+	heads := make([]*domain.AuditChainHead, 0, len(ms))
+	for _, m := range ms {
+		heads = append(heads, &domain.AuditChainHead{
+			Partition: m.Partition,
+			Sequence:  m.Sequence,
+			Hash:      m.Hash,
+		})
+	}
+	return heads, nil
+}
+
+func (r *auditRepository) Head(partition string) (*domain.AuditChainHead, error) {
+	var m models.AuditChainHead
+
+	result := r.db.Where("partition_key = ? AND sequence > 0", partition).First(&m)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &domain.AuditChainHead{
+		Partition: m.Partition,
+		Sequence:  m.Sequence,
+		Hash:      m.Hash,
+	}, nil
+}
+
+func (r *auditRepository) EachInChain(partition string, fn func(*domain.AuditEntry) error) error {
+	rows, err := r.db.Model(&models.AuditLog{}).
+		Where("partition_key = ? AND sequence > 0", partition).
+		Order("sequence ASC").
+		Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var m models.AuditLog
+		if err := r.db.ScanRows(rows, &m); err != nil {
+			return err
+		}
+		if err := fn(models.ToAuditLogDomain(&m)); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (r *auditRepository) AppendCheckpoint(checkpoint *domain.AuditCheckpoint) error {
+	m := models.ToAuditCheckpointModels(checkpoint)
+
+	// Another instance may have signed the same head already.
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(m)
+	if result.Error != nil {
+		return result.Error
+	}
+	checkpoint.ID = m.ID
+	return nil
+}
+
+func (r *auditRepository) LastCheckpoint(partition string) (*domain.AuditCheckpoint, error) {
+	var m models.AuditCheckpoint
+
+	result := r.db.Where("partition_key = ?", partition).Order("sequence DESC").First(&m)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return models.ToAuditCheckpointDomain(&m), nil
+}
+
+func (r *auditRepository) Checkpoints(partition string) ([]*domain.AuditCheckpoint, error) {
+	var ms []models.AuditCheckpoint
+	if err := r.db.Where("partition_key = ?", partition).Order("sequence ASC").Find(&ms).Error; err != nil {
+		return nil, err
+	}
+
+	checkpoints := make([]*domain.AuditCheckpoint, 0, len(ms))
+	for i := range ms {
+		checkpoints = append(checkpoints, models.ToAuditCheckpointDomain(&ms[i]))
+	}
+	return checkpoints, nil
+}
+
+func (r *auditRepository) LastPurge(partition string) (*domain.AuditPurge, error) {
+	var m models.AuditPurge
+
+	result := r.db.Where("partition_key = ?", partition).Order("sequence DESC").First(&m)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return models.ToAuditPurgeDomain(&m), nil
+}
+
+func (r *auditRepository) Purge(before time.Time, purges []*domain.AuditPurge) (int64, error) {
+	var deleted int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var purged []*domain.AuditPurge
+		for _, purge := range purges {
+			// Locking the head keeps entries from being appended to the
+			// partition until the purge commits.
+			var head models.AuditChainHead
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&head, "partition_key = ?", purge.Partition).Error; err != nil {
+				return err
+			}
+			if head.Sequence != purge.Sequence || head.Hash != purge.Hash {
+				continue
+			}
+			// Another instance may have purged the same head already.
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(models.ToAuditPurgeModels(purge)).Error; err != nil {
+				return err
+			}
+			purged = append(purged, purge)
+		}
+
+		// The append-only triggers let deletes through only for this role,
+		// with audit.purge set.
+		if err := tx.Exec("SET LOCAL ROLE " + models.AuditPurgeRole).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("SET LOCAL audit.purge = 'on'").Error; err != nil {
+			return err
+		}
+		for _, purge := range purged {
+			result := tx.Where("partition_key = ? AND sequence > 0 AND sequence <= ?", purge.Partition, purge.Sequence).
+				Delete(&models.AuditLog{})
+			if result.Error != nil {
+				return result.Error
+			}
+			deleted += result.RowsAffected
+		}
+		result := tx.Where("partition_key = '' AND occurred_at < ?", before).Delete(&models.AuditLog{})
+		if result.Error != nil {
+			return result.Error
+		}
+		deleted += result.RowsAffected
+		return nil
+	})
+	return deleted, err
+}
+
+func (r *auditRepository) filter(filter domain.AuditFilter) *gorm.DB {
+	query := r.db
+	for column, value := range map[string]string{
+		"actor_id":   filter.ActorID,
+		"subject_id": filter.SubjectID,
+		"email":      filter.Email,
+		"action":     filter.Action,
+		"outcome":    filter.Outcome,
+		"ip":         filter.IP,
+	} {
+		if value != "" {
+			query = query.Where(column+" = ?", value)
+		}
+	}
+	if !filter.From.IsZero() {
+		query = query.Where("occurred_at >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("occurred_at < ?", filter.To)
+	}
+	// A new session lets Find run both the count and the page query on it.
+	return query.Session(&gorm.Session{})
+}
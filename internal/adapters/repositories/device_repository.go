@@ -0,0 +1,79 @@
+package repositories
+
+import (
+	"errors"
+	"time"
+
+	"github.com/kanta/backend-challenge/internal/adapters/repositories/models"
+	"github.com/kanta/backend-challenge/internal/core/domain"
+	"github.com/kanta/backend-challenge/internal/core/ports"
+	"gorm.io/gorm"
+)
+
+type deviceRepository struct {
+	db *gorm.DB
+}
+
+func NewDeviceRepository(db *gorm.DB) ports.DeviceRepository {
+	return &deviceRepository{
+		db: db,
+	}
+}
+
+func (r *deviceRepository) Create(device *domain.Device) error {
+	m := models.ToUserDeviceModels(device)
+
+	result := r.db.Create(m)
+	if result.Error != nil {
+		return result.Error
+	}
+	device.ID = m.ID
+	return nil
+}
+
+func (r *deviceRepository) FindByFingerprint(userID, fingerprint string) (*domain.Device, error) {
+	var m models.UserDevice
+
+	result := r.db.First(&m, "user_id = ? AND fingerprint = ?", userID, fingerprint)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, errors.New("device not found")
+	}
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return models.ToUserDeviceDomain(&m), nil
+}
+
+func (r *deviceRepository) Touch(id, ip string, seenAt time.Time) error {
+	return r.db.Model(&models.UserDevice{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"last_ip":      ip,
+		"last_seen_at": seenAt,
+	}).Error
+}
+
+func (r *deviceRepository) ListByUser(userID string) ([]*domain.Device, error) {
+	var ms []models.UserDevice
+
+	result := r.db.Where("user_id = ?", userID).Order("last_seen_at DESC").Find(&ms)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	devices := make([]*domain.Device, 0, len(ms))
+	for i := range ms {
+		devices = append(devices, models.ToUserDeviceDomain(&ms[i]))
+	}
+	return devices, nil
+}
+
+func (r *deviceRepository) Delete(userID, id string) error {
+	result := r.db.Where("user_id = ? AND id = ?", userID, id).Delete(&models.UserDevice{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("device not found")
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package repositories
+
+import (
+	"errors"
+	"time"
+
+	"github.com/kanta/backend-challenge/internal/adapters/repositories/models"
+	"github.com/kanta/backend-challenge/internal/core/domain"
+	"github.com/kanta/backend-challenge/internal/core/ports"
+	"gorm.io/gorm"
+)
+
+type exportRepository struct {
+	db *gorm.DB
+}
+
+func NewExportRepository(db *gorm.DB) ports.ExportRepository {
+	return &exportRepository{
+		db: db,
+	}
+}
+
+func (r *exportRepository) Create(export *domain.DataExport, since time.Time) error {
+	m := models.ToDataExportModels(export)
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		// Serialises concurrent requests of the same user, so that only one
+		// of them passes the check below.
+		if err := tx.Exec(`SELECT pg_advisory_xact_lock(hashtext(?))`, "data_export:"+export.UserID).Error; err != nil {
+			return err
+		}
+
+		var recent int64
+		err := tx.Model(&models.DataExport{}).
+			Where("user_id = ? AND status <> ? AND created_at > ?", export.UserID, domain.ExportFailed, since).
+			Count(&recent).Error
+		if err != nil {
+			return err
+		}
+		if recent > 0 {
+			return domain.ErrExportRateLimited
+		}
+		return tx.Create(m).Error
+	})
+	if err != nil {
+		return err
+	}
+	export.ID = m.ID
+	return nil
+}
+
+func (r *exportRepository) Find(id string) (*domain.DataExport, error) {
+	var m models.DataExport
+
+	result := r.db.First(&m, "id = ?", id)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, domain.ErrExportNotFound
+	}
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return models.ToDataExportDomain(&m), nil
+}
+
+func (r *exportRepository) ClaimPending(now time.Time, lease time.Duration, limit int) ([]*domain.DataExport, error) {
+	var ms []models.DataExport
+	result := r.db.Raw(`UPDATE data_exports SET lease_until = ?
+		WHERE id IN (
+			SELECT id FROM data_exports
+			WHERE status = ? AND lease_until <= ?
+			ORDER BY created_at
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`, now.Add(lease), domain.ExportPending, now, limit).Scan(&ms)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	exports := make([]*domain.DataExport, 0, len(ms))
+	for i := range ms {
+		exports = append(exports, models.ToDataExportDomain(&ms[i]))
+	}
+	return exports, nil
+}
+
+func (r *exportRepository) Update(export *domain.DataExport) error {
+	m := models.ToDataExportModels(export)
+
+	return r.db.Model(m).
+		Select("status", "error", "size", "completed_at", "expires_at").
+		Updates(m).Error
+}
+
+func (r *exportRepository) FindExpired(now time.Time, limit int) ([]*domain.DataExport, error) {
+	var ms []models.DataExport
+
+	result := r.db.Where("status = ? AND expires_at <= ?", domain.ExportReady, now).Order("expires_at").Limit(limit).Find(&ms)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	exports := make([]*domain.DataExport, 0, len(ms))
+	for i := range ms {
+		exports = append(exports, models.ToDataExportDomain(&ms[i]))
+	}
+	return exports, nil
+}
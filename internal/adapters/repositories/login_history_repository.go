@@ -0,0 +1,52 @@
+package repositories
+
+import (
+	"github.com/kanta/backend-challenge/internal/adapters/repositories/models"
+	"github.com/kanta/backend-challenge/internal/core/domain"
+	"github.com/kanta/backend-challenge/internal/core/ports"
+	"gorm.io/gorm"
+)
+
+type loginHistoryRepository struct {
+	db *gorm.DB
+}
+
+func NewLoginHistoryRepository(db *gorm.DB) ports.LoginHistoryRepository {
+	return &loginHistoryRepository{
+		db: db,
+	}
+}
+
+func (r *loginHistoryRepository) Record(record *domain.LoginRecord) error {
+	m := models.ToLoginHistoryModels(record)
+
+	result := r.db.Create(m)
+	if result.Error != nil {
+		return result.Error
+	}
+	record.ID = m.ID
+	return nil
+}
+
+func (r *loginHistoryRepository) Recent(userID string, limit int) ([]*domain.LoginRecord, error) {
+	return r.find(r.db.Where("user_id = ?", userID).Order("occurred_at DESC").Limit(limit))
+}
+
+func (r *loginHistoryRepository) ListByUser(userID string) ([]*domain.LoginRecord, error) {
+	return r.find(r.db.Where("user_id = ?", userID).Order("occurred_at DESC"))
+}
+
+func (r *loginHistoryRepository) find(query *gorm.DB) ([]*domain.LoginRecord, error) {
+	var ms []models.LoginHistory
+
+	result := query.Find(&ms)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	records := make([]*domain.LoginRecord, 0, len(ms))
+	for i := range ms {
+		records = append(records, models.ToLoginHistoryDomain(&ms[i]))
+	}
+	return records, nil
+}
@@ -0,0 +1,214 @@
+package models
+
+import (
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kanta/backend-challenge/internal/core/domain"
+)
+
+type AuditLog struct {
+	ID         string         `gorm:"primaryKey;type:uuid;default:gen_random_uuid()" json:"id"`
+	OccurredAt time.Time      `gorm:"not null;index" json:"occurred_at"`
+	Action     string         `gorm:"type:varchar(64);not null;index" json:"action"`
+	Outcome    string         `gorm:"type:varchar(16);not null" json:"outcome"`
+	ActorID    string         `gorm:"type:varchar(255);index" json:"actor_id"`
+	SubjectID  string         `gorm:"type:varchar(255);index" json:"subject_id"`
+	Email      string         `gorm:"type:varchar(255);index" json:"email"`
+	IP         string         `gorm:"type:varchar(64);index" json:"ip"`
+	UserAgent  string         `gorm:"type:text" json:"user_agent"`
+	Reason     string         `gorm:"type:varchar(255)" json:"reason"`
+	Details    map[string]any `gorm:"type:jsonb;serializer:json" json:"details"`
+	// Entries written before hash chaining have sequence 0 and no hashes.
+	Partition string `gorm:"column:partition_key;type:varchar(16);not null;default:'';uniqueIndex:idx_audit_logs_chain,where:sequence > 0" json:"partition"`
+	Sequence  int64  `gorm:"not null;default:0;uniqueIndex:idx_audit_logs_chain" json:"sequence"`
+	PrevHash  string `gorm:"type:varchar(64);not null;default:''" json:"prev_hash"`
+	Hash      string `gorm:"type:varchar(64);not null;default:''" json:"hash"`
+}
+
+func (AuditLog) TableName() string {
+	return "audit_logs"
+}
+
+// AuditChainHead is the last entry of a partition's hash chain. Appending
+// locks the row, so entries of one partition are chained one at a time.
+type AuditChainHead struct {
+	Partition string    `gorm:"column:partition_key;type:varchar(16);primaryKey" json:"partition"`
+	Sequence  int64     `gorm:"not null" json:"sequence"`
+	Hash      string    `gorm:"type:varchar(64);not null" json:"hash"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (AuditChainHead) TableName() string {
+	return "audit_chain_heads"
+}
+
+type AuditCheckpoint struct {
+	ID        string    `gorm:"primaryKey;type:uuid;default:gen_random_uuid()" json:"id"`
+	Partition string    `gorm:"column:partition_key;type:varchar(16);not null;uniqueIndex:idx_audit_checkpoints_head" json:"partition"`
+	Sequence  int64     `gorm:"not null;uniqueIndex:idx_audit_checkpoints_head" json:"sequence"`
+	Hash      string    `gorm:"type:varchar(64);not null" json:"hash"`
+	CreatedAt time.Time `gorm:"not null" json:"created_at"`
+	KeyID     string    `gorm:"type:varchar(64);not null" json:"key_id"`
+	Signature []byte    `gorm:"type:bytea;not null" json:"signature"`
+}
+
+func (AuditCheckpoint) TableName() string {
+	return "audit_checkpoints"
+}
+
+// AuditPurge records that retention deleted a partition's entries up to
+// Sequence. Like checkpoints, purges are never deleted.
+type AuditPurge struct {
+	ID        string    `gorm:"primaryKey;type:uuid;default:gen_random_uuid()" json:"id"`
+	Partition string    `gorm:"column:partition_key;type:varchar(16);not null;uniqueIndex:idx_audit_purges_head" json:"partition"`
+	Sequence  int64     `gorm:"not null;uniqueIndex:idx_audit_purges_head" json:"sequence"`
+	Hash      string    `gorm:"type:varchar(64);not null" json:"hash"`
+	PurgedAt  time.Time `gorm:"not null" json:"purged_at"`
+	KeyID     string    `gorm:"type:varchar(64);not null" json:"key_id"`
+	Signature []byte    `gorm:"type:bytea;not null" json:"signature"`
+}
+
+func (AuditPurge) TableName() string {
+	return "audit_purges"
+}
+
+// AuditPurgeRole is the database role the retention purge switches to. It
+// is not created here: an administrator creates it and grants it to the
+// application's user, see the README.
+const AuditPurgeRole = "audit_purger"
+
+// auditAppendOnly rejects changes to existing audit rows, checkpoints and
+// purges at the database level. Only the retention purge may delete audit
+// rows, as AuditPurgeRole with audit.purge set in its transaction.
+var auditAppendOnly = append([]string{
+	`CREATE OR REPLACE FUNCTION audit_logs_append_only() RETURNS trigger AS $$
+BEGIN
+	IF TG_OP = 'DELETE' AND TG_TABLE_NAME = 'audit_logs'
+		AND current_user = '` + AuditPurgeRole + `'
+		AND current_setting('audit.purge', true) = 'on' THEN
+		RETURN OLD;
+	END IF;
+	RAISE EXCEPTION '% is append-only', TG_TABLE_NAME;
+END;
+$$ LANGUAGE plpgsql`,
+}, slices.Concat(
+	appendOnlyTriggers("audit_logs"),
+	appendOnlyTriggers("audit_checkpoints"),
+	appendOnlyTriggers("audit_purges"),
+)...)
+
+func appendOnlyTriggers(table string) []string {
+	return []string{
+		`DROP TRIGGER IF EXISTS ` + table + `_no_update ON ` + table,
+		`CREATE TRIGGER ` + table + `_no_update BEFORE UPDATE OR DELETE ON ` + table + `
+	FOR EACH ROW EXECUTE FUNCTION audit_logs_append_only()`,
+		`DROP TRIGGER IF EXISTS ` + table + `_no_truncate ON ` + table,
+		`CREATE TRIGGER ` + table + `_no_truncate BEFORE TRUNCATE ON ` + table + `
+	FOR EACH STATEMENT EXECUTE FUNCTION audit_logs_append_only()`,
+	}
+}
+
+func ToAuditLogModels(e *domain.AuditEntry) *AuditLog {
+	id := e.ID
+	if _, err := uuid.Parse(id); err != nil {
+		id = uuid.New().String()
+	}
+
+	return &AuditLog{
+		ID:         id,
+		OccurredAt: e.OccurredAt,
+		Action:     e.Action,
+		Outcome:    e.Outcome,
+		ActorID:    e.ActorID,
+		SubjectID:  e.SubjectID,
+		Email:      e.Email,
+		IP:         e.IP,
+		UserAgent:  e.UserAgent,
+		Reason:     e.Reason,
+		Details:    e.Details,
+		Partition:  e.Partition,
+		Sequence:   e.Sequence,
+		PrevHash:   e.PrevHash,
+		Hash:       e.Hash,
+	}
+}
+
+func ToAuditLogDomain(m *AuditLog) *domain.AuditEntry {
+	return &domain.AuditEntry{
+		ID:         m.ID,
+		OccurredAt: m.OccurredAt,
+		Action:     m.Action,
+		Outcome:    m.Outcome,
+		ActorID:    m.ActorID,
+		SubjectID:  m.SubjectID,
+		Email:      m.Email,
+		IP:         m.IP,
+		UserAgent:  m.UserAgent,
+		Reason:     m.Reason,
+		Details:    m.Details,
+		Partition:  m.Partition,
+		Sequence:   m.Sequence,
+		PrevHash:   m.PrevHash,
+		Hash:       m.Hash,
+	}
+}
+
+func ToAuditCheckpointModels(c *domain.AuditCheckpoint) *AuditCheckpoint {
+	id := c.ID
+	if _, err := uuid.Parse(id); err != nil {
+		id = uuid.New().String()
+	}
+
+	return &AuditCheckpoint{
+		ID:        id,
+		Partition: c.Partition,
+		Sequence:  c.Sequence,
+		Hash:      c.Hash,
+		CreatedAt: c.CreatedAt,
+		KeyID:     c.KeyID,
+		Signature: c.Signature,
+	}
+}
+
+func ToAuditCheckpointDomain(m *AuditCheckpoint) *domain.AuditCheckpoint {
+	return &domain.AuditCheckpoint{
+		ID:        m.ID,
+		Partition: m.Partition,
+		Sequence:  m.Sequence,
+		Hash:      m.Hash,
+		CreatedAt: m.CreatedAt,
+		KeyID:     m.KeyID,
+		Signature: m.Signature,
+	}
+}
+
+func ToAuditPurgeModels(p *domain.AuditPurge) *AuditPurge {
+	id := p.ID
+	if _, err := uuid.Parse(id); err != nil {
+		id = uuid.New().String()
+	}
+
+	return &AuditPurge{
+		ID:        id,
+		Partition: p.Partition,
+		Sequence:  p.Sequence,
+		Hash:      p.Hash,
+		PurgedAt:  p.PurgedAt,
+		KeyID:     p.KeyID,
+		Signature: p.Signature,
+	}
+}
+
+func ToAuditPurgeDomain(m *AuditPurge) *domain.AuditPurge {
+	return &domain.AuditPurge{
+		ID:        m.ID,
+		Partition: m.Partition,
+		Sequence:  m.Sequence,
+		Hash:      m.Hash,
+		PurgedAt:  m.PurgedAt,
+		KeyID:     m.KeyID,
+		Signature: m.Signature,
+	}
+}
@@ -0,0 +1,58 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kanta/backend-challenge/internal/core/domain"
+)
+
+type DataExport struct {
+	ID          string     `gorm:"primaryKey;type:uuid" json:"id"`
+	UserID      string     `gorm:"type:uuid;not null;index:idx_data_exports_user,priority:1" json:"user_id"`
+	Status      string     `gorm:"type:varchar(16);not null;index:idx_data_exports_status,priority:1" json:"status"`
+	Error       string     `gorm:"type:text" json:"error"`
+	Size        int64      `gorm:"not null;default:0" json:"size"`
+	CreatedAt   time.Time  `gorm:"not null;index:idx_data_exports_user,priority:2" json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at"`
+	ExpiresAt   *time.Time `gorm:"index:idx_data_exports_status,priority:2" json:"expires_at"`
+	// LeaseUntil hides a pending export from other workers while one is
+	// building it.
+	LeaseUntil time.Time `gorm:"not null" json:"lease_until"`
+}
+
+func (DataExport) TableName() string {
+	return "data_exports"
+}
+
+func ToDataExportModels(e *domain.DataExport) *DataExport {
+	id := e.ID
+	if _, err := uuid.Parse(id); err != nil {
+		id = uuid.New().String()
+	}
+
+	return &DataExport{
+		ID:          id,
+		UserID:      e.UserID,
+		Status:      e.Status,
+		Error:       e.Error,
+		Size:        e.Size,
+		CreatedAt:   e.CreatedAt,
+		CompletedAt: e.CompletedAt,
+		ExpiresAt:   e.ExpiresAt,
+		LeaseUntil:  e.CreatedAt,
+	}
+}
+
+func ToDataExportDomain(m *DataExport) *domain.DataExport {
+	return &domain.DataExport{
+		ID:          m.ID,
+		UserID:      m.UserID,
+		Status:      m.Status,
+		Error:       m.Error,
+		Size:        m.Size,
+		CreatedAt:   m.CreatedAt,
+		CompletedAt: m.CompletedAt,
+		ExpiresAt:   m.ExpiresAt,
+	}
+}
@@ -0,0 +1,54 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kanta/backend-challenge/internal/core/domain"
+)
+
+type UserDevice struct {
+	ID          string    `gorm:"primaryKey;type:uuid;default:gen_random_uuid()" json:"id"`
+	UserID      string    `gorm:"type:uuid;not null;uniqueIndex:idx_user_devices_fingerprint" json:"user_id"`
+	Fingerprint string    `gorm:"type:varchar(64);not null;uniqueIndex:idx_user_devices_fingerprint" json:"fingerprint"`
+	DeviceID    string    `gorm:"type:varchar(255)" json:"device_id"`
+	UserAgent   string    `gorm:"type:text" json:"user_agent"`
+	LastIP      string    `gorm:"type:varchar(64)" json:"last_ip"`
+	FirstSeenAt time.Time `gorm:"not null" json:"first_seen_at"`
+	LastSeenAt  time.Time `gorm:"not null" json:"last_seen_at"`
+}
+
+func (UserDevice) TableName() string {
+	return "user_devices"
+}
+
+func ToUserDeviceModels(d *domain.Device) *UserDevice {
+	id := d.ID
+	if _, err := uuid.Parse(id); err != nil {
+		id = uuid.New().String()
+	}
+
+	return &UserDevice{
+		ID:          id,
+		UserID:      d.UserID,
+		Fingerprint: d.Fingerprint,
+		DeviceID:    d.DeviceID,
+		UserAgent:   d.UserAgent,
+		LastIP:      d.LastIP,
+		FirstSeenAt: d.FirstSeenAt,
+		LastSeenAt:  d.LastSeenAt,
+	}
+}
+
+func ToUserDeviceDomain(d *UserDevice) *domain.Device {
+	return &domain.Device{
+		ID:          d.ID,
+		UserID:      d.UserID,
+		Fingerprint: d.Fingerprint,
+		DeviceID:    d.DeviceID,
+		UserAgent:   d.UserAgent,
+		LastIP:      d.LastIP,
+		FirstSeenAt: d.FirstSeenAt,
+		LastSeenAt:  d.LastSeenAt,
+	}
+}
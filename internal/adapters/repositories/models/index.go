@@ -6,7 +6,17 @@ func GetAllModels() []interface{} {
 	var models []interface{}
 
 	modelsMap := map[string]interface{}{
-		"User": &User{},
+		"User":                &User{},
+		"UserDevice":          &UserDevice{},
+		"AuditLog":            &AuditLog{},
+		"AuditChainHead":      &AuditChainHead{},
+		"AuditCheckpoint":     &AuditCheckpoint{},
+		"AuditPurge":          &AuditPurge{},
+		"WebhookSubscription": &WebhookSubscription{},
+		"WebhookDelivery":     &WebhookDelivery{},
+		"OutboxMessage":       &OutboxMessage{},
+		"LoginHistory":        &LoginHistory{},
+		"DataExport":          &DataExport{},
 	}
 
 	for _, m := range modelsMap {
@@ -16,3 +26,13 @@ func GetAllModels() []interface{} {
 	fmt.Printf("Found %d models for AutoMigrate\n", len(models))
 	return models
 }
+
+// GetPostMigrations returns statements run after AutoMigrate, for database
+// objects GORM cannot declare such as triggers.
+func GetPostMigrations() []string {
+	var statements []string
+	statements = append(statements, auditAppendOnly...)
+	statements = append(statements, outboxIndexes...)
+	statements = append(statements, webhookDeliveryIndexes...)
+	return statements
+}
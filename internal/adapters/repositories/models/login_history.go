@@ -0,0 +1,72 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kanta/backend-challenge/internal/core/domain"
+)
+
+type LoginHistory struct {
+	ID             string    `gorm:"primaryKey;type:uuid;default:gen_random_uuid()" json:"id"`
+	UserID         string    `gorm:"type:uuid;not null;index:idx_login_history_user,priority:1" json:"user_id"`
+	IP             string    `gorm:"type:varchar(64);not null" json:"ip"`
+	Country        string    `gorm:"type:varchar(2)" json:"country"`
+	ASN            int64     `json:"asn"`
+	ASOrganization string    `gorm:"type:varchar(255)" json:"as_organization"`
+	Latitude       *float64  `json:"latitude"`
+	Longitude      *float64  `json:"longitude"`
+	Score          int       `gorm:"not null;default:0" json:"score"`
+	Decision       string    `gorm:"type:varchar(16);not null" json:"decision"`
+	OccurredAt     time.Time `gorm:"not null;index:idx_login_history_user,priority:2,sort:desc" json:"occurred_at"`
+}
+
+func (LoginHistory) TableName() string {
+	return "login_history"
+}
+
+func ToLoginHistoryModels(r *domain.LoginRecord) *LoginHistory {
+	id := r.ID
+	if _, err := uuid.Parse(id); err != nil {
+		id = uuid.New().String()
+	}
+
+	m := &LoginHistory{
+		ID:             id,
+		UserID:         r.UserID,
+		IP:             r.IP,
+		Country:        r.Location.Country,
+		ASN:            int64(r.Location.ASN),
+		ASOrganization: r.Location.ASOrganization,
+		Score:          r.Score,
+		Decision:       r.Decision,
+		OccurredAt:     r.OccurredAt,
+	}
+	if r.Location.HasCoordinates {
+		m.Latitude = &r.Location.Latitude
+		m.Longitude = &r.Location.Longitude
+	}
+	return m
+}
+
+func ToLoginHistoryDomain(m *LoginHistory) *domain.LoginRecord {
+	r := &domain.LoginRecord{
+		ID:     m.ID,
+		UserID: m.UserID,
+		IP:     m.IP,
+		Location: domain.GeoLocation{
+			Country:        m.Country,
+			ASN:            uint(m.ASN),
+			ASOrganization: m.ASOrganization,
+		},
+		Score:      m.Score,
+		Decision:   m.Decision,
+		OccurredAt: m.OccurredAt,
+	}
+	if m.Latitude != nil && m.Longitude != nil {
+		r.Location.HasCoordinates = true
+		r.Location.Latitude = *m.Latitude
+		r.Location.Longitude = *m.Longitude
+	}
+	return r
+}
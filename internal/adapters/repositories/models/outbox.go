@@ -0,0 +1,65 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/kanta/backend-challenge/internal/core/domain"
+)
+
+type OutboxMessage struct {
+	ID            int64      `gorm:"primaryKey;autoIncrement" json:"id"`
+	EventID       string     `gorm:"type:varchar(64);not null;uniqueIndex" json:"event_id"`
+	EventType     string     `gorm:"type:varchar(64);not null" json:"event_type"`
+	AggregateID   string     `gorm:"type:varchar(255);not null" json:"aggregate_id"`
+	Payload       string     `gorm:"type:jsonb;not null" json:"payload"`
+	Attempts      int        `gorm:"not null;default:0" json:"attempts"`
+	NextAttemptAt time.Time  `gorm:"not null" json:"next_attempt_at"`
+	LastError     string     `gorm:"type:text" json:"last_error"`
+	CreatedAt     time.Time  `json:"created_at"`
+	PublishedTo   []string   `gorm:"type:jsonb;serializer:json" json:"published_to"`
+	PublishedAt   *time.Time `gorm:"index" json:"published_at"`
+}
+
+func (OutboxMessage) TableName() string {
+	return "outbox_messages"
+}
+
+// outboxIndexes backs the relay's search for due messages, which only looks
+// at unpublished rows.
+var outboxIndexes = []string{
+	`CREATE INDEX IF NOT EXISTS idx_outbox_messages_pending ON outbox_messages (aggregate_id, id) WHERE published_at IS NULL`,
+	`CREATE INDEX IF NOT EXISTS idx_outbox_messages_due ON outbox_messages (next_attempt_at, id) WHERE published_at IS NULL`,
+}
+
+func ToOutboxMessageModels(event domain.Event) (*OutboxMessage, error) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+	return &OutboxMessage{
+		EventID:       event.ID,
+		EventType:     event.Type,
+		AggregateID:   event.AggregateID,
+		Payload:       string(payload),
+		NextAttemptAt: event.OccurredAt,
+		CreatedAt:     event.OccurredAt,
+	}, nil
+}
+
+func ToOutboxMessageDomain(m *OutboxMessage) (*domain.OutboxMessage, error) {
+	var event domain.Event
+	if err := json.Unmarshal([]byte(m.Payload), &event); err != nil {
+		return nil, err
+	}
+	return &domain.OutboxMessage{
+		ID:            m.ID,
+		Event:         event,
+		Attempts:      m.Attempts,
+		NextAttemptAt: m.NextAttemptAt,
+		LastError:     m.LastError,
+		CreatedAt:     m.CreatedAt,
+		PublishedTo:   m.PublishedTo,
+		PublishedAt:   m.PublishedAt,
+	}, nil
+}
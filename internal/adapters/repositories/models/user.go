@@ -8,11 +8,14 @@ import (
 )
 
 type User struct {
-	ID        string    `gorm:"primaryKey;type:uuid;default:gen_random_uuid()" json:"id"`
-	Name      string    `gorm:"type:varchar(255);not null" json:"name"`
-	Email     string    `gorm:"type:varchar(255);uniqueIndex;not null" json:"email"`
-	Password  string    `gorm:"type:varchar(255);not null" json:"-"`
-	CreatedAt time.Time `gorm:"not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+	ID       string `gorm:"primaryKey;type:uuid;default:gen_random_uuid()" json:"id"`
+	Name     string `gorm:"type:varchar(255);not null" json:"name"`
+	Email    string `gorm:"type:varchar(255);uniqueIndex;not null" json:"email"`
+	Password string `gorm:"type:varchar(255);not null" json:"-"`
+	Role     string `gorm:"type:varchar(32);not null;default:user" json:"role"`
+	// TokenVersion is bumped to sign the user out everywhere.
+	TokenVersion int       `gorm:"not null;default:0" json:"-"`
+	CreatedAt    time.Time `gorm:"not null;default:CURRENT_TIMESTAMP" json:"created_at"`
 }
 
 func (User) TableName() string {
@@ -31,20 +34,24 @@ func ToUserModels(u *domain.User) *User {
 	}
 
 	return &User{
-		ID:        id,
-		Name:      u.Name,
-		Email:     u.Email,
-		Password:  u.Password,
-		CreatedAt: u.CreatedAt,
+		ID:           id,
+		Name:         u.Name,
+		Email:        u.Email,
+		Password:     u.Password,
+		Role:         u.Role,
+		TokenVersion: u.TokenVersion,
+		CreatedAt:    u.CreatedAt,
 	}
 }
 
 func ToUserDomain(u *User) *domain.User {
 	return &domain.User{
-		ID:        u.ID,
-		Name:      u.Name,
-		Email:     u.Email,
-		Password:  u.Password,
-		CreatedAt: u.CreatedAt,
+		ID:           u.ID,
+		Name:         u.Name,
+		Email:        u.Email,
+		Password:     u.Password,
+		Role:         u.Role,
+		TokenVersion: u.TokenVersion,
+		CreatedAt:    u.CreatedAt,
 	}
 }
@@ -0,0 +1,119 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kanta/backend-challenge/internal/core/domain"
+)
+
+type WebhookSubscription struct {
+	ID         string    `gorm:"primaryKey;type:uuid" json:"id"`
+	URL        string    `gorm:"type:text;not null" json:"url"`
+	Secret     string    `gorm:"type:varchar(255);not null" json:"-"`
+	EventTypes []string  `gorm:"type:jsonb;serializer:json;not null" json:"event_types"`
+	Active     bool      `gorm:"not null;default:true" json:"active"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+func (WebhookSubscription) TableName() string {
+	return "webhook_subscriptions"
+}
+
+type WebhookDelivery struct {
+	ID             string     `gorm:"primaryKey;type:uuid" json:"id"`
+	SubscriptionID string     `gorm:"type:uuid;not null;index" json:"subscription_id"`
+	EventID        string     `gorm:"type:varchar(64);not null" json:"event_id"`
+	EventType      string     `gorm:"type:varchar(64);not null" json:"event_type"`
+	Payload        string     `gorm:"type:jsonb;not null" json:"payload"`
+	Status         string     `gorm:"type:varchar(16);not null;index:idx_webhook_deliveries_due,priority:1" json:"status"`
+	Attempts       int        `gorm:"not null;default:0" json:"attempts"`
+	LastStatusCode int        `json:"last_status_code"`
+	LastError      string     `gorm:"type:text" json:"last_error"`
+	NextAttemptAt  time.Time  `gorm:"not null;index:idx_webhook_deliveries_due,priority:2" json:"next_attempt_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+	DeliveredAt    *time.Time `json:"delivered_at"`
+}
+
+func (WebhookDelivery) TableName() string {
+	return "webhook_deliveries"
+}
+
+// webhookDeliveryIndexes allow one delivery per subscription and event, so an
+// event the outbox relays again is not delivered twice. Duplicates written
+// before the index existed are removed first, keeping the oldest.
+var webhookDeliveryIndexes = []string{
+	`DELETE FROM webhook_deliveries d USING webhook_deliveries o
+		WHERE d.subscription_id = o.subscription_id AND d.event_id = o.event_id
+		AND (d.created_at, d.id) > (o.created_at, o.id)`,
+	`CREATE UNIQUE INDEX IF NOT EXISTS idx_webhook_deliveries_event ON webhook_deliveries (subscription_id, event_id)`,
+}
+
+func ToWebhookSubscriptionModels(s *domain.WebhookSubscription) *WebhookSubscription {
+	id := s.ID
+	if _, err := uuid.Parse(id); err != nil {
+		id = uuid.New().String()
+	}
+
+	return &WebhookSubscription{
+		ID:         id,
+		URL:        s.URL,
+		Secret:     s.Secret,
+		EventTypes: s.EventTypes,
+		Active:     s.Active,
+		CreatedAt:  s.CreatedAt,
+		UpdatedAt:  s.UpdatedAt,
+	}
+}
+
+func ToWebhookSubscriptionDomain(m *WebhookSubscription) *domain.WebhookSubscription {
+	return &domain.WebhookSubscription{
+		ID:         m.ID,
+		URL:        m.URL,
+		Secret:     m.Secret,
+		EventTypes: m.EventTypes,
+		Active:     m.Active,
+		CreatedAt:  m.CreatedAt,
+		UpdatedAt:  m.UpdatedAt,
+	}
+}
+
+func ToWebhookDeliveryModels(d *domain.WebhookDelivery) *WebhookDelivery {
+	id := d.ID
+	if _, err := uuid.Parse(id); err != nil {
+		id = uuid.New().String()
+	}
+
+	return &WebhookDelivery{
+		ID:             id,
+		SubscriptionID: d.SubscriptionID,
+		EventID:        d.EventID,
+		EventType:      d.EventType,
+		Payload:        string(d.Payload),
+		Status:         d.Status,
+		Attempts:       d.Attempts,
+		LastStatusCode: d.LastStatusCode,
+		LastError:      d.LastError,
+		NextAttemptAt:  d.NextAttemptAt,
+		CreatedAt:      d.CreatedAt,
+		DeliveredAt:    d.DeliveredAt,
+	}
+}
+
+func ToWebhookDeliveryDomain(m *WebhookDelivery) *domain.WebhookDelivery {
+	return &domain.WebhookDelivery{
+		ID:             m.ID,
+		SubscriptionID: m.SubscriptionID,
+		EventID:        m.EventID,
+		EventType:      m.EventType,
+		Payload:        []byte(m.Payload),
+		Status:         m.Status,
+		Attempts:       m.Attempts,
+		LastStatusCode: m.LastStatusCode,
+		LastError:      m.LastError,
+		NextAttemptAt:  m.NextAttemptAt,
+		CreatedAt:      m.CreatedAt,
+		DeliveredAt:    m.DeliveredAt,
+	}
+}
@@ -0,0 +1,111 @@
+package repositories
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/kanta/backend-challenge/internal/adapters/repositories/models"
+	"github.com/kanta/backend-challenge/internal/core/domain"
+	"github.com/kanta/backend-challenge/internal/core/ports"
+	"gorm.io/gorm"
+)
+
+type outboxRepository struct {
+	db *gorm.DB
+}
+
+func NewOutboxRepository(db *gorm.DB) ports.OutboxRepository {
+	return &outboxRepository{
+		db: db,
+	}
+}
+
+func (r *outboxRepository) Append(events ...domain.Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	ms := make([]*models.OutboxMessage, 0, len(events))
+	for _, event := range events {
+		m, err := models.ToOutboxMessageModels(event)
+		if err != nil {
+			return err
+		}
+		ms = append(ms, m)
+	}
+	return r.db.Create(ms).Error
+}
+
+func (r *outboxRepository) ClaimDue(now time.Time, lease time.Duration, limit int) ([]*domain.OutboxMessage, error) {
+	var ms []models.OutboxMessage
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		// Claims are serialized so that a relay never claims a message while
+		// another is claiming an earlier one of the same aggregate. The lock
+		// is released with the transaction, before anything is published.
+		acquired := false
+		if err := tx.Raw(`SELECT pg_try_advisory_xact_lock(hashtext('outbox_relay'))`).Scan(&acquired).Error; err != nil {
+			return err
+		}
+		if !acquired {
+			return nil
+		}
+
+		return tx.Raw(`UPDATE outbox_messages SET next_attempt_at = ?
+			WHERE id IN (
+				SELECT o.id FROM outbox_messages o
+				WHERE o.published_at IS NULL AND o.next_attempt_at <= ?
+				AND NOT EXISTS (
+					SELECT 1 FROM outbox_messages p
+					WHERE p.aggregate_id = o.aggregate_id AND p.published_at IS NULL
+					AND p.id < o.id AND p.next_attempt_at > ?
+				)
+				ORDER BY o.id
+				LIMIT ?
+			)
+			RETURNING *`, now.Add(lease), now, now, limit).Scan(&ms).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// RETURNING does not keep the order of the subquery.
+	slices.SortFunc(ms, func(a, b models.OutboxMessage) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+	messages := make([]*domain.OutboxMessage, 0, len(ms))
+	for i := range ms {
+		message, err := models.ToOutboxMessageDomain(&ms[i])
+		if err != nil {
+			return nil, fmt.Errorf("decode outbox message %d: %w", ms[i].ID, err)
+		}
+		messages = append(messages, message)
+	}
+	return messages, nil
+}
+
+func (r *outboxRepository) Update(messages ...*domain.OutboxMessage) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for _, message := range messages {
+			err := tx.Model(&models.OutboxMessage{ID: message.ID}).
+				Select("attempts", "next_attempt_at", "last_error", "published_to", "published_at").
+				Updates(&models.OutboxMessage{
+					Attempts:      message.Attempts,
+					NextAttemptAt: message.NextAttemptAt,
+					LastError:     message.LastError,
+					PublishedTo:   message.PublishedTo,
+					PublishedAt:   message.PublishedAt,
+				}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *outboxRepository) DeletePublished(cutoff time.Time) (int64, error) {
+	result := r.db.Where("published_at < ?", cutoff).Delete(&models.OutboxMessage{})
+	return result.RowsAffected, result.Error
+}
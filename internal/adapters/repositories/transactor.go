@@ -0,0 +1,25 @@
+package repositories
+
+import (
+	"github.com/kanta/backend-challenge/internal/core/ports"
+	"gorm.io/gorm"
+)
+
+type transactor struct {
+	db *gorm.DB
+}
+
+func NewTransactor(db *gorm.DB) ports.Transactor {
+	return &transactor{
+		db: db,
+	}
+}
+
+func (t *transactor) WithinTransaction(fn func(repos ports.Repositories) error) error {
+	return t.db.Transaction(func(tx *gorm.DB) error {
+		return fn(ports.Repositories{
+			Users:  NewUserRepository(tx),
+			Outbox: NewOutboxRepository(tx),
+		})
+	})
+}
@@ -21,12 +21,18 @@ func NewUserRepository(db *gorm.DB) ports.UserRepository {
 }
 
 func (r *userRepository) Create(user *domain.User) error {
-	user.CreatedAt = time.Now()
+	if user.CreatedAt.IsZero() {
+		user.CreatedAt = time.Now()
+	}
 
 	m := models.ToUserModels(user)
 
 	result := r.db.Create(m)
-	return result.Error
+	if result.Error != nil {
+		return result.Error
+	}
+	user.ID = m.ID
+	return nil
 }
 
 func (r *userRepository) FindOne(filter map[string]interface{}) (*domain.User, error) {
@@ -56,3 +62,26 @@ func (r *userRepository) FindByID(id string) (*domain.User, error) {
 
 	return models.ToUserDomain(&m), nil
 }
+
+func (r *userRepository) IncrementTokenVersion(id string) (int, error) {
+	var version int
+	result := r.db.Raw("UPDATE users SET token_version = token_version + 1 WHERE id = ? RETURNING token_version", id).Scan(&version)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return 0, errors.New("user not found")
+	}
+	return version, nil
+}
+
+func (r *userRepository) UpdatePassword(id, password string) error {
+	result := r.db.Model(&models.User{}).Where("id = ?", id).Update("password", password)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("user not found")
+	}
+	return nil
+}
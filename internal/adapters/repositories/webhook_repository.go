@@ -0,0 +1,184 @@
+package repositories
+
+import (
+	"errors"
+	"time"
+
+	"github.com/kanta/backend-challenge/internal/adapters/repositories/models"
+	"github.com/kanta/backend-challenge/internal/core/domain"
+	"github.com/kanta/backend-challenge/internal/core/ports"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type webhookRepository struct {
+	db *gorm.DB
+}
+
+func NewWebhookRepository(db *gorm.DB) ports.WebhookRepository {
+	return &webhookRepository{
+		db: db,
+	}
+}
+
+func (r *webhookRepository) CreateSubscription(subscription *domain.WebhookSubscription) error {
+	m := models.ToWebhookSubscriptionModels(subscription)
+
+	result := r.db.Create(m)
+	if result.Error != nil {
+		return result.Error
+	}
+	subscription.ID = m.ID
+	subscription.CreatedAt, subscription.UpdatedAt = m.CreatedAt, m.UpdatedAt
+	return nil
+}
+
+func (r *webhookRepository) UpdateSubscription(subscription *domain.WebhookSubscription) error {
+	m := models.ToWebhookSubscriptionModels(subscription)
+
+	result := r.db.Model(m).Select("url", "secret", "event_types", "active", "updated_at").Updates(m)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrWebhookNotFound
+	}
+	subscription.UpdatedAt = m.UpdatedAt
+	return nil
+}
+
+func (r *webhookRepository) DeleteSubscription(id string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("subscription_id = ?", id).Delete(&models.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		result := tx.Delete(&models.WebhookSubscription{}, "id = ?", id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return domain.ErrWebhookNotFound
+		}
+		return nil
+	})
+}
+
+func (r *webhookRepository) FindSubscription(id string) (*domain.WebhookSubscription, error) {
+	var m models.WebhookSubscription
+
+	result := r.db.First(&m, "id = ?", id)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, domain.ErrWebhookNotFound
+	}
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return models.ToWebhookSubscriptionDomain(&m), nil
+}
+
+func (r *webhookRepository) ListSubscriptions() ([]*domain.WebhookSubscription, error) {
+	var ms []models.WebhookSubscription
+	if err := r.db.Order("created_at").Find(&ms).Error; err != nil {
+		return nil, err
+	}
+
+	subscriptions := make([]*domain.WebhookSubscription, 0, len(ms))
+	for i := range ms {
+		subscriptions = append(subscriptions, models.ToWebhookSubscriptionDomain(&ms[i]))
+	}
+	return subscriptions, nil
+}
+
+func (r *webhookRepository) CreateDeliveries(deliveries []*domain.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	ms := make([]*models.WebhookDelivery, 0, len(deliveries))
+	for _, d := range deliveries {
+		ms = append(ms, models.ToWebhookDeliveryModels(d))
+	}
+
+	// A delivery that already exists for the subscription and event is
+	// kept as it is.
+	if err := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(ms).Error; err != nil {
+		return err
+	}
+	for i, m := range ms {
+		deliveries[i].ID = m.ID
+	}
+	return nil
+}
+
+func (r *webhookRepository) ClaimDueDeliveries(now time.Time, lease time.Duration, limit int) ([]*domain.WebhookDelivery, error) {
+	var ms []models.WebhookDelivery
+	result := r.db.Raw(`UPDATE webhook_deliveries SET next_attempt_at = ?
+		WHERE id IN (
+			SELECT id FROM webhook_deliveries
+			WHERE status = ? AND next_attempt_at <= ?
+			ORDER BY next_attempt_at
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`, now.Add(lease), domain.DeliveryPending, now, limit).Scan(&ms)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	deliveries := make([]*domain.WebhookDelivery, 0, len(ms))
+	for i := range ms {
+		deliveries = append(deliveries, models.ToWebhookDeliveryDomain(&ms[i]))
+	}
+	return deliveries, nil
+}
+
+func (r *webhookRepository) UpdateDelivery(delivery *domain.WebhookDelivery) error {
+	m := models.ToWebhookDeliveryModels(delivery)
+
+	return r.db.Model(m).
+		Select("status", "attempts", "last_status_code", "last_error", "next_attempt_at", "delivered_at").
+		Updates(m).Error
+}
+
+func (r *webhookRepository) FindDelivery(subscriptionID, id string) (*domain.WebhookDelivery, error) {
+	var m models.WebhookDelivery
+
+	result := r.db.First(&m, "id = ? AND subscription_id = ?", id, subscriptionID)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, domain.ErrWebhookDeliveryNotFound
+	}
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return models.ToWebhookDeliveryDomain(&m), nil
+}
+
+func (r *webhookRepository) FindDeliveries(filter domain.WebhookDeliveryFilter) ([]*domain.WebhookDelivery, int64, error) {
+	query := r.db.Model(&models.WebhookDelivery{}).Where("subscription_id = ?", filter.SubscriptionID)
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	query = query.Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var ms []models.WebhookDelivery
+	result := query.
+		Order("created_at DESC, id DESC").
+		Offset((filter.Page - 1) * filter.PageSize).
+		Limit(filter.PageSize).
+		Find(&ms)
+	if result.Error != nil {
+		return nil, 0, result.Error
+	}
+
+	deliveries := make([]*domain.WebhookDelivery, 0, len(ms))
+	for i := range ms {
+		deliveries = append(deliveries, models.ToWebhookDeliveryDomain(&ms[i]))
+	}
+	return deliveries, total, nil
+}
@@ -0,0 +1,165 @@
+package reputation
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/netip"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kanta/backend-challenge/internal/core/domain"
+	"github.com/kanta/backend-challenge/internal/core/ports"
+	"go.uber.org/zap"
+)
+
+// List is an IP reputation list file with one IP or CIDR per line. Blank
+// lines and lines starting with # are ignored.
+type List struct {
+	Name  string
+	Path  string
+	Score int
+}
+
+// ParseLists reads list definitions of the form "name:path[:score]". Lists
+// without a score get defaultScore.
+func ParseLists(entries []string, defaultScore int) ([]List, error) {
+	lists := make([]List, 0, len(entries))
+	for _, entry := range entries {
+		parts := strings.Split(entry, ":")
+		if len(parts) < 2 || len(parts) > 3 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid reputation list %q, want name:path[:score]", entry)
+		}
+		list := List{Name: parts[0], Path: parts[1], Score: defaultScore}
+		if len(parts) == 3 {
+			score, err := strconv.Atoi(parts[2])
+			if err != nil {
+				return nil, fmt.Errorf("invalid score in reputation list %q", entry)
+			}
+			list.Score = score
+		}
+		lists = append(lists, list)
+	}
+	return lists, nil
+}
+
+type loadedList struct {
+	List
+	prefixes []netip.Prefix
+}
+
+type Lists struct {
+	lists []List
+
+	mu     sync.RWMutex
+	loaded []loadedList
+}
+
+// NewLists loads lists. Run reloads them so that updated files are picked
+// up without a restart.
+func NewLists(lists []List) (*Lists, error) {
+	l := &Lists{lists: lists}
+	if err := l.Reload(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+var _ ports.IPReputation = (*Lists)(nil)
+
+func (l *Lists) Lookup(ip string) []domain.ReputationHit {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return nil
+	}
+	addr = addr.Unmap()
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	var hits []domain.ReputationHit
+	for _, list := range l.loaded {
+		for _, prefix := range list.prefixes {
+			if prefix.Contains(addr) {
+				hits = append(hits, domain.ReputationHit{List: list.Name, Score: list.Score})
+				break
+			}
+		}
+	}
+	return hits
+}
+
+// Reload reads every list file again. On error the previous lists stay in
+// use.
+func (l *Lists) Reload() error {
+	loaded := make([]loadedList, 0, len(l.lists))
+	for _, list := range l.lists {
+		prefixes, err := readList(list.Path)
+		if err != nil {
+			return fmt.Errorf("reputation list %s: %w", list.Name, err)
+		}
+		loaded = append(loaded, loadedList{List: list, prefixes: prefixes})
+	}
+
+	l.mu.Lock()
+	l.loaded = loaded
+	l.mu.Unlock()
+	return nil
+}
+
+// Run reloads the lists every interval until ctx is done.
+func (l *Lists) Run(ctx context.Context, interval time.Duration) {
+	if len(l.lists) == 0 || interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := l.Reload(); err != nil {
+			zap.L().Error("failed to reload reputation lists", zap.Error(err))
+		}
+	}
+}
+
+func readList(path string) ([]netip.Prefix, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var prefixes []netip.Prefix
+	scanner := bufio.NewScanner(file)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		prefix, err := parsePrefix(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		prefixes = append(prefixes, prefix)
+	}
+	return prefixes, scanner.Err()
+}
+
+func parsePrefix(s string) (netip.Prefix, error) {
+	if strings.Contains(s, "/") {
+		prefix, err := netip.ParsePrefix(s)
+		return prefix.Masked(), err
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
@@ -0,0 +1,154 @@
+package siem
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kanta/backend-challenge/internal/core/domain"
+)
+
+const (
+	FormatCEF  = "cef"
+	FormatJSON = "json"
+)
+
+const (
+	cefVendor  = "Kanta"
+	cefProduct = "backend-challenge"
+	cefVersion = "1.0"
+)
+
+var (
+	cefHeaderEscaper    = strings.NewReplacer(`\`, `\\`, `|`, `\|`, "\r", " ", "\n", " ")
+	cefExtensionEscaper = strings.NewReplacer(`\`, `\\`, `=`, `\=`, "\r", `\r`, "\n", `\n`)
+)
+
+// formatCEF renders event as an ArcSight Common Event Format message.
+func formatCEF(event domain.SecurityEvent) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "CEF:0|%s|%s|%s|%s|%s|%d|",
+		cefVendor, cefProduct, cefVersion,
+		cefHeaderEscaper.Replace(event.Name),
+		cefHeaderEscaper.Replace(strings.ReplaceAll(event.Name, "_", " ")),
+		event.Severity,
+	)
+
+	extension := []struct{ key, value string }{
+		{"rt", strconv.FormatInt(event.OccurredAt.UnixMilli(), 10)},
+		{"cat", event.Category},
+		{"outcome", event.Outcome},
+		{"externalId", event.ID},
+		{"suid", event.ActorID},
+		{"duid", event.SubjectID},
+		{"suser", event.Email},
+		{"src", event.IP},
+		{"requestClientApplication", event.UserAgent},
+		{"reason", event.Reason},
+	}
+	if len(event.Details) > 0 {
+		raw, _ := json.Marshal(event.Details)
+		extension = append(extension, struct{ key, value string }{"cs1Label", "details"}, struct{ key, value string }{"cs1", string(raw)})
+	}
+
+	first := true
+	for _, field := range extension {
+		if field.value == "" {
+			continue
+		}
+		if !first {
+			b.WriteByte(' ')
+		}
+		first = false
+		b.WriteString(field.key)
+		b.WriteByte('=')
+		b.WriteString(cefExtensionEscaper.Replace(field.value))
+	}
+	return b.String()
+}
+
+type jsonEvent struct {
+	ID         string         `json:"id,omitempty"`
+	Category   string         `json:"category"`
+	Name       string         `json:"name"`
+	Severity   int            `json:"severity"`
+	OccurredAt time.Time      `json:"occurred_at"`
+	Outcome    string         `json:"outcome,omitempty"`
+	ActorID    string         `json:"actor_id,omitempty"`
+	SubjectID  string         `json:"subject_id,omitempty"`
+	Email      string         `json:"email,omitempty"`
+	IP         string         `json:"ip,omitempty"`
+	UserAgent  string         `json:"user_agent,omitempty"`
+	Reason     string         `json:"reason,omitempty"`
+	Details    map[string]any `json:"details,omitempty"`
+}
+
+func formatJSON(event domain.SecurityEvent) (string, error) {
+	raw, err := json.Marshal(jsonEvent(event))
+	return string(raw), err
+}
+
+// syslogSeverity maps the CEF severity scale onto syslog severities.
+func syslogSeverity(severity int) int {
+	switch {
+	case severity >= 9:
+		return 2 // critical
+	case severity >= 7:
+		return 3 // error
+	case severity >= 4:
+		return 4 // warning
+	default:
+		return 6 // informational
+	}
+}
+
+// syslogMessage renders an RFC 5424 message without structured data.
+func syslogMessage(facility int, hostname, appName string, pid int, event domain.SecurityEvent, msg string) string {
+	return fmt.Sprintf("<%d>1 %s %s %s %d %s - %s",
+		facility*8+syslogSeverity(event.Severity),
+		event.OccurredAt.UTC().Format("2006-01-02T15:04:05.000000Z07:00"),
+		headerField(hostname, 255),
+		headerField(appName, 48),
+		pid,
+		headerField(event.Name, 32),
+		msg,
+	)
+}
+
+// headerField makes s a valid RFC 5424 header field: printable ASCII without
+// spaces, at most max characters, "-" when empty.
+func headerField(s string, max int) string {
+	s = strings.Map(func(r rune) rune {
+		if r <= ' ' || r > '~' {
+			return '_'
+		}
+		return r
+	}, s)
+	if len(s) > max {
+		s = s[:max]
+	}
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// ParseCategories reads category entries of the form "name" or
+// "name:format" into the Formats map of Config, using defaultFormat for
+// entries without one.
+func ParseCategories(entries []string, defaultFormat string) map[string]string {
+	formats := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		name, format, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		if name == "" {
+			continue
+		}
+		if !ok {
+			format = defaultFormat
+		}
+		formats[name] = strings.ToLower(format)
+	}
+	return formats
+}
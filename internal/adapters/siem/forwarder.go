@@ -0,0 +1,260 @@
+package siem
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/kanta/backend-challenge/internal/core/domain"
+	"github.com/kanta/backend-challenge/internal/core/ports"
+	"go.uber.org/zap"
+)
+
+const (
+	minBackoff   = 500 * time.Millisecond
+	writeTimeout = 5 * time.Second
+	// drainTimeout bounds how long Run keeps sending queued messages after
+	// its context is done.
+	drainTimeout = 2 * time.Second
+)
+
+type Config struct {
+	// Network is "udp", "tcp" or "tls".
+	Network  string
+	Address  string
+	TLS      *tls.Config
+	AppName  string
+	Facility int
+	// Formats maps each forwarded category to "cef" or "json"; categories
+	// not listed are dropped.
+	Formats    map[string]string
+	BufferSize int
+	MaxBackoff time.Duration
+}
+
+// Forwarder sends security events to a syslog collector (RFC 5424). Events
+// are formatted when forwarded and queued in a bounded buffer; Run delivers
+// them and reconnects with exponential backoff while the collector is down,
+// retrying the message that failed to send. Over UDP only local send errors
+// are seen, so a datagram the collector never receives is lost. When the
+// buffer is full new events are dropped and counted.
+type Forwarder struct {
+	cfg      Config
+	hostname string
+	pid      int
+	queue    chan string
+	dropped  atomic.Uint64
+}
+
+func NewForwarder(cfg Config) (*Forwarder, error) {
+	switch cfg.Network {
+	case "udp", "tcp", "tls":
+	default:
+		return nil, fmt.Errorf("unsupported syslog network %q", cfg.Network)
+	}
+	for category, format := range cfg.Formats {
+		if format != FormatCEF && format != FormatJSON {
+			return nil, fmt.Errorf("unsupported format %q for category %s", format, category)
+		}
+	}
+	if cfg.MaxBackoff < minBackoff {
+		cfg.MaxBackoff = minBackoff
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = ""
+	}
+	return &Forwarder{
+		cfg:      cfg,
+		hostname: hostname,
+		pid:      os.Getpid(),
+		queue:    make(chan string, max(cfg.BufferSize, 1)),
+	}, nil
+}
+
+var _ ports.SecurityEventForwarder = (*Forwarder)(nil)
+
+func (f *Forwarder) Forward(event domain.SecurityEvent) {
+	format, ok := f.cfg.Formats[event.Category]
+	if !ok {
+		return
+	}
+
+	var msg string
+	if format == FormatJSON {
+		var err error
+		if msg, err = formatJSON(event); err != nil {
+			zap.L().Warn("failed to format security event", zap.String("name", event.Name), zap.Error(err))
+			return
+		}
+	} else {
+		msg = formatCEF(event)
+	}
+
+	select {
+	case f.queue <- syslogMessage(f.cfg.Facility, f.hostname, f.cfg.AppName, f.pid, event, msg):
+	default:
+		if n := f.dropped.Add(1); n == 1 || n%1000 == 0 {
+			zap.L().Warn("syslog buffer full, dropping security events", zap.Uint64("dropped", n))
+		}
+	}
+}
+
+// Publish forwards domain events that map to a security event, so the
+// forwarder can be used as an EventProducer.
+func (f *Forwarder) Publish(ctx context.Context, event domain.Event) error {
+	if securityEvent, ok := domain.SecurityEventFromEvent(event); ok {
+		f.Forward(securityEvent)
+	}
+	return nil
+}
+
+// Run delivers queued messages until ctx is done, then makes a short attempt
+// to send what is left.
+func (f *Forwarder) Run(ctx context.Context) {
+	var (
+		conn    net.Conn
+		pending string
+		backoff = minBackoff
+	)
+	defer func() {
+		if conn != nil {
+			conn.Close()
+		}
+	}()
+
+	for {
+		if pending == "" {
+			select {
+			case <-ctx.Done():
+				f.drain(conn, "")
+				return
+			case pending = <-f.queue:
+			}
+		}
+
+		if conn == nil {
+			var err error
+			if conn, err = f.dial(ctx); err != nil {
+				zap.L().Warn("failed to connect to syslog collector", zap.String("address", f.cfg.Address), zap.Error(err))
+				if !sleep(ctx, backoff) {
+					f.drain(nil, pending)
+					return
+				}
+				backoff = min(backoff*2, f.cfg.MaxBackoff)
+				continue
+			}
+		}
+
+		if err := f.write(conn, pending); err != nil {
+			zap.L().Warn("failed to send to syslog collector", zap.String("address", f.cfg.Address), zap.Error(err))
+			conn.Close()
+			conn = nil
+			continue
+		}
+		pending = ""
+		backoff = minBackoff
+	}
+}
+
+// drain sends pending, unless empty, and the queued messages for at most
+// drainTimeout. Without a connection it dials once more first. Whatever
+// cannot be sent is dropped and counted.
+func (f *Forwarder) drain(conn net.Conn, pending string) {
+	ctx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+	defer cancel()
+
+	if conn == nil {
+		var err error
+		if conn, err = f.dial(ctx); err != nil {
+			f.dropQueued(pending, err)
+			return
+		}
+		defer conn.Close()
+	}
+	if pending != "" {
+		if err := f.write(conn, pending); err != nil {
+			f.dropQueued(pending, err)
+			return
+		}
+	}
+
+	for ctx.Err() == nil {
+		select {
+		case msg := <-f.queue:
+			if err := f.write(conn, msg); err != nil {
+				f.dropQueued(msg, err)
+				return
+			}
+		default:
+			return
+		}
+	}
+	f.dropQueued("", ctx.Err())
+}
+
+// dropQueued discards msg, unless empty, and everything still queued, and
+// logs how many messages were lost.
+func (f *Forwarder) dropQueued(msg string, reason error) {
+	var n uint64
+	if msg != "" {
+		n++
+	}
+discard:
+	for {
+		select {
+		case <-f.queue:
+			n++
+		default:
+			break discard
+		}
+	}
+	if n == 0 {
+		return
+	}
+	f.dropped.Add(n)
+	zap.L().Warn("dropping undelivered security events on shutdown",
+		zap.String("address", f.cfg.Address),
+		zap.Uint64("dropped", n),
+		zap.Error(reason),
+	)
+}
+
+func (f *Forwarder) dial(ctx context.Context) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: writeTimeout}
+	if f.cfg.Network == "tls" {
+		tlsDialer := &tls.Dialer{NetDialer: dialer, Config: f.cfg.TLS}
+		return tlsDialer.DialContext(ctx, "tcp", f.cfg.Address)
+	}
+	return dialer.DialContext(ctx, f.cfg.Network, f.cfg.Address)
+}
+
+// write sends one message per datagram over UDP and uses octet-counting
+// framing (RFC 6587, RFC 5425) over TCP and TLS.
+func (f *Forwarder) write(conn net.Conn, msg string) error {
+	if err := conn.SetWriteDeadline(time.Now().Add(writeTimeout)); err != nil {
+		return err
+	}
+	if f.cfg.Network != "udp" {
+		msg = strconv.Itoa(len(msg)) + " " + msg
+	}
+	_, err := conn.Write([]byte(msg))
+	return err
+}
+
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
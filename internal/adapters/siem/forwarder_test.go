@@ -0,0 +1,245 @@
+package siem
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kanta/backend-challenge/internal/core/domain"
+)
+
+func newTestForwarder(t *testing.T, network, address string) *Forwarder {
+	t.Helper()
+	f, err := NewForwarder(Config{
+		Network:    network,
+		Address:    address,
+		AppName:    "backend-api",
+		Facility:   10,
+		Formats:    map[string]string{domain.CategoryAuthentication: FormatJSON},
+		BufferSize: 16,
+		MaxBackoff: time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func testEvent(name string) domain.SecurityEvent {
+	return domain.SecurityEvent{
+		ID:         name,
+		Category:   domain.CategoryAuthentication,
+		Name:       name,
+		Severity:   5,
+		OccurredAt: time.Now(),
+		Outcome:    domain.AuditFailure,
+	}
+}
+
+// startForwarder runs f until the returned stop function is called, which
+// waits for Run, including its drain, to return.
+func startForwarder(f *Forwarder) (stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		f.Run(ctx)
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// unusedAddress returns a local TCP address nothing listens on yet.
+func unusedAddress(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+	return addr
+}
+
+func listenTCP(t *testing.T, addr string) net.Listener {
+	t.Helper()
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	return l
+}
+
+// acceptFrames accepts one connection on l and reads n octet-counted
+// messages from it.
+func acceptFrames(t *testing.T, l net.Listener, n int) []string {
+	t.Helper()
+	conn, err := l.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if err := conn.SetReadDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		t.Fatal(err)
+	}
+
+	r := bufio.NewReader(conn)
+	frames := make([]string, 0, n)
+	for len(frames) < n {
+		prefix, err := r.ReadString(' ')
+		if err != nil {
+			t.Fatalf("read frame length: %v", err)
+		}
+		length, err := strconv.Atoi(strings.TrimSuffix(prefix, " "))
+		if err != nil {
+			t.Fatalf("invalid frame length %q", prefix)
+		}
+		msg := make([]byte, length)
+		if _, err := io.ReadFull(r, msg); err != nil {
+			t.Fatalf("read frame: %v", err)
+		}
+		frames = append(frames, string(msg))
+	}
+	return frames
+}
+
+func assertDelivered(t *testing.T, got []string, names ...string) {
+	t.Helper()
+	if len(got) != len(names) {
+		t.Fatalf("received %d messages, want %d", len(got), len(names))
+	}
+	for i, name := range names {
+		if !strings.HasPrefix(got[i], "<84>1 ") {
+			t.Errorf("message %d has no RFC 5424 header: %q", i, got[i])
+		}
+		if !strings.Contains(got[i], `"name":"`+name+`"`) {
+			t.Errorf("message %d = %q, want event %s", i, got[i], name)
+		}
+	}
+}
+
+func TestForwarderDelivers(t *testing.T) {
+	tests := []struct {
+		network string
+		// collect starts a collector and returns its address and a function
+		// reading n messages from it.
+		collect func(t *testing.T) (string, func(n int) []string)
+	}{
+		{
+			network: "udp",
+			collect: func(t *testing.T) (string, func(n int) []string) {
+				pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+				if err != nil {
+					t.Fatal(err)
+				}
+				t.Cleanup(func() { pc.Close() })
+				return pc.LocalAddr().String(), func(n int) []string {
+					if err := pc.SetReadDeadline(time.Now().Add(5 * time.Second)); err != nil {
+						t.Fatal(err)
+					}
+					var msgs []string
+					buf := make([]byte, 64*1024)
+					for len(msgs) < n {
+						size, _, err := pc.ReadFrom(buf)
+						if err != nil {
+							t.Fatalf("read datagram: %v", err)
+						}
+						msgs = append(msgs, string(buf[:size]))
+					}
+					return msgs
+				}
+			},
+		},
+		{
+			network: "tcp",
+			collect: func(t *testing.T) (string, func(n int) []string) {
+				l := listenTCP(t, "127.0.0.1:0")
+				return l.Addr().String(), func(n int) []string {
+					return acceptFrames(t, l, n)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.network, func(t *testing.T) {
+			addr, read := tt.collect(t)
+			f := newTestForwarder(t, tt.network, addr)
+			stop := startForwarder(f)
+			defer stop()
+
+			f.Forward(testEvent("login_failed"))
+			f.Forward(testEvent("account_locked"))
+			// Categories without a format are not forwarded.
+			f.Forward(domain.SecurityEvent{Category: "unlisted", Name: "ignored"})
+
+			assertDelivered(t, read(2), "login_failed", "account_locked")
+		})
+	}
+}
+
+func TestForwarderRetriesAfterReconnect(t *testing.T) {
+	addr := unusedAddress(t)
+	f := newTestForwarder(t, "tcp", addr)
+	f.Forward(testEvent("login_failed"))
+	stop := startForwarder(f)
+	defer stop()
+
+	// The first dial fails; the message must survive the backoff and go
+	// out once the collector is up.
+	time.Sleep(100 * time.Millisecond)
+	l := listenTCP(t, addr)
+	f.Forward(testEvent("account_locked"))
+
+	assertDelivered(t, acceptFrames(t, l, 2), "login_failed", "account_locked")
+	if n := f.dropped.Load(); n != 0 {
+		t.Fatalf("dropped = %d, want 0", n)
+	}
+}
+
+func TestForwarderShutdown(t *testing.T) {
+	tests := []struct {
+		name string
+		// collectorUp starts the collector while Run waits to reconnect.
+		collectorUp bool
+		wantDropped uint64
+	}{
+		{name: "collector back up", collectorUp: true, wantDropped: 0},
+		{name: "collector down", collectorUp: false, wantDropped: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr := unusedAddress(t)
+			f := newTestForwarder(t, "tcp", addr)
+			for _, name := range []string{"login_failed", "account_locked", "login_succeeded"} {
+				f.Forward(testEvent(name))
+			}
+
+			stop := startForwarder(f)
+			time.Sleep(100 * time.Millisecond)
+			var l net.Listener
+			if tt.collectorUp {
+				l = listenTCP(t, addr)
+			}
+			// Stop before the backoff ends, so only the final dial of the
+			// drain can deliver.
+			stop()
+
+			if n := f.dropped.Load(); n != tt.wantDropped {
+				t.Fatalf("dropped = %d, want %d", n, tt.wantDropped)
+			}
+			if l != nil {
+				assertDelivered(t, acceptFrames(t, l, 3), "login_failed", "account_locked", "login_succeeded")
+			}
+		})
+	}
+}
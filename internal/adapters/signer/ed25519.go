@@ -0,0 +1,87 @@
+package signer
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/kanta/backend-challenge/internal/core/ports"
+)
+
+var ErrNoPrivateKey = errors.New("signer has no private key")
+
+type ed25519Signer struct {
+	private ed25519.PrivateKey
+	public  ed25519.PublicKey
+	keyID   string
+}
+
+// NewEd25519Signer loads a PKCS #8 PEM Ed25519 private key, as written by
+// `openssl genpkey -algorithm ed25519`.
+func NewEd25519Signer(privateKeyFile string) (ports.CheckpointSigner, error) {
+	key, err := readPEM(privateKeyFile, x509.ParsePKCS8PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	private, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s is not an Ed25519 private key", privateKeyFile)
+	}
+	return newSigner(private, private.Public().(ed25519.PublicKey)), nil
+}
+
+// NewEd25519Verifier loads a PKIX PEM Ed25519 public key, as written by
+// `openssl pkey -pubout`. The result verifies signatures but cannot sign.
+func NewEd25519Verifier(publicKeyFile string) (ports.CheckpointSigner, error) {
+	key, err := readPEM(publicKeyFile, x509.ParsePKIXPublicKey)
+	if err != nil {
+		return nil, err
+	}
+	public, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%s is not an Ed25519 public key", publicKeyFile)
+	}
+	return newSigner(nil, public), nil
+}
+
+func newSigner(private ed25519.PrivateKey, public ed25519.PublicKey) *ed25519Signer {
+	sum := sha256.Sum256(public)
+	return &ed25519Signer{
+		private: private,
+		public:  public,
+		keyID:   hex.EncodeToString(sum[:8]),
+	}
+}
+
+// KeyID identifies the public key so auditors can tell which key to use.
+func (s *ed25519Signer) KeyID() string {
+	return s.keyID
+}
+
+func (s *ed25519Signer) Sign(payload []byte) ([]byte, error) {
+	if s.private == nil {
+		return nil, ErrNoPrivateKey
+	}
+	return ed25519.Sign(s.private, payload), nil
+}
+
+func (s *ed25519Signer) Verify(payload, signature []byte) bool {
+	return ed25519.Verify(s.public, payload, signature)
+}
+
+func readPEM(path string, parse func([]byte) (any, error)) (any, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, fmt.Errorf("%s contains no PEM block", path)
+	}
+	return parse(block.Bytes)
+}
@@ -0,0 +1,114 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+
+	"github.com/kanta/backend-challenge/internal/core/ports"
+)
+
+// maxResponseBody is how much of a response is read before the connection
+// is reused; receivers only need to answer with a status code.
+const maxResponseBody = 64 << 10
+
+// errBlockedAddress is returned when a subscription URL resolves to an
+// address inside the network.
+var errBlockedAddress = errors.New("webhook address is not publicly routable")
+
+// nonPublicPrefixes are special-purpose ranges that netip does not classify
+// as private, loopback or link-local.
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+}
+
+type httpSender struct {
+	client *http.Client
+}
+
+// NewHTTPSender posts webhooks with the given per-request timeout. Redirects
+// are not followed, so a signed payload only reaches the subscribed URL.
+// Connections to loopback, private, link-local and other non-public
+// addresses are refused unless they are in allowed. The check runs on the
+// resolved address at connect time, so a public name that resolves inside
+// the network is refused too.
+func NewHTTPSender(timeout time.Duration, allowed []netip.Prefix) ports.WebhookSender {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: dialControl(allowed),
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	// Through a proxy only the proxy's address would be checked.
+	transport.Proxy = nil
+
+	return &httpSender{
+		client: &http.Client{
+			Timeout:   timeout,
+			Transport: transport,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+func (s *httpSender) Send(ctx context.Context, url string, header http.Header, body []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header = header.Clone()
+	req.Header.Set("User-Agent", "backend-challenge-webhooks/1.0")
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(res.Body, maxResponseBody))
+
+	return res.StatusCode, nil
+}
+
+// dialControl refuses connections to non-public addresses outside allowed.
+func dialControl(allowed []netip.Prefix) func(network, address string, c syscall.RawConn) error {
+	return func(network, address string, _ syscall.RawConn) error {
+		addrPort, err := netip.ParseAddrPort(address)
+		if err != nil {
+			return err
+		}
+		addr := addrPort.Addr().Unmap()
+		for _, prefix := range allowed {
+			if prefix.Contains(addr) {
+				return nil
+			}
+		}
+		if !isPublic(addr) {
+			return fmt.Errorf("%w: %s", errBlockedAddress, addr)
+		}
+		return nil
+	}
+}
+
+func isPublic(addr netip.Addr) bool {
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return false
+	}
+	for _, prefix := range nonPublicPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
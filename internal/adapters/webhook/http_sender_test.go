@@ -0,0 +1,189 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// loopback lets the tests reach httptest receivers past the address check.
+var loopback = []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8")}
+
+func TestHTTPSenderSend(t *testing.T) {
+	tests := []struct {
+		name       string
+		handler    http.HandlerFunc
+		wantStatus int
+		wantErr    bool
+	}{
+		{
+			name:       "accepted",
+			handler:    func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) },
+			wantStatus: http.StatusNoContent,
+		},
+		{
+			name:       "server error is returned, not retried",
+			handler:    func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusInternalServerError) },
+			wantStatus: http.StatusInternalServerError,
+		},
+		{
+			name: "slow receiver times out",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				select {
+				case <-r.Context().Done():
+				case <-time.After(time.Second):
+				}
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests.Add(1)
+				tt.handler(w, r)
+			}))
+			defer server.Close()
+
+			status, err := NewHTTPSender(100*time.Millisecond, loopback).Send(context.Background(), server.URL, http.Header{}, []byte(`{}`))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Send() error = %v, want error %v", err, tt.wantErr)
+			}
+			if status != tt.wantStatus {
+				t.Fatalf("Send() status = %d, want %d", status, tt.wantStatus)
+			}
+			if n := requests.Load(); n != 1 {
+				t.Fatalf("receiver got %d requests, want 1", n)
+			}
+		})
+	}
+}
+
+func TestHTTPSenderSendsPayloadAndHeaders(t *testing.T) {
+	var (
+		gotBody   string
+		gotHeader http.Header
+		gotMethod string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, _ := io.ReadAll(r.Body)
+		gotBody, gotHeader, gotMethod = string(raw), r.Header, r.Method
+	}))
+	defer server.Close()
+
+	header := http.Header{}
+	header.Set("Webhook-Signature", "t=1,v1=abc")
+	if _, err := NewHTTPSender(time.Second, loopback).Send(context.Background(), server.URL, header, []byte(`{"id":"1"}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	if gotMethod != http.MethodPost {
+		t.Errorf("method = %s, want POST", gotMethod)
+	}
+	if gotBody != `{"id":"1"}` {
+		t.Errorf("body = %s", gotBody)
+	}
+	if got := gotHeader.Get("Webhook-Signature"); got != "t=1,v1=abc" {
+		t.Errorf("Webhook-Signature = %q", got)
+	}
+	if got := gotHeader.Get("User-Agent"); got != "backend-challenge-webhooks/1.0" {
+		t.Errorf("User-Agent = %q", got)
+	}
+}
+
+func TestHTTPSenderDoesNotFollowRedirects(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+	}{
+		{"found", http.StatusFound},
+		{"temporary redirect", http.StatusTemporaryRedirect},
+		{"permanent redirect", http.StatusPermanentRedirect},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var followed atomic.Bool
+			target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				followed.Store(true)
+			}))
+			defer target.Close()
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				http.Redirect(w, r, target.URL, tt.status)
+			}))
+			defer server.Close()
+
+			status, err := NewHTTPSender(time.Second, loopback).Send(context.Background(), server.URL, http.Header{}, []byte(`{}`))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if status != tt.status {
+				t.Fatalf("Send() status = %d, want %d", status, tt.status)
+			}
+			if followed.Load() {
+				t.Fatal("the signed payload was sent to the redirect target")
+			}
+		})
+	}
+}
+
+func TestHTTPSenderRefusesNonPublicAddresses(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+	}))
+	defer server.Close()
+
+	_, err := NewHTTPSender(time.Second, nil).Send(context.Background(), server.URL, http.Header{}, []byte(`{}`))
+	if !errors.Is(err, errBlockedAddress) {
+		t.Fatalf("Send() error = %v, want %v", err, errBlockedAddress)
+	}
+	if n := requests.Load(); n != 0 {
+		t.Fatalf("receiver got %d requests, want 0", n)
+	}
+}
+
+func TestDialControl(t *testing.T) {
+	control := dialControl([]netip.Prefix{netip.MustParsePrefix("10.1.0.0/16")})
+	tests := []struct {
+		address string
+		allowed bool
+	}{
+		{"93.184.216.34:443", true},
+		{"[2606:2800:220:1::1]:443", true},
+		{"10.1.2.3:443", true},
+		{"127.0.0.1:443", false},
+		{"[::1]:443", false},
+		{"[::ffff:127.0.0.1]:443", false},
+		{"10.2.0.1:443", false},
+		{"172.16.0.1:443", false},
+		{"192.168.1.1:443", false},
+		{"169.254.169.254:80", false},
+		{"[fe80::1]:443", false},
+		{"[fd00::1]:443", false},
+		{"0.0.0.0:443", false},
+		{"100.64.0.1:443", false},
+		{"224.0.0.1:443", false},
+		{"[64:ff9b::7f00:1]:443", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			err := control("tcp", tt.address, nil)
+			if tt.allowed && err != nil {
+				t.Fatalf("control() = %v, want nil", err)
+			}
+			if !tt.allowed && !errors.Is(err, errBlockedAddress) {
+				t.Fatalf("control() = %v, want %v", err, errBlockedAddress)
+			}
+		})
+	}
+}
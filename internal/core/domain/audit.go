@@ -0,0 +1,74 @@
+package domain
+
+import "time"
+
+// Audited actions.
+const (
+	AuditRegister             = "register"
+	AuditLogin                = "login"
+	AuditTokenRefresh         = "token_refresh"
+	AuditServiceToken         = "service_token"
+	AuditLogout               = "logout"
+	AuditLogoutEverywhere     = "logout_everywhere"
+	AuditReauthenticate       = "reauthenticate"
+	AuditPasswordChange       = "password_change"
+	AuditPasswordResetRequest = "password_reset_request"
+	AuditPasswordReset        = "password_reset"
+	AuditAccountUnlock        = "account_unlock"
+	AuditSessionsRevoke       = "sessions_revoke"
+	AuditDataExport           = "data_export"
+)
+
+const (
+	AuditSuccess = "success"
+	AuditFailure = "failure"
+)
+
+// AuditEntry is one record of the append-only authentication audit log.
+type AuditEntry struct {
+	ID         string    `json:"id"`
+	OccurredAt time.Time `json:"occurred_at"`
+	Action     string    `json:"action"`
+	Outcome    string    `json:"outcome"`
+	// ActorID is who performed the action: a user, an admin or a service.
+	ActorID string `json:"actor_id,omitempty"`
+	// SubjectID is the user the action was performed on when that is not
+	// the actor, e.g. an admin unlocking an account.
+	SubjectID string `json:"subject_id,omitempty"`
+	// Email is the address given when no user is known yet, e.g. a failed
+	// login.
+	Email     string         `json:"email,omitempty"`
+	IP        string         `json:"ip,omitempty"`
+	UserAgent string         `json:"user_agent,omitempty"`
+	Reason    string         `json:"reason,omitempty"`
+	Details   map[string]any `json:"details,omitempty"`
+
+	// Partition, Sequence, PrevHash and Hash link the entry into its
+	// partition's hash chain; see AuditChainHash.
+	Partition string `json:"partition,omitempty"`
+	Sequence  int64  `json:"sequence,omitempty"`
+	PrevHash  string `json:"prev_hash,omitempty"`
+	Hash      string `json:"hash,omitempty"`
+}
+
+// AuditFilter selects audit entries. Zero fields match everything.
+type AuditFilter struct {
+	ActorID   string
+	SubjectID string
+	Email     string
+	Action    string
+	Outcome   string
+	IP        string
+	From      time.Time
+	To        time.Time
+	Page      int
+	PageSize  int
+}
+
+// AuditPage is one page of audit entries, newest first.
+type AuditPage struct {
+	Entries  []*AuditEntry
+	Total    int64
+	Page     int
+	PageSize int
+}
@@ -0,0 +1,141 @@
+package domain
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// AuditPartition returns the hash chain an entry recorded at t belongs to.
+// Every calendar month (UTC) is chained separately.
+func AuditPartition(t time.Time) string {
+	return t.UTC().Format("2006-01")
+}
+
+// AuditChainHead is the last entry appended to a partition.
+type AuditChainHead struct {
+	Partition string
+	Sequence  int64
+	Hash      string
+}
+
+// AuditCheckpoint is a signed statement of a partition's head at CreatedAt.
+// Entries up to Sequence cannot be removed or altered without breaking either
+// the chain or the signature.
+type AuditCheckpoint struct {
+	ID        string    `json:"id"`
+	Partition string    `json:"partition"`
+	Sequence  int64     `json:"sequence"`
+	Hash      string    `json:"hash"`
+	CreatedAt time.Time `json:"created_at"`
+	KeyID     string    `json:"key_id"`
+	Signature []byte    `json:"signature"`
+}
+
+// SigningPayload is the message the checkpoint signature covers.
+func (c *AuditCheckpoint) SigningPayload() []byte {
+	return fmt.Appendf(nil, "audit-checkpoint\n%s\n%d\n%s\n%s",
+		c.Partition, c.Sequence, c.Hash, c.CreatedAt.UTC().Format(time.RFC3339Nano))
+}
+
+// AuditPurge is a signed statement that retention deleted a partition's
+// entries up to Sequence, whose hash was Hash. The chain continues from it as
+// if those entries were still there.
+type AuditPurge struct {
+	ID        string    `json:"id"`
+	Partition string    `json:"partition"`
+	Sequence  int64     `json:"sequence"`
+	Hash      string    `json:"hash"`
+	PurgedAt  time.Time `json:"purged_at"`
+	KeyID     string    `json:"key_id"`
+	Signature []byte    `json:"signature"`
+}
+
+// SigningPayload is the message the purge signature covers.
+func (p *AuditPurge) SigningPayload() []byte {
+	return fmt.Appendf(nil, "audit-purge\n%s\n%d\n%s\n%s",
+		p.Partition, p.Sequence, p.Hash, p.PurgedAt.UTC().Format(time.RFC3339Nano))
+}
+
+// AuditChainBreak is the first place a partition's chain fails to verify.
+type AuditChainBreak struct {
+	Sequence int64
+	EntryID  string
+	Reason   string
+}
+
+// AuditVerification is the result of walking one partition's chain.
+type AuditVerification struct {
+	Partition   string
+	Entries     int64
+	Checkpoints int
+	// Purge is the latest purge of the partition; Entries counts only the
+	// entries after it.
+	Purge *AuditPurge
+	// Break is nil when the chain and its checkpoints are intact.
+	Break *AuditChainBreak
+}
+
+type auditHashInput struct {
+	Partition  string          `json:"partition"`
+	Sequence   int64           `json:"sequence"`
+	PrevHash   string          `json:"prev_hash"`
+	ID         string          `json:"id"`
+	OccurredAt string          `json:"occurred_at"`
+	Action     string          `json:"action"`
+	Outcome    string          `json:"outcome"`
+	ActorID    string          `json:"actor_id"`
+	SubjectID  string          `json:"subject_id"`
+	Email      string          `json:"email"`
+	IP         string          `json:"ip"`
+	UserAgent  string          `json:"user_agent"`
+	Reason     string          `json:"reason"`
+	Details    json.RawMessage `json:"details"`
+}
+
+// AuditChainHash returns the hex SHA-256 of e's content and chain position,
+// including PrevHash, so changing any earlier entry changes every later hash.
+// OccurredAt must already be truncated to the storage precision.
+func AuditChainHash(e *AuditEntry) (string, error) {
+	details, err := canonicalJSON(e.Details)
+	if err != nil {
+		return "", err
+	}
+	raw, err := json.Marshal(auditHashInput{
+		Partition:  e.Partition,
+		Sequence:   e.Sequence,
+		PrevHash:   e.PrevHash,
+		ID:         e.ID,
+		OccurredAt: e.OccurredAt.UTC().Format(time.RFC3339Nano),
+		Action:     e.Action,
+		Outcome:    e.Outcome,
+		ActorID:    e.ActorID,
+		SubjectID:  e.SubjectID,
+		Email:      e.Email,
+		IP:         e.IP,
+		UserAgent:  e.UserAgent,
+		Reason:     e.Reason,
+		Details:    details,
+	})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// canonicalJSON encodes v as it will read back from storage: numbers become
+// float64 and object keys are sorted.
+func canonicalJSON(v any) (json.RawMessage, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var decoded any
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		return nil, err
+	}
+	return json.Marshal(decoded)
+}
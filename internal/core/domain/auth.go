@@ -0,0 +1,92 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	// ErrLoginLocked is returned while an account or client IP is locked out.
+	// It is returned for unknown emails too so that it does not reveal
+	// whether an account exists.
+	ErrLoginLocked = errors.New("too many failed login attempts, try again later")
+
+	ErrInvalidResetToken = errors.New("invalid or expired reset token")
+	ErrReauthRequired    = errors.New("recent authentication required")
+
+	ErrInvalidDPoPProof = errors.New("invalid DPoP proof")
+	// ErrUseDPoPNonce asks the client to retry with the nonce from the
+	// DPoP-Nonce response header.
+	ErrUseDPoPNonce = errors.New("use_dpop_nonce")
+)
+
+// Authentication method references (RFC 8176).
+const (
+	AMRPassword = "pwd"
+	AMROTP      = "otp"
+	AMRMFA      = "mfa"
+)
+
+// Authentication context class references.
+const (
+	ACRSingleFactor = "aal1"
+	ACRMultiFactor  = "aal2"
+)
+
+// TokenSubject is the user and authentication context embedded in issued
+// tokens.
+type TokenSubject struct {
+	UserID   string
+	Role     string
+	ClientID string
+	AuthTime time.Time
+	AMR      []string
+	ACR      string
+	// TokenVersion is the user's token version when the tokens were issued.
+	TokenVersion int
+	// Confirmation holds the proof-of-possession key the tokens are bound
+	// to, if any.
+	Confirmation Confirmation
+}
+
+// NewTokenSubject records that user has just authenticated with methods.
+func NewTokenSubject(user *User, methods ...string) TokenSubject {
+	acr := ACRSingleFactor
+	if len(methods) > 1 {
+		acr = ACRMultiFactor
+	}
+	return TokenSubject{
+		UserID:       user.ID,
+		Role:         user.Role,
+		AuthTime:     time.Now(),
+		AMR:          methods,
+		ACR:          acr,
+		TokenVersion: user.TokenVersion,
+	}
+}
+
+type ReauthenticateRequest struct {
+	Password string `json:"password"`
+}
+
+// ClientInfo describes the client that issued a request.
+type ClientInfo struct {
+	IP        string `json:"ip"`
+	UserAgent string `json:"user_agent"`
+	// DeviceID is an optional stable identifier supplied by the client app.
+	DeviceID string `json:"device_id,omitempty"`
+	// ChallengePassed is set when the request came with a valid challenge
+	// solution.
+	ChallengePassed bool `json:"-"`
+}
+
+// LockoutPolicy controls brute-force protection on login.
+type LockoutPolicy struct {
+	MaxAccountFailures int
+	MaxIPFailures      int
+	FailureWindow      time.Duration
+	Duration           time.Duration
+	DelayBase          time.Duration
+	DelayMax           time.Duration
+}
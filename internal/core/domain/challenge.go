@@ -0,0 +1,67 @@
+package domain
+
+import (
+	"errors"
+	"net/netip"
+	"time"
+)
+
+var (
+	ErrChallengeRequired = errors.New("challenge required")
+	// ErrChallengeFailed covers wrong, expired and reused solutions.
+	ErrChallengeFailed = errors.New("challenge failed")
+)
+
+// Challenge types.
+const (
+	ChallengeProofOfWork = "pow"
+	ChallengeCaptcha     = "captcha"
+)
+
+// Challenge tells a client what to solve before its request is accepted.
+type Challenge struct {
+	Type string `json:"type"`
+
+	// For proof of work: find a nonce such that SHA-256 of
+	// "<token>:<nonce>" starts with Difficulty zero bits, and send
+	// "<token>:<nonce>".
+	Algorithm  string     `json:"algorithm,omitempty"`
+	Token      string     `json:"token,omitempty"`
+	Difficulty int        `json:"difficulty,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+
+	// For CAPTCHA: render the provider's widget with SiteKey and send the
+	// response token it produces.
+	Provider string `json:"provider,omitempty"`
+	SiteKey  string `json:"site_key,omitempty"`
+}
+
+// ChallengePolicy decides when auth requests must solve a challenge.
+type ChallengePolicy struct {
+	// IPFailureThreshold requires a challenge from IPs with at least this
+	// many recent login failures. Zero disables the check.
+	IPFailureThreshold int
+	// NewRange requires a challenge from IP ranges without a successful
+	// login within RangeMemory.
+	NewRange    bool
+	RangeMemory time.Duration
+}
+
+// IPRange returns the network ip belongs to for risk checks: its /24 for
+// IPv4 and /48 for IPv6. It returns "" for unparsable addresses.
+func IPRange(ip string) string {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ""
+	}
+	addr = addr.Unmap()
+	bits := 48
+	if addr.Is4() {
+		bits = 24
+	}
+	prefix, err := addr.Prefix(bits)
+	if err != nil {
+		return ""
+	}
+	return prefix.String()
+}
@@ -0,0 +1,14 @@
+package domain
+
+import "time"
+
+type Device struct {
+	ID          string    `json:"id"`
+	UserID      string    `json:"-"`
+	Fingerprint string    `json:"-"`
+	DeviceID    string    `json:"device_id,omitempty"`
+	UserAgent   string    `json:"user_agent"`
+	LastIP      string    `json:"last_ip"`
+	FirstSeenAt time.Time `json:"first_seen_at"`
+	LastSeenAt  time.Time `json:"last_seen_at"`
+}
@@ -0,0 +1,207 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	EventUserRegistered = "user.registered"
+	EventUserLoggedIn   = "user.logged_in"
+
+	EventAccountLocked = "auth.account_locked"
+	EventIPLocked      = "auth.ip_locked"
+
+	EventPasswordChanged        = "auth.password_changed"
+	EventPasswordResetRequested = "auth.password_reset_requested"
+
+	EventNewDeviceLogin  = "auth.new_device_login"
+	EventSuspiciousLogin = "auth.suspicious_login"
+
+	EventSessionRevoked  = "auth.session_revoked"
+	EventSessionsRevoked = "auth.sessions_revoked"
+)
+
+// PublishedEventTypes are the events sent to the configured event producer,
+// each described by a schema in docs/events. New events stay internal until
+// they are added here.
+var PublishedEventTypes = []string{
+	EventUserRegistered,
+	EventUserLoggedIn,
+	EventAccountLocked,
+	EventIPLocked,
+	EventPasswordChanged,
+	EventPasswordResetRequested,
+	EventNewDeviceLogin,
+	EventSuspiciousLogin,
+	EventSessionRevoked,
+	EventSessionsRevoked,
+}
+
+// Event is the envelope every domain event is published in. Its layout and
+// that of each payload are described by the JSON schemas in docs/events.
+type Event struct {
+	// ID is unique per event so consumers can drop duplicates.
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	// Version is the schema version of Data. Fields are only ever added to
+	// a version; anything else gets a new one.
+	Version int `json:"version"`
+	// AggregateID names the user, account or IP the event is about. Events
+	// with the same AggregateID are published in order.
+	AggregateID string         `json:"aggregate_id"`
+	OccurredAt  time.Time      `json:"occurred_at"`
+	Data        map[string]any `json:"data"`
+}
+
+// EventPayload is the typed data of one event type and schema version.
+type EventPayload interface {
+	EventType() string
+	SchemaVersion() int
+	AggregateID() string
+}
+
+// NewEvent wraps payload in a new envelope. Data holds payload as it encodes
+// to JSON, so consumers see the same fields in every adapter.
+func NewEvent(payload EventPayload) Event {
+	var data map[string]any
+	raw, err := json.Marshal(payload)
+	if err == nil {
+		err = json.Unmarshal(raw, &data)
+	}
+	if err != nil {
+		// Payloads are plain structs; this cannot happen.
+		panic("encode event payload: " + err.Error())
+	}
+
+	return Event{
+		ID:          uuid.New().String(),
+		Type:        payload.EventType(),
+		Version:     payload.SchemaVersion(),
+		AggregateID: payload.AggregateID(),
+		OccurredAt:  time.Now().UTC(),
+		Data:        data,
+	}
+}
+
+type UserRegistered struct {
+	UserID string `json:"user_id"`
+	Name   string `json:"name"`
+	Email  string `json:"email"`
+}
+
+func (UserRegistered) EventType() string     { return EventUserRegistered }
+func (UserRegistered) SchemaVersion() int    { return 1 }
+func (p UserRegistered) AggregateID() string { return p.UserID }
+
+type UserLoggedIn struct {
+	UserID    string `json:"user_id"`
+	Email     string `json:"email"`
+	IP        string `json:"ip"`
+	UserAgent string `json:"user_agent"`
+	DeviceID  string `json:"device_id,omitempty"`
+}
+
+func (UserLoggedIn) EventType() string     { return EventUserLoggedIn }
+func (UserLoggedIn) SchemaVersion() int    { return 1 }
+func (p UserLoggedIn) AggregateID() string { return p.UserID }
+
+type AccountLocked struct {
+	Email       string    `json:"email"`
+	IP          string    `json:"ip"`
+	Failures    int64     `json:"failures"`
+	LockedUntil time.Time `json:"locked_until"`
+}
+
+func (AccountLocked) EventType() string     { return EventAccountLocked }
+func (AccountLocked) SchemaVersion() int    { return 1 }
+func (p AccountLocked) AggregateID() string { return p.Email }
+
+type IPLocked struct {
+	IP          string    `json:"ip"`
+	Failures    int64     `json:"failures"`
+	LockedUntil time.Time `json:"locked_until"`
+}
+
+func (IPLocked) EventType() string     { return EventIPLocked }
+func (IPLocked) SchemaVersion() int    { return 1 }
+func (p IPLocked) AggregateID() string { return p.IP }
+
+type PasswordChanged struct {
+	UserID string `json:"user_id"`
+	// Reason is "change" or "reset".
+	Reason string `json:"reason"`
+}
+
+func (PasswordChanged) EventType() string     { return EventPasswordChanged }
+func (PasswordChanged) SchemaVersion() int    { return 1 }
+func (p PasswordChanged) AggregateID() string { return p.UserID }
+
+// PasswordResetRequested reports that a reset token was mailed to the user.
+// The token itself is never published; version 1 carried it.
+type PasswordResetRequested struct {
+	UserID    string    `json:"user_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func (PasswordResetRequested) EventType() string     { return EventPasswordResetRequested }
+func (PasswordResetRequested) SchemaVersion() int    { return 2 }
+func (p PasswordResetRequested) AggregateID() string { return p.UserID }
+
+type NewDeviceLogin struct {
+	UserID    string `json:"user_id"`
+	Email     string `json:"email"`
+	DeviceID  string `json:"device_id"`
+	UserAgent string `json:"user_agent"`
+	IP        string `json:"ip"`
+}
+
+func (NewDeviceLogin) EventType() string     { return EventNewDeviceLogin }
+func (NewDeviceLogin) SchemaVersion() int    { return 1 }
+func (p NewDeviceLogin) AggregateID() string { return p.UserID }
+
+// SuspiciousLogin reports a login with valid credentials whose risk
+// assessment reached a decision other than allow. It is published once per
+// login, after any challenge was solved.
+type SuspiciousLogin struct {
+	UserID   string   `json:"user_id"`
+	Email    string   `json:"email"`
+	IP       string   `json:"ip"`
+	Country  string   `json:"country,omitempty"`
+	ASN      uint     `json:"asn,omitempty"`
+	Score    int      `json:"score"`
+	Decision string   `json:"decision"`
+	Signals  []string `json:"signals"`
+	// ChallengePassed is set when the client solved a challenge, or is
+	// trusted, so a challenge decision was met.
+	ChallengePassed bool `json:"challenge_passed,omitempty"`
+}
+
+func (SuspiciousLogin) EventType() string     { return EventSuspiciousLogin }
+func (SuspiciousLogin) SchemaVersion() int    { return 1 }
+func (p SuspiciousLogin) AggregateID() string { return p.UserID }
+
+// SessionRevoked reports the end of a single session, e.g. on logout.
+type SessionRevoked struct {
+	UserID string `json:"user_id"`
+	// TokenID is the jti of the revoked access token, if known.
+	TokenID string `json:"token_id,omitempty"`
+	Reason  string `json:"reason"`
+}
+
+func (SessionRevoked) EventType() string     { return EventSessionRevoked }
+func (SessionRevoked) SchemaVersion() int    { return 1 }
+func (p SessionRevoked) AggregateID() string { return p.UserID }
+
+// SessionsRevoked reports that every session of the user was invalidated.
+type SessionsRevoked struct {
+	UserID       string `json:"user_id"`
+	Reason       string `json:"reason"`
+	TokenVersion int    `json:"token_version"`
+}
+
+func (SessionsRevoked) EventType() string     { return EventSessionsRevoked }
+func (SessionsRevoked) SchemaVersion() int    { return 1 }
+func (p SessionsRevoked) AggregateID() string { return p.UserID }
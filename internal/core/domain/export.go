@@ -0,0 +1,76 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrExportNotFound    = errors.New("data export not found")
+	ErrExportRateLimited = errors.New("a data export was already requested recently")
+	ErrExportExpired     = errors.New("data export has expired")
+	ErrInvalidExportLink = errors.New("download link is invalid or has expired")
+)
+
+// Data export statuses. An export is pending until the archive has been
+// built, and expired once the archive has been deleted again.
+const (
+	ExportPending = "pending"
+	ExportReady   = "ready"
+	ExportFailed  = "failed"
+	ExportExpired = "expired"
+)
+
+// DataExport is a user's request for a copy of their data.
+type DataExport struct {
+	ID          string     `json:"id"`
+	UserID      string     `json:"-"`
+	Status      string     `json:"status"`
+	Error       string     `json:"error,omitempty"`
+	Size        int64      `json:"size,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	// ExpiresAt is when the archive is deleted.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// DownloadURL is a signed link to the archive, valid until
+	// DownloadExpiresAt. It is only set on ready exports.
+	DownloadURL       string     `json:"download_url,omitempty"`
+	DownloadExpiresAt *time.Time `json:"download_expires_at,omitempty"`
+}
+
+type ExportPolicy struct {
+	// Interval is the least time between two exports of a user. Failed
+	// exports do not count.
+	Interval time.Duration
+	// Retention is how long a built archive is kept.
+	Retention time.Duration
+	// URLTTL is how long a download link is valid.
+	URLTTL time.Duration
+	// BaseURL prefixes download links, e.g. "https://api.example.com".
+	BaseURL      string
+	PollInterval time.Duration
+}
+
+// UserDataArchive is everything stored about a user, as handed out by a data
+// export.
+type UserDataArchive struct {
+	Version      int             `json:"version"`
+	GeneratedAt  time.Time       `json:"generated_at"`
+	Profile      ExportedProfile `json:"profile"`
+	Sessions     []*Session      `json:"sessions"`
+	Devices      []*Device       `json:"devices"`
+	LoginHistory []*LoginRecord  `json:"login_history"`
+	AuditEntries []*AuditEntry   `json:"audit_entries"`
+	// LinkedIdentities lists external identity providers linked to the
+	// account. There are none yet, so it is always empty.
+	LinkedIdentities []any `json:"linked_identities"`
+}
+
+// ExportedProfile is the user record without the password hash.
+type ExportedProfile struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
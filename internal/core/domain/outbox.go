@@ -0,0 +1,38 @@
+package domain
+
+import "time"
+
+// OutboxMessage is an event waiting in the outbox to be published. It is
+// written in the same transaction as the change it reports, so the event is
+// published if and only if the change was committed.
+type OutboxMessage struct {
+	// ID orders messages; the relay publishes them in ID order per aggregate.
+	ID            int64
+	Event         Event
+	Attempts      int
+	NextAttemptAt time.Time
+	LastError     string
+	CreatedAt     time.Time
+	// PublishedTo names the sinks that have accepted the message, so a retry
+	// only goes to the ones that have not.
+	PublishedTo []string
+	// PublishedAt is set once every sink has accepted the message.
+	PublishedAt *time.Time
+}
+
+// OutboxPolicy controls the outbox relay.
+type OutboxPolicy struct {
+	PollInterval time.Duration
+	BatchSize    int
+	// Lease is how long a claimed batch belongs to a relay. Messages the
+	// relay has not finished by then are claimed again, e.g. after a crash.
+	Lease time.Duration
+	// Failed publishes are retried after BackoffBase, doubling up to
+	// BackoffMax. Messages are retried until they are published.
+	BackoffBase time.Duration
+	BackoffMax  time.Duration
+	// Published messages are deleted once older than Retention, checked
+	// every CleanupInterval.
+	Retention       time.Duration
+	CleanupInterval time.Duration
+}
@@ -0,0 +1,52 @@
+package domain
+
+import (
+	"strings"
+	"time"
+)
+
+// PasswordPolicy describes the rules a new password must satisfy.
+type PasswordPolicy struct {
+	MinLength      int
+	MaxBytes       int
+	RequireUpper   bool
+	RequireLower   bool
+	RequireDigit   bool
+	RequireSymbol  bool
+	RejectPersonal bool
+	CheckBreached  bool
+	ResetTokenTTL  time.Duration
+}
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// ValidationError collects every rule an input failed.
+type ValidationError struct {
+	Errors []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Errors))
+	for _, fe := range e.Errors {
+		messages = append(messages, fe.Message)
+	}
+	return strings.Join(messages, "; ")
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
@@ -0,0 +1,13 @@
+package domain
+
+import "time"
+
+type RateLimitResult struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// ResetAfter is the time until the current window ends.
+	ResetAfter time.Duration
+	// RetryAfter is set when the request was rejected.
+	RetryAfter time.Duration
+}
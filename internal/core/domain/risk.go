@@ -0,0 +1,110 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrLoginBlocked rejects a login with the right password whose risk is too
+// high. Clients get the response of a wrong password, but it does not count
+// towards the lockout.
+var ErrLoginBlocked = errors.New("login blocked")
+
+// Risk decisions, from least to most severe.
+const (
+	RiskAllow     = "allow"
+	RiskAlert     = "alert"
+	RiskChallenge = "challenge"
+	RiskBlock     = "block"
+)
+
+// Risk signals.
+const (
+	RiskSignalNewCountry       = "new_country"
+	RiskSignalNewASN           = "new_asn"
+	RiskSignalImpossibleTravel = "impossible_travel"
+	// RiskSignalReputation is followed by ":<list name>".
+	RiskSignalReputation = "reputation"
+)
+
+// GeoLocation is what the GeoIP database knows about an IP. Fields are zero
+// when unknown.
+type GeoLocation struct {
+	Country        string  `json:"country,omitempty"`
+	ASN            uint    `json:"asn,omitempty"`
+	ASOrganization string  `json:"as_organization,omitempty"`
+	HasCoordinates bool    `json:"-"`
+	Latitude       float64 `json:"latitude,omitempty"`
+	Longitude      float64 `json:"longitude,omitempty"`
+}
+
+// ReputationHit is an IP reputation list that contains an IP.
+type ReputationHit struct {
+	List  string `json:"list"`
+	Score int    `json:"score"`
+}
+
+// RiskAssessment is the outcome of scoring one login.
+type RiskAssessment struct {
+	Score    int         `json:"score"`
+	Decision string      `json:"decision"`
+	Signals  []string    `json:"signals,omitempty"`
+	Location GeoLocation `json:"location"`
+	// ChallengePassed is set when the client solved a challenge with the
+	// login, which satisfies a challenge decision.
+	ChallengePassed bool `json:"challenge_passed,omitempty"`
+}
+
+// AddSignal records signal and adds its score.
+func (a *RiskAssessment) AddSignal(score int, signal string) {
+	a.Score += score
+	a.Signals = append(a.Signals, signal)
+}
+
+// LoginRecord is one successful login in a user's login history.
+type LoginRecord struct {
+	ID         string      `json:"id"`
+	UserID     string      `json:"user_id"`
+	IP         string      `json:"ip"`
+	Location   GeoLocation `json:"location"`
+	Score      int         `json:"score"`
+	Decision   string      `json:"decision"`
+	OccurredAt time.Time   `json:"occurred_at"`
+}
+
+// RiskPolicy weighs the signals of a login and maps the score to a
+// decision. A zero threshold disables its decision.
+type RiskPolicy struct {
+	NewCountryScore       int
+	NewASNScore           int
+	ImpossibleTravelScore int
+	// ImpossibleTravelKmh is the highest plausible speed between two
+	// logins. Distances under ImpossibleTravelMinKm are ignored, since
+	// GeoIP coordinates are approximate.
+	ImpossibleTravelKmh   float64
+	ImpossibleTravelMinKm float64
+	// HistorySize is how many recent logins count as the user's usual
+	// places.
+	HistorySize int
+
+	AlertScore     int
+	ChallengeScore int
+	BlockScore     int
+}
+
+// Decide returns the most severe decision whose threshold score reaches.
+func (p RiskPolicy) Decide(score int) string {
+	for _, threshold := range []struct {
+		score    int
+		decision string
+	}{
+		{p.BlockScore, RiskBlock},
+		{p.ChallengeScore, RiskChallenge},
+		{p.AlertScore, RiskAlert},
+	} {
+		if threshold.score > 0 && score >= threshold.score {
+			return threshold.decision
+		}
+	}
+	return RiskAllow
+}
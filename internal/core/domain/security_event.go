@@ -0,0 +1,115 @@
+package domain
+
+import "time"
+
+// Security event categories, used to select what is forwarded to a SIEM.
+const (
+	CategoryAuthentication = "authentication"
+	CategoryAccount        = "account"
+	CategoryAdmin          = "admin"
+	CategorySecurity       = "security"
+)
+
+var auditCategories = map[string]string{
+	AuditRegister:             CategoryAccount,
+	AuditLogin:                CategoryAuthentication,
+	AuditTokenRefresh:         CategoryAuthentication,
+	AuditServiceToken:         CategoryAuthentication,
+	AuditLogout:               CategoryAuthentication,
+	AuditLogoutEverywhere:     CategoryAuthentication,
+	AuditReauthenticate:       CategoryAuthentication,
+	AuditPasswordChange:       CategoryAccount,
+	AuditPasswordResetRequest: CategoryAccount,
+	AuditPasswordReset:        CategoryAccount,
+	AuditAccountUnlock:        CategoryAdmin,
+	AuditSessionsRevoke:       CategoryAdmin,
+}
+
+// eventCategories lists the domain events forwarded as security events.
+// Events that only repeat an audit entry, such as a password change, are
+// left out.
+var eventCategories = map[string]string{
+	EventAccountLocked:   CategorySecurity,
+	EventIPLocked:        CategorySecurity,
+	EventNewDeviceLogin:  CategorySecurity,
+	EventSuspiciousLogin: CategorySecurity,
+}
+
+// SecurityEvent is an audit entry or domain event in the shape forwarded to a
+// SIEM. Severity uses the CEF scale from 0 (lowest) to 10.
+type SecurityEvent struct {
+	ID         string
+	Category   string
+	Name       string
+	Severity   int
+	OccurredAt time.Time
+	Outcome    string
+	ActorID    string
+	SubjectID  string
+	Email      string
+	IP         string
+	UserAgent  string
+	Reason     string
+	Details    map[string]any
+}
+
+func SecurityEventFromAudit(e *AuditEntry) SecurityEvent {
+	category, ok := auditCategories[e.Action]
+	if !ok {
+		category = CategorySecurity
+	}
+	severity := 3
+	if e.Outcome == AuditFailure {
+		severity = 5
+	}
+	if e.Reason == ErrLoginLocked.Error() {
+		severity = 7
+	}
+
+	return SecurityEvent{
+		ID:         e.ID,
+		Category:   category,
+		Name:       "audit." + e.Action,
+		Severity:   severity,
+		OccurredAt: e.OccurredAt,
+		Outcome:    e.Outcome,
+		ActorID:    e.ActorID,
+		SubjectID:  e.SubjectID,
+		Email:      e.Email,
+		IP:         e.IP,
+		UserAgent:  e.UserAgent,
+		Reason:     e.Reason,
+		Details:    e.Details,
+	}
+}
+
+// SecurityEventFromEvent reports false for events that are not forwarded.
+func SecurityEventFromEvent(e Event) (SecurityEvent, bool) {
+	category, ok := eventCategories[e.Type]
+	if !ok {
+		return SecurityEvent{}, false
+	}
+	severity := 5
+	switch e.Type {
+	case EventAccountLocked, EventIPLocked:
+		severity = 8
+	case EventSuspiciousLogin:
+		severity = 7
+	}
+
+	str := func(key string) string {
+		s, _ := e.Data[key].(string)
+		return s
+	}
+	return SecurityEvent{
+		Category:   category,
+		Name:       e.Type,
+		Severity:   severity,
+		OccurredAt: e.OccurredAt,
+		ActorID:    str("user_id"),
+		Email:      str("email"),
+		IP:         str("ip"),
+		UserAgent:  str("user_agent"),
+		Details:    e.Data,
+	}, true
+}
@@ -0,0 +1,28 @@
+package domain
+
+import "errors"
+
+var (
+	ErrClientCertificateRequired = errors.New("client certificate required")
+	ErrUnknownServiceIdentity    = errors.New("unknown service identity")
+	ErrCertificateMismatch       = errors.New("client certificate does not match token")
+	ErrUnsupportedGrantType      = errors.New("unsupported_grant_type")
+)
+
+// ServiceIdentity maps a client certificate to an internal service. Every
+// non-empty matcher must match the certificate.
+type ServiceIdentity struct {
+	Name string `json:"name"`
+	// Subject is the certificate's full distinguished name, e.g.
+	// "CN=billing,O=Example".
+	Subject    string `json:"subject,omitempty"`
+	CommonName string `json:"common_name,omitempty"`
+	DNSName    string `json:"dns_name,omitempty"`
+	// ClientID selects the token policy, which must allow the
+	// client_credentials grant.
+	ClientID string `json:"client_id"`
+}
+
+type TokenRequest struct {
+	GrantType string `json:"grant_type" form:"grant_type"`
+}
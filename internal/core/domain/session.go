@@ -0,0 +1,31 @@
+package domain
+
+import "time"
+
+// SessionPolicy bounds how long a login stays valid. A session ends when it
+// has not been refreshed for IdleTimeout or once AbsoluteTimeout has passed
+// since login, whichever comes first.
+type SessionPolicy struct {
+	AccessTokenTTL  time.Duration
+	IdleTimeout     time.Duration
+	AbsoluteTimeout time.Duration
+}
+
+type Session struct {
+	UserID     string    `json:"user_id"`
+	ClientID   string    `json:"client_id,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	// Token IDs of the session's current tokens, revoked on logout in
+	// denylist mode.
+	AccessTokenID   string    `json:"access_token_id,omitempty"`
+	AccessExpiresAt time.Time `json:"access_expires_at"`
+	RefreshTokenID  string    `json:"refresh_token_id,omitempty"`
+}
+
+// IdleTTL is how long the session may stay unused from now on, capped by its
+// absolute expiry.
+func (s *Session) IdleTTL(now time.Time, policy SessionPolicy) time.Duration {
+	return min(policy.IdleTimeout, s.ExpiresAt.Sub(now))
+}
@@ -0,0 +1,82 @@
+package domain
+
+import (
+	"errors"
+	"slices"
+	"time"
+)
+
+const (
+	ClientTypeWeb     = "web"
+	ClientTypeMobile  = "mobile"
+	ClientTypeCLI     = "cli"
+	ClientTypeService = "service"
+)
+
+const (
+	GrantPassword          = "password"
+	GrantRefreshToken      = "refresh_token"
+	GrantClientCredentials = "client_credentials"
+)
+
+// Refresh behaviours.
+const (
+	// RefreshSliding extends the idle timeout on every refresh.
+	RefreshSliding = "sliding"
+	// RefreshFixed keeps the refresh token valid until the absolute timeout
+	// whether or not it is used.
+	RefreshFixed = "fixed"
+	// RefreshDisabled issues no refresh token at all.
+	RefreshDisabled = "disabled"
+)
+
+var (
+	ErrUnknownClient     = errors.New("unknown client")
+	ErrGrantNotAllowed   = errors.New("grant type not allowed for client")
+	ErrRefreshNotAllowed = errors.New("refresh is disabled for client")
+)
+
+// TokenPolicy is the token configuration for one client.
+type TokenPolicy struct {
+	ClientID   string
+	ClientType string
+	Audience   []string
+	GrantTypes []string
+	Refresh    string
+	SessionPolicy
+}
+
+func (p *TokenPolicy) AllowsGrant(grant string) bool {
+	return slices.Contains(p.GrantTypes, grant)
+}
+
+// TokenPolicies holds the policy of every known client.
+type TokenPolicies struct {
+	DefaultClient string
+	Clients       map[string]*TokenPolicy
+	// Audience identifies this API. Tokens without it in "aud" are
+	// rejected; empty disables the check.
+	Audience string
+}
+
+// Get returns the policy for clientID, or the default client's policy when
+// clientID is empty.
+func (p *TokenPolicies) Get(clientID string) (*TokenPolicy, error) {
+	if clientID == "" {
+		clientID = p.DefaultClient
+	}
+	policy, ok := p.Clients[clientID]
+	if !ok {
+		return nil, ErrUnknownClient
+	}
+	return policy, nil
+}
+
+// SessionTTL is how long session may remain unused from now on under this
+// policy.
+func (p *TokenPolicy) SessionTTL(session *Session, now time.Time) time.Duration {
+	if p.Refresh == RefreshFixed {
+		return session.ExpiresAt.Sub(now)
+	}
+	return session.IdleTTL(now, p.SessionPolicy)
+}
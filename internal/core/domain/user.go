@@ -6,17 +6,29 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+	// RoleService is carried by tokens issued to internal services.
+	RoleService = "service"
+)
+
 type User struct {
-	ID        string    `json:"id"`
-	Name      string    `json:"name"`
-	Email     string    `json:"email"`
-	Password  string    `json:"password"`
-	CreatedAt time.Time `json:"created_at"`
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Email    string `json:"email"`
+	Password string `json:"password"`
+	Role     string `json:"role,omitempty"`
+	// TokenVersion is bumped to invalidate every token issued to the user.
+	TokenVersion int       `json:"-"`
+	CreatedAt    time.Time `json:"created_at"`
 }
 
 type Login struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
+	// ClientID selects the token policy. The default client is used when empty.
+	ClientID string `json:"client_id,omitempty"`
 }
 
 type TokenPair struct {
@@ -27,9 +39,52 @@ type TokenPair struct {
 type Claims struct {
 	UserID string `json:"user_id"`
 	Type   string `json:"type"`
+	Role   string `json:"role,omitempty"`
+	// ClientID is the client the token was issued to, which determines its
+	// token policy on refresh.
+	ClientID string `json:"client_id,omitempty"`
+	// AuthTime, AMR and ACR follow OpenID Connect: when the user last
+	// authenticated, with which methods, and the resulting assurance level.
+	AuthTime *jwt.NumericDate `json:"auth_time,omitempty"`
+	AMR      []string         `json:"amr,omitempty"`
+	ACR      string           `json:"acr,omitempty"`
+	// TokenVersion must not be older than the user's current version.
+	TokenVersion int `json:"token_version,omitempty"`
+	// Confirmation binds the token to a key or certificate the client must
+	// prove it holds.
+	Confirmation *Confirmation `json:"cnf,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// Confirmation is the "cnf" claim of RFC 7800.
+type Confirmation struct {
+	// JKT is the JWK SHA-256 thumbprint of a DPoP key (RFC 9449).
+	JKT string `json:"jkt,omitempty"`
+	// X5TS256 is the SHA-256 thumbprint of a TLS client certificate
+	// (RFC 8705).
+	X5TS256 string `json:"x5t#S256,omitempty"`
+}
+
+// TokenSubject returns the subject the claims were issued for, so that
+// refreshed tokens carry the same authentication context.
+func (c *Claims) TokenSubject() TokenSubject {
+	subject := TokenSubject{
+		UserID:       c.UserID,
+		Role:         c.Role,
+		ClientID:     c.ClientID,
+		AMR:          c.AMR,
+		ACR:          c.ACR,
+		TokenVersion: c.TokenVersion,
+	}
+	if c.Confirmation != nil {
+		subject.Confirmation = *c.Confirmation
+	}
+	if c.AuthTime != nil {
+		subject.AuthTime = c.AuthTime.Time
+	}
+	return subject
+}
+
 type RefreshTokenRequest struct {
 	RefreshToken string `json:"refresh_token"`
 }
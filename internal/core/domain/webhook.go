@@ -0,0 +1,128 @@
+package domain
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrWebhookNotFound         = errors.New("webhook subscription not found")
+	ErrWebhookDeliveryNotFound = errors.New("webhook delivery not found")
+	ErrInvalidWebhookURL       = errors.New("webhook url must be an absolute https url")
+	ErrUnknownWebhookEvent     = errors.New("unknown webhook event type")
+	ErrWebhookNotDead          = errors.New("only dead-lettered deliveries can be redelivered")
+)
+
+// WebhookEventTypes are the account lifecycle events subscriptions can
+// receive. Authentication and security events are never sent outside.
+var WebhookEventTypes = []string{
+	EventUserRegistered,
+}
+
+// Webhook delivery statuses. A delivery is pending until it succeeds or runs
+// out of attempts, when it is dead-lettered.
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryDead      = "dead"
+)
+
+// Headers sent with every webhook request.
+const (
+	WebhookIDHeader        = "Webhook-Id"
+	WebhookEventHeader     = "Webhook-Event"
+	WebhookSignatureHeader = "Webhook-Signature"
+)
+
+type WebhookSubscription struct {
+	ID  string `json:"id"`
+	URL string `json:"url"`
+	// Secret is only returned when the subscription is created.
+	Secret     string    `json:"secret,omitempty"`
+	EventTypes []string  `json:"event_types"`
+	Active     bool      `json:"active"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// Subscribes reports whether s should receive events of eventType.
+func (s *WebhookSubscription) Subscribes(eventType string) bool {
+	if !s.Active {
+		return false
+	}
+	for _, t := range s.EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery is one event sent to one subscription, and its delivery
+// log: the attempts made so far and the result of the last one.
+type WebhookDelivery struct {
+	ID             string     `json:"id"`
+	SubscriptionID string     `json:"subscription_id"`
+	EventID        string     `json:"event_id"`
+	EventType      string     `json:"event_type"`
+	Payload        []byte     `json:"-"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	LastStatusCode int        `json:"last_status_code,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+}
+
+type WebhookSubscriptionRequest struct {
+	URL        string   `json:"url"`
+	EventTypes []string `json:"event_types"`
+	// Secret is generated when empty on create.
+	Secret string `json:"secret"`
+	Active *bool  `json:"active"`
+}
+
+type WebhookDeliveryFilter struct {
+	SubscriptionID string
+	Status         string
+	Page           int
+	PageSize       int
+}
+
+type WebhookDeliveryPage struct {
+	Deliveries []*WebhookDelivery
+	Total      int64
+	Page       int
+	PageSize   int
+}
+
+// WebhookSignature returns the Webhook-Signature header for body sent at
+// timestamp: "t=<unix seconds>,v1=<hex HMAC-SHA256 of "<t>.<body>">".
+// Receivers should recompute it and reject stale timestamps.
+func WebhookSignature(secret string, timestamp time.Time, body []byte) string {
+	t := strconv.FormatInt(timestamp.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(t))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "t=" + t + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// WebhookPolicy configures webhook delivery.
+type WebhookPolicy struct {
+	// AllowHTTP permits plain http URLs, e.g. for local receivers.
+	AllowHTTP   bool
+	MaxAttempts int
+	// Failed attempts are retried after BackoffBase, doubling up to
+	// BackoffMax.
+	BackoffBase    time.Duration
+	BackoffMax     time.Duration
+	PollInterval   time.Duration
+	RequestTimeout time.Duration
+	BatchSize      int
+}
@@ -0,0 +1,74 @@
+package ports
+
+import (
+	"context"
+	"time"
+
+	"github.com/kanta/backend-challenge/internal/core/domain"
+)
+
+// AuditRepository stores audit entries. It has no update or delete.
+type AuditRepository interface {
+	// Append links entries, in order, into the hash chains of their
+	// partitions and stores them in one transaction.
+	Append(entries ...*domain.AuditEntry) error
+	Find(filter domain.AuditFilter) ([]*domain.AuditEntry, int64, error)
+	// Each calls fn for every entry matching filter, oldest first, without
+	// loading them all at once.
+	Each(filter domain.AuditFilter, fn func(*domain.AuditEntry) error) error
+
+	// Partitions lists every partition with chained entries, checkpoints or
+	// a chain head.
+	Partitions() ([]string, error)
+	Heads() ([]*domain.AuditChainHead, error)
+	// Head returns nil when partition has no chained entries.
+	Head(partition string) (*domain.AuditChainHead, error)
+	// EachInChain calls fn for every entry of partition in sequence order.
+	EachInChain(partition string, fn func(*domain.AuditEntry) error) error
+	AppendCheckpoint(checkpoint *domain.AuditCheckpoint) error
+	// LastCheckpoint returns nil when partition has no checkpoint yet.
+	LastCheckpoint(partition string) (*domain.AuditCheckpoint, error)
+	Checkpoints(partition string) ([]*domain.AuditCheckpoint, error)
+	// LastPurge returns nil when partition was never purged.
+	LastPurge(partition string) (*domain.AuditPurge, error)
+	// Purge stores each purge and deletes the entries it covers, and deletes
+	// unchained entries older than before, in one transaction. A purge whose
+	// partition grew since it was signed is skipped. Chain heads and
+	// checkpoints are kept. It returns the number of entries deleted.
+	Purge(before time.Time, purges []*domain.AuditPurge) (int64, error)
+}
+
+// CheckpointSigner signs and verifies audit checkpoints.
+type CheckpointSigner interface {
+	KeyID() string
+	Sign(payload []byte) ([]byte, error)
+	Verify(payload, signature []byte) bool
+}
+
+type AuditService interface {
+	// Record appends entry to the audit log. Failures are logged, never
+	// returned, so auditing cannot break the audited request. While Run is
+	// running entries are queued and written in batches; when the queue is
+	// full they are written synchronously instead of being dropped.
+	Record(ctx context.Context, entry domain.AuditEntry)
+	// Run writes queued entries until ctx is done, then writes the rest
+	// before returning. Entries recorded afterwards are written
+	// synchronously.
+	Run(ctx context.Context)
+	// Query returns one page of matching entries, newest first.
+	Query(ctx context.Context, filter domain.AuditFilter) (*domain.AuditPage, error)
+	Export(ctx context.Context, filter domain.AuditFilter, fn func(*domain.AuditEntry) error) error
+
+	// Checkpoint signs the head of every partition that grew since its last
+	// checkpoint.
+	Checkpoint(ctx context.Context) error
+	// RunCheckpoints calls Checkpoint every interval until ctx is done.
+	RunCheckpoints(ctx context.Context, interval time.Duration)
+	// RunRetention deletes entries older than retention, a whole partition
+	// at a time, every interval until ctx is done, leaving a signed purge
+	// in their place. A retention of 0, or no signer, keeps entries forever.
+	RunRetention(ctx context.Context, retention, interval time.Duration)
+	// Verify walks the hash chain of partition and checks it against the
+	// signed checkpoints.
+	Verify(ctx context.Context, partition string) (*domain.AuditVerification, error)
+}
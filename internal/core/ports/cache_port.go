@@ -9,4 +9,7 @@ type CachePort interface {
 	SetToken(ctx context.Context, key string, value string, expiration time.Duration) error
 	GetToken(ctx context.Context, key string) (string, error)
 	DeleteToken(ctx context.Context, key string) error
+	// TakeToken gets and deletes key in one step, so that only one caller
+	// can ever read a single-use token.
+	TakeToken(ctx context.Context, key string) (string, error)
 }
@@ -0,0 +1,26 @@
+package ports
+
+import (
+	"context"
+
+	"github.com/kanta/backend-challenge/internal/core/domain"
+)
+
+// ChallengeVerifier issues and checks one kind of challenge, such as proof of
+// work or a third-party CAPTCHA.
+type ChallengeVerifier interface {
+	Issue(ctx context.Context, client domain.ClientInfo) (*domain.Challenge, error)
+	// Verify returns domain.ErrChallengeFailed when solution is wrong,
+	// expired or reused, and other errors when it could not be checked.
+	Verify(ctx context.Context, solution string, client domain.ClientInfo) error
+}
+
+type ChallengeService interface {
+	// Required reports whether requests from ip must solve a challenge.
+	Required(ctx context.Context, ip string) (bool, error)
+	Issue(ctx context.Context, client domain.ClientInfo) (*domain.Challenge, error)
+	Verify(ctx context.Context, solution string, client domain.ClientInfo) error
+	// Publish learns the ranges users log in from. It logs cache errors
+	// rather than returning them.
+	Publish(ctx context.Context, event domain.Event) error
+}
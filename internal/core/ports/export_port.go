@@ -0,0 +1,42 @@
+package ports
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/kanta/backend-challenge/internal/core/domain"
+)
+
+type ExportRepository interface {
+	// Create stores export unless the user has another export, not failed,
+	// created after since. Then it returns domain.ErrExportRateLimited.
+	Create(export *domain.DataExport, since time.Time) error
+	Find(id string) (*domain.DataExport, error)
+	// ClaimPending returns up to limit pending exports and hides them from
+	// other workers for lease.
+	ClaimPending(now time.Time, lease time.Duration, limit int) ([]*domain.DataExport, error)
+	Update(export *domain.DataExport) error
+	// FindExpired returns ready exports whose archive expired before now.
+	FindExpired(now time.Time, limit int) ([]*domain.DataExport, error)
+}
+
+// ExportStore keeps the archives of data exports.
+type ExportStore interface {
+	// Save writes the archive of id with fn and returns its size.
+	Save(id string, fn func(w io.Writer) error) (int64, error)
+	Open(id string) (io.ReadCloser, error)
+	Delete(id string) error
+}
+
+type ExportService interface {
+	// Request queues an export of the user's data.
+	Request(ctx context.Context, userID string) (*domain.DataExport, error)
+	// Get returns one of the user's exports, with a download link if ready.
+	Get(ctx context.Context, userID, id string) (*domain.DataExport, error)
+	// Open checks a download link and opens the archive it points to.
+	Open(ctx context.Context, id string, expires int64, signature string) (io.ReadCloser, error)
+	// Run builds pending exports and deletes expired archives until ctx is
+	// done.
+	Run(ctx context.Context)
+}
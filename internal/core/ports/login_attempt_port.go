@@ -0,0 +1,18 @@
+package ports
+
+import (
+	"context"
+	"time"
+)
+
+type LoginAttemptPort interface {
+	// RegisterFailure increments the failure counter for key and returns the
+	// new count. The counter expires window after the first failure.
+	RegisterFailure(ctx context.Context, key string, window time.Duration) (int64, error)
+	GetFailures(ctx context.Context, key string) (int64, error)
+	ResetFailures(ctx context.Context, key string) error
+	Lock(ctx context.Context, key string, duration time.Duration) error
+	// LockedFor returns the remaining lock duration, or zero if key is not locked.
+	LockedFor(ctx context.Context, key string) (time.Duration, error)
+	Unlock(ctx context.Context, key string) error
+}
@@ -0,0 +1,17 @@
+package ports
+
+import (
+	"context"
+	"time"
+
+	"github.com/kanta/backend-challenge/internal/core/domain"
+)
+
+// Mailer delivers account emails. Secrets such as reset tokens only ever go
+// through it, never through events or logs.
+type Mailer interface {
+	SendPasswordReset(ctx context.Context, to, token string, expiresAt time.Time) error
+	// SendNewDeviceLogin tells the user that device signed in to their
+	// account for the first time.
+	SendNewDeviceLogin(ctx context.Context, to string, device *domain.Device) error
+}
@@ -0,0 +1,50 @@
+package ports
+
+import (
+	"context"
+	"time"
+
+	"github.com/kanta/backend-challenge/internal/core/domain"
+)
+
+type OutboxRepository interface {
+	Append(events ...domain.Event) error
+	// ClaimDue returns up to limit due messages in ID order and moves their
+	// NextAttemptAt to now+lease, so no other relay claims them meanwhile.
+	// Claims are serialized by a lock; a relay that does not get it claims
+	// nothing. A message is not due while an earlier unpublished message of
+	// its aggregate is waiting for a retry or claimed.
+	ClaimDue(now time.Time, lease time.Duration, limit int) ([]*domain.OutboxMessage, error)
+	// Update stores the Attempts, NextAttemptAt, LastError, PublishedTo and
+	// PublishedAt of messages.
+	Update(messages ...*domain.OutboxMessage) error
+	// DeletePublished removes messages published before cutoff.
+	DeletePublished(cutoff time.Time) (int64, error)
+}
+
+// Repositories are the repositories bound to one transaction.
+type Repositories struct {
+	Users  UserRepository
+	Outbox OutboxRepository
+}
+
+// Transactor runs fn in a database transaction that is committed if fn
+// returns nil and rolled back otherwise.
+type Transactor interface {
+	WithinTransaction(fn func(repos Repositories) error) error
+}
+
+// OutboxSink is a producer the relay publishes to. Name identifies it in
+// OutboxMessage.PublishedTo, so it must not change between releases.
+type OutboxSink struct {
+	Name     string
+	Producer EventProducer
+}
+
+type OutboxRelay interface {
+	// Relay publishes one batch of due messages and returns how many were
+	// processed.
+	Relay(ctx context.Context) (int, error)
+	// Run relays messages and deletes old published ones until ctx is done.
+	Run(ctx context.Context)
+}
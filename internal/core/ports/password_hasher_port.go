@@ -0,0 +1,13 @@
+package ports
+
+type PasswordHasher interface {
+	// Hash returns an encoded hash that records the algorithm and its
+	// parameters alongside the salt and digest.
+	Hash(password string) (string, error)
+	Verify(encoded, password string) (bool, error)
+	// Supports reports whether encoded is in a format Verify understands.
+	Supports(encoded string) bool
+	// NeedsRehash reports whether encoded was produced by a different
+	// algorithm or with weaker parameters than the current configuration.
+	NeedsRehash(encoded string) bool
+}
@@ -1 +1,11 @@
 package ports
+
+import (
+	"context"
+
+	"github.com/kanta/backend-challenge/internal/core/domain"
+)
+
+type EventProducer interface {
+	Publish(ctx context.Context, event domain.Event) error
+}
@@ -0,0 +1,12 @@
+package ports
+
+import (
+	"context"
+	"time"
+
+	"github.com/kanta/backend-challenge/internal/core/domain"
+)
+
+type RateLimiterPort interface {
+	Allow(ctx context.Context, key string, limit int, window time.Duration) (*domain.RateLimitResult, error)
+}
@@ -0,0 +1,11 @@
+package ports
+
+import (
+	"context"
+	"time"
+)
+
+type ReplayCache interface {
+	// Remember records key for ttl. It returns false if key was already seen.
+	Remember(ctx context.Context, key string, ttl time.Duration) (bool, error)
+}
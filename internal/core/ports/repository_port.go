@@ -1,6 +1,8 @@
 package ports
 
 import (
+	"time"
+
 	"github.com/kanta/backend-challenge/internal/core/domain"
 )
 
@@ -8,4 +10,16 @@ type UserRepository interface {
 	Create(user *domain.User) error
 	FindOne(filter map[string]interface{}) (*domain.User, error)
 	FindByID(id string) (*domain.User, error)
+	UpdatePassword(id, password string) error
+	// IncrementTokenVersion bumps the user's token version and returns the
+	// new value.
+	IncrementTokenVersion(id string) (int, error)
+}
+
+type DeviceRepository interface {
+	Create(device *domain.Device) error
+	FindByFingerprint(userID, fingerprint string) (*domain.Device, error)
+	Touch(id, ip string, seenAt time.Time) error
+	ListByUser(userID string) ([]*domain.Device, error)
+	Delete(userID, id string) error
 }
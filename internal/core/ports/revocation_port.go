@@ -0,0 +1,12 @@
+package ports
+
+import "context"
+
+// RevocationBus fans token revocations out to every API instance.
+type RevocationBus interface {
+	PublishRevocation(ctx context.Context, userID string) error
+	// SubscribeRevocations calls onRevoke for every revoked user until ctx is
+	// done. onReset is called whenever revocations may have been missed,
+	// e.g. after reconnecting.
+	SubscribeRevocations(ctx context.Context, onRevoke func(userID string), onReset func()) error
+}
@@ -0,0 +1,33 @@
+package ports
+
+import (
+	"context"
+
+	"github.com/kanta/backend-challenge/internal/core/domain"
+)
+
+type GeoIPLookup interface {
+	// Lookup returns an empty location for IPs the database does not know.
+	Lookup(ip string) (domain.GeoLocation, error)
+}
+
+type IPReputation interface {
+	// Lookup returns every list that contains ip.
+	Lookup(ip string) []domain.ReputationHit
+}
+
+type LoginHistoryRepository interface {
+	Record(record *domain.LoginRecord) error
+	// Recent returns the user's last limit logins, newest first.
+	Recent(userID string, limit int) ([]*domain.LoginRecord, error)
+	ListByUser(userID string) ([]*domain.LoginRecord, error)
+}
+
+type RiskEngine interface {
+	// Assess scores a login by user from client. It does not fail; missing
+	// data only lowers the score. With a nil user only signals about the
+	// client count, so the result is the same whether the account exists.
+	Assess(ctx context.Context, user *domain.User, client domain.ClientInfo) *domain.RiskAssessment
+	// RecordLogin adds an accepted login to the user's history.
+	RecordLogin(ctx context.Context, user *domain.User, client domain.ClientInfo, assessment *domain.RiskAssessment)
+}
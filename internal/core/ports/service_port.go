@@ -1,12 +1,32 @@
 package ports
 
 import (
+	"context"
+
 	"github.com/kanta/backend-challenge/internal/core/domain"
 )
 
 type Service interface {
-	Register(name, email, password string) error
-	Authenticate(email, password string) (*domain.User, error)
+	Register(ctx context.Context, name, email, password string) error
+	// Authenticate checks the login risk and the credentials. A blocked
+	// login fails like a wrong password. On failure the
+	// user is still returned if the email belongs to an account, only so the
+	// attempt can be attributed in the audit log.
+	Authenticate(ctx context.Context, email, password string, client domain.ClientInfo) (*domain.User, *domain.RiskAssessment, error)
+	Reauthenticate(ctx context.Context, userID, password string, client domain.ClientInfo) (*domain.User, error)
 	CreateUser(user *domain.User) error
 	GetUserByID(id string) (*domain.User, error)
+	UnlockAccount(ctx context.Context, userID string) error
+	ChangePassword(ctx context.Context, userID, currentPassword, newPassword string) error
+	RequestPasswordReset(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, token, newPassword string) error
+	ListDevices(userID string) ([]*domain.Device, error)
+	ForgetDevice(userID, deviceID string) error
+	TokenVersion(ctx context.Context, userID string) (int, error)
+	RevokeAllSessions(ctx context.Context, userID, reason string) error
+	RecordLogout(ctx context.Context, userID, tokenID string)
+}
+
+type UserImportService interface {
+	ImportUser(user *domain.User) error
 }
@@ -0,0 +1,20 @@
+package ports
+
+import (
+	"context"
+	"time"
+
+	"github.com/kanta/backend-challenge/internal/core/domain"
+)
+
+// TokenVersionPort returns a user's current token version. Tokens carrying an
+// older version have been revoked.
+type TokenVersionPort interface {
+	TokenVersion(ctx context.Context, userID string) (int, error)
+}
+
+type SessionStore interface {
+	Save(ctx context.Context, session *domain.Session, ttl time.Duration) error
+	Get(ctx context.Context, userID string) (*domain.Session, error)
+	Delete(ctx context.Context, userID string) error
+}
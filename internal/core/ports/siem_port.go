@@ -0,0 +1,9 @@
+package ports
+
+import "github.com/kanta/backend-challenge/internal/core/domain"
+
+// SecurityEventForwarder ships security events to an external collector such
+// as a SIEM. Forward must not block the caller.
+type SecurityEventForwarder interface {
+	Forward(event domain.SecurityEvent)
+}
@@ -0,0 +1,51 @@
+package ports
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/kanta/backend-challenge/internal/core/domain"
+)
+
+type WebhookRepository interface {
+	CreateSubscription(subscription *domain.WebhookSubscription) error
+	UpdateSubscription(subscription *domain.WebhookSubscription) error
+	DeleteSubscription(id string) error
+	// FindSubscription includes the secret.
+	FindSubscription(id string) (*domain.WebhookSubscription, error)
+	ListSubscriptions() ([]*domain.WebhookSubscription, error)
+
+	// CreateDeliveries skips deliveries whose subscription already has one
+	// for the event.
+	CreateDeliveries(deliveries []*domain.WebhookDelivery) error
+	// ClaimDueDeliveries returns up to limit pending deliveries due at now
+	// and postpones them by lease, so that no other worker picks them up
+	// while they are being sent.
+	ClaimDueDeliveries(now time.Time, lease time.Duration, limit int) ([]*domain.WebhookDelivery, error)
+	UpdateDelivery(delivery *domain.WebhookDelivery) error
+	FindDelivery(subscriptionID, id string) (*domain.WebhookDelivery, error)
+	FindDeliveries(filter domain.WebhookDeliveryFilter) ([]*domain.WebhookDelivery, int64, error)
+}
+
+// WebhookSender posts a signed payload and returns the response status code.
+type WebhookSender interface {
+	Send(ctx context.Context, url string, header http.Header, body []byte) (int, error)
+}
+
+type WebhookService interface {
+	// Publish queues a delivery of event to every subscription of its type,
+	// so the service can be used as an EventProducer.
+	Publish(ctx context.Context, event domain.Event) error
+	// RunDispatcher sends due deliveries until ctx is done.
+	RunDispatcher(ctx context.Context)
+
+	CreateSubscription(ctx context.Context, req domain.WebhookSubscriptionRequest) (*domain.WebhookSubscription, error)
+	UpdateSubscription(ctx context.Context, id string, req domain.WebhookSubscriptionRequest) (*domain.WebhookSubscription, error)
+	DeleteSubscription(ctx context.Context, id string) error
+	GetSubscription(ctx context.Context, id string) (*domain.WebhookSubscription, error)
+	ListSubscriptions(ctx context.Context) ([]*domain.WebhookSubscription, error)
+	ListDeliveries(ctx context.Context, filter domain.WebhookDeliveryFilter) (*domain.WebhookDeliveryPage, error)
+	// Redeliver puts a dead-lettered delivery back in the queue.
+	Redeliver(ctx context.Context, subscriptionID, deliveryID string) (*domain.WebhookDelivery, error)
+}
@@ -0,0 +1,422 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/kanta/backend-challenge/internal/core/domain"
+	"github.com/kanta/backend-challenge/internal/core/ports"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultAuditPageSize = 50
+	maxAuditPageSize     = 500
+	// auditBatchSize is the most queued entries written in one transaction.
+	auditBatchSize = 100
+)
+
+// errStopWalk ends a chain walk early once a break has been found.
+var errStopWalk = errors.New("stop walking the audit chain")
+
+type auditService struct {
+	repo ports.AuditRepository
+	// signer signs checkpoints; nil disables them.
+	signer ports.CheckpointSigner
+	// forwarder receives every recorded entry; it may be nil.
+	forwarder ports.SecurityEventForwarder
+	// queue holds entries for Run; nil writes every entry synchronously.
+	queue chan domain.AuditEntry
+	// mu makes Record see stopped before Run's final drain, so no entry is
+	// left in the queue.
+	mu      sync.RWMutex
+	stopped bool
+}
+
+// NewAuditService queues up to queueSize entries for Run; 0 writes entries
+// synchronously.
+func NewAuditService(repo ports.AuditRepository, signer ports.CheckpointSigner, forwarder ports.SecurityEventForwarder, queueSize int) ports.AuditService {
+	s := &auditService{
+		repo:      repo,
+		signer:    signer,
+		forwarder: forwarder,
+	}
+	if queueSize > 0 {
+		s.queue = make(chan domain.AuditEntry, queueSize)
+	}
+	return s
+}
+
+func (s *auditService) Record(ctx context.Context, entry domain.AuditEntry) {
+	if entry.OccurredAt.IsZero() {
+		entry.OccurredAt = time.Now().UTC()
+	}
+	if s.enqueue(entry) {
+		return
+	}
+	s.write([]*domain.AuditEntry{&entry})
+}
+
+func (s *auditService) enqueue(entry domain.AuditEntry) bool {
+	if s.queue == nil {
+		return false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.stopped {
+		return false
+	}
+	select {
+	case s.queue <- entry:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s *auditService) Run(ctx context.Context) {
+	if s.queue == nil {
+		return
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			s.mu.Lock()
+			s.stopped = true
+			s.mu.Unlock()
+			for {
+				select {
+				case entry := <-s.queue:
+					s.writeQueued(entry)
+				default:
+					return
+				}
+			}
+		case entry := <-s.queue:
+			s.writeQueued(entry)
+		}
+	}
+}
+
+// writeQueued writes first together with whatever else is queued, up to
+// auditBatchSize entries, so that the partition head is locked once per
+// batch rather than once per entry.
+func (s *auditService) writeQueued(first domain.AuditEntry) {
+	batch := []*domain.AuditEntry{&first}
+collect:
+	for len(batch) < auditBatchSize {
+		select {
+		case entry := <-s.queue:
+			batch = append(batch, &entry)
+		default:
+			break collect
+		}
+	}
+	s.write(batch)
+}
+
+func (s *auditService) write(entries []*domain.AuditEntry) {
+	if err := s.repo.Append(entries...); err != nil {
+		for _, entry := range entries {
+			zap.L().Error("failed to write audit entry",
+				zap.String("action", entry.Action),
+				zap.String("outcome", entry.Outcome),
+				zap.String("actor_id", entry.ActorID),
+				zap.Error(err),
+			)
+		}
+	}
+	// Entries are forwarded even when storing them failed, so the SIEM
+	// still sees them.
+	if s.forwarder != nil {
+		for _, entry := range entries {
+			s.forwarder.Forward(domain.SecurityEventFromAudit(entry))
+		}
+	}
+}
+
+func (s *auditService) Query(ctx context.Context, filter domain.AuditFilter) (*domain.AuditPage, error) {
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.PageSize < 1 {
+		filter.PageSize = defaultAuditPageSize
+	}
+	filter.PageSize = min(filter.PageSize, maxAuditPageSize)
+
+	entries, total, err := s.repo.Find(filter)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.AuditPage{
+		Entries:  entries,
+		Total:    total,
+		Page:     filter.Page,
+		PageSize: filter.PageSize,
+	}, nil
+}
+
+func (s *auditService) Export(ctx context.Context, filter domain.AuditFilter, fn func(*domain.AuditEntry) error) error {
+	return s.repo.Each(filter, func(entry *domain.AuditEntry) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return fn(entry)
+	})
+}
+
+func (s *auditService) Checkpoint(ctx context.Context) error {
+	if s.signer == nil {
+		return nil
+	}
+	heads, err := s.repo.Heads()
+	if err != nil {
+		return err
+	}
+
+	for _, head := range heads {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		last, err := s.repo.LastCheckpoint(head.Partition)
+		if err != nil {
+			return err
+		}
+		if last != nil && last.Sequence >= head.Sequence {
+			continue
+		}
+
+		checkpoint := &domain.AuditCheckpoint{
+			Partition: head.Partition,
+			Sequence:  head.Sequence,
+			Hash:      head.Hash,
+			CreatedAt: time.Now().UTC().Truncate(time.Microsecond),
+			KeyID:     s.signer.KeyID(),
+		}
+		if checkpoint.Signature, err = s.signer.Sign(checkpoint.SigningPayload()); err != nil {
+			return err
+		}
+		if err := s.repo.AppendCheckpoint(checkpoint); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *auditService) RunCheckpoints(ctx context.Context, interval time.Duration) {
+	if s.signer == nil || interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Checkpoint(ctx); err != nil && ctx.Err() == nil {
+				zap.L().Error("failed to write audit checkpoint", zap.Error(err))
+			}
+		}
+	}
+}
+
+func (s *auditService) RunRetention(ctx context.Context, retention, interval time.Duration) {
+	if s.signer == nil || retention <= 0 || interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		before := time.Now().UTC().Add(-retention)
+		if n, err := s.purge(before); err != nil {
+			zap.L().Error("failed to purge expired audit entries", zap.Error(err))
+		} else if n > 0 {
+			zap.L().Info("purged expired audit entries",
+				zap.Int64("count", n),
+				zap.String("before_partition", domain.AuditPartition(before)),
+			)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// purge signs the head of every partition that ended before the one of
+// before and grew since its last purge, then deletes the entries covered.
+func (s *auditService) purge(before time.Time) (int64, error) {
+	cutoff := domain.AuditPartition(before)
+	heads, err := s.repo.Heads()
+	if err != nil {
+		return 0, err
+	}
+
+	var purges []*domain.AuditPurge
+	for _, head := range heads {
+		if head.Partition >= cutoff {
+			continue
+		}
+		last, err := s.repo.LastPurge(head.Partition)
+		if err != nil {
+			return 0, err
+		}
+		if last != nil && last.Sequence >= head.Sequence {
+			continue
+		}
+
+		purge := &domain.AuditPurge{
+			Partition: head.Partition,
+			Sequence:  head.Sequence,
+			Hash:      head.Hash,
+			PurgedAt:  time.Now().UTC().Truncate(time.Microsecond),
+			KeyID:     s.signer.KeyID(),
+		}
+		if purge.Signature, err = s.signer.Sign(purge.SigningPayload()); err != nil {
+			return 0, err
+		}
+		purges = append(purges, purge)
+	}
+	return s.repo.Purge(before, purges)
+}
+
+func (s *auditService) Verify(ctx context.Context, partition string) (*domain.AuditVerification, error) {
+	checkpoints, err := s.repo.Checkpoints(partition)
+	if err != nil {
+		return nil, err
+	}
+	result := &domain.AuditVerification{
+		Partition:   partition,
+		Checkpoints: len(checkpoints),
+	}
+
+	bySequence := make(map[int64]*domain.AuditCheckpoint, len(checkpoints))
+	for _, checkpoint := range checkpoints {
+		if s.signer != nil && !s.signer.Verify(checkpoint.SigningPayload(), checkpoint.Signature) {
+			result.Break = &domain.AuditChainBreak{
+				Sequence: checkpoint.Sequence,
+				Reason:   fmt.Sprintf("checkpoint %s has an invalid signature", checkpoint.ID),
+			}
+			return result, nil
+		}
+		bySequence[checkpoint.Sequence] = checkpoint
+	}
+
+	// Entries up to the last purge are gone; the chain continues from the
+	// hash it recorded.
+	var start int64
+	var prevHash string
+	purge, err := s.repo.LastPurge(partition)
+	if err != nil {
+		return nil, err
+	}
+	if purge != nil {
+		brk := func(reason string) (*domain.AuditVerification, error) {
+			result.Break = &domain.AuditChainBreak{Sequence: purge.Sequence, Reason: reason}
+			return result, nil
+		}
+		if s.signer != nil && !s.signer.Verify(purge.SigningPayload(), purge.Signature) {
+			return brk(fmt.Sprintf("purge %s has an invalid signature", purge.ID))
+		}
+		if checkpoint, ok := bySequence[purge.Sequence]; ok && checkpoint.Hash != purge.Hash {
+			return brk(fmt.Sprintf("purge %s differs from checkpoint %s", purge.ID, checkpoint.ID))
+		}
+		result.Purge = purge
+		start, prevHash = purge.Sequence, purge.Hash
+	}
+
+	// The head is read before walking, so entries appended meanwhile only
+	// extend the walk past it.
+	head, err := s.repo.Head(partition)
+	if err != nil {
+		return nil, err
+	}
+	if purge != nil && (head == nil || head.Sequence < purge.Sequence ||
+		head.Sequence == purge.Sequence && head.Hash != purge.Hash) {
+		result.Break = &domain.AuditChainBreak{
+			Sequence: purge.Sequence,
+			Reason:   fmt.Sprintf("the chain head does not match purge %s", purge.ID),
+		}
+		return result, nil
+	}
+
+	err = s.repo.EachInChain(partition, func(entry *domain.AuditEntry) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		expected := start + result.Entries + 1
+		brk := func(reason string) error {
+			result.Break = &domain.AuditChainBreak{Sequence: expected, EntryID: entry.ID, Reason: reason}
+			return errStopWalk
+		}
+
+		if entry.Sequence != expected {
+			return brk(fmt.Sprintf("entry %d is missing, found %d", expected, entry.Sequence))
+		}
+		if entry.PrevHash != prevHash {
+			return brk("previous hash does not match the preceding entry")
+		}
+		hash, err := domain.AuditChainHash(entry)
+		if err != nil {
+			return err
+		}
+		if hash != entry.Hash {
+			return brk("entry content does not match its hash")
+		}
+		if checkpoint, ok := bySequence[entry.Sequence]; ok && checkpoint.Hash != entry.Hash {
+			return brk(fmt.Sprintf("hash differs from checkpoint %s", checkpoint.ID))
+		}
+		if head != nil && head.Sequence == entry.Sequence && head.Hash != entry.Hash {
+			return brk("hash differs from the chain head")
+		}
+
+		prevHash = entry.Hash
+		result.Entries++
+		return nil
+	})
+	if err != nil && !errors.Is(err, errStopWalk) {
+		return nil, err
+	}
+	if result.Break != nil {
+		return result, nil
+	}
+
+	last := start + result.Entries
+	if n := len(checkpoints); n > 0 && checkpoints[n-1].Sequence > last {
+		result.Break = &domain.AuditChainBreak{
+			Sequence: last + 1,
+			Reason: fmt.Sprintf("entries %d to %d are missing (checkpoint %s)",
+				last+1, checkpoints[n-1].Sequence, checkpoints[n-1].ID),
+		}
+		return result, nil
+	}
+
+	if head == nil && result.Entries > 0 {
+		// The first entries may have been appended during the walk.
+		if head, err = s.repo.Head(partition); err != nil {
+			return nil, err
+		}
+		if head == nil {
+			result.Break = &domain.AuditChainBreak{
+				Sequence: result.Entries,
+				Reason:   "the chain head is missing",
+			}
+		}
+		return result, nil
+	}
+	if head != nil && head.Sequence > last {
+		result.Break = &domain.AuditChainBreak{
+			Sequence: last + 1,
+			Reason:   fmt.Sprintf("entries %d to %d are missing (chain head)", last+1, head.Sequence),
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,18 @@
+package services
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+// retryBackoff returns the wait before the next attempt after attempts
+// failures: base, doubling up to max, plus up to 10% jitter so that retries
+// of one outage spread out.
+func retryBackoff(base, max time.Duration, attempts int) time.Duration {
+	wait := base
+	for i := 1; i < attempts && wait < max; i++ {
+		wait *= 2
+	}
+	wait = min(wait, max)
+	return wait + rand.N(wait/10+1)
+}
@@ -0,0 +1,79 @@
+package services
+
+import (
+	"context"
+
+	"github.com/kanta/backend-challenge/internal/core/domain"
+	"github.com/kanta/backend-challenge/internal/core/ports"
+	"go.uber.org/zap"
+)
+
+type challengeService struct {
+	attempts ports.LoginAttemptPort
+	cache    ports.CachePort
+	verifier ports.ChallengeVerifier
+	policy   domain.ChallengePolicy
+}
+
+func NewChallengeService(attempts ports.LoginAttemptPort, cache ports.CachePort, verifier ports.ChallengeVerifier, policy domain.ChallengePolicy) ports.ChallengeService {
+	return &challengeService{
+		attempts: attempts,
+		cache:    cache,
+		verifier: verifier,
+		policy:   policy,
+	}
+}
+
+func knownRangeKey(ipRange string) string {
+	return "challenge:range:" + ipRange
+}
+
+func (s *challengeService) Required(ctx context.Context, ip string) (bool, error) {
+	if s.policy.IPFailureThreshold > 0 {
+		failures, err := s.attempts.GetFailures(ctx, ipKey(ip))
+		if err != nil {
+			return false, err
+		}
+		if failures >= int64(s.policy.IPFailureThreshold) {
+			return true, nil
+		}
+	}
+
+	if s.policy.NewRange {
+		ipRange := domain.IPRange(ip)
+		if ipRange == "" {
+			return true, nil
+		}
+		// GetToken fails for missing keys as well as cache errors; both
+		// mean the range is not known to be safe.
+		if _, err := s.cache.GetToken(ctx, knownRangeKey(ipRange)); err != nil {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *challengeService) Issue(ctx context.Context, client domain.ClientInfo) (*domain.Challenge, error) {
+	return s.verifier.Issue(ctx, client)
+}
+
+func (s *challengeService) Verify(ctx context.Context, solution string, client domain.ClientInfo) error {
+	return s.verifier.Verify(ctx, solution, client)
+}
+
+func (s *challengeService) Publish(ctx context.Context, event domain.Event) error {
+	if !s.policy.NewRange || event.Type != domain.EventUserLoggedIn {
+		return nil
+	}
+	ip, _ := event.Data["ip"].(string)
+	ipRange := domain.IPRange(ip)
+	if ipRange == "" {
+		return nil
+	}
+	// A range that is not remembered only costs its users another
+	// challenge, so a cache error must not hold up the outbox.
+	if err := s.cache.SetToken(ctx, knownRangeKey(ipRange), "1", s.policy.RangeMemory); err != nil {
+		zap.L().Warn("failed to remember login range", zap.String("range", ipRange), zap.Error(err))
+	}
+	return nil
+}
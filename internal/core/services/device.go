@@ -0,0 +1,73 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/kanta/backend-challenge/internal/core/domain"
+	"go.uber.org/zap"
+)
+
+// deviceFingerprint prefers the identifier the client app supplies. Without
+// one it falls back to the user agent together with the IP range, since a
+// common user agent alone is easy to match: a new network then counts as a
+// new device.
+func deviceFingerprint(client domain.ClientInfo) string {
+	source := "ua:" + client.UserAgent + "|range:" + domain.IPRange(client.IP)
+	if client.DeviceID != "" {
+		source = "id:" + client.DeviceID
+	}
+	sum := sha256.Sum256([]byte(source))
+	return hex.EncodeToString(sum[:])
+}
+
+// trackDevice records the device a login came from. When it has not been
+// seen before for this user, including the first device and devices that
+// were forgotten, the user is emailed and an event is published.
+func (s *service) trackDevice(ctx context.Context, user *domain.User, client domain.ClientInfo) {
+	now := time.Now()
+	fingerprint := deviceFingerprint(client)
+
+	if device, err := s.deviceRepo.FindByFingerprint(user.ID, fingerprint); err == nil {
+		if err := s.deviceRepo.Touch(device.ID, client.IP, now); err != nil {
+			zap.L().Warn("failed to update device", zap.String("user_id", user.ID), zap.Error(err))
+		}
+		return
+	}
+
+	device := &domain.Device{
+		UserID:      user.ID,
+		Fingerprint: fingerprint,
+		DeviceID:    client.DeviceID,
+		UserAgent:   client.UserAgent,
+		LastIP:      client.IP,
+		FirstSeenAt: now,
+		LastSeenAt:  now,
+	}
+	if err := s.deviceRepo.Create(device); err != nil {
+		zap.L().Warn("failed to record device", zap.String("user_id", user.ID), zap.Error(err))
+		return
+	}
+
+	if err := s.mailer.SendNewDeviceLogin(ctx, user.Email, device); err != nil {
+		zap.L().Error("failed to send new device email", zap.String("user_id", user.ID), zap.Error(err))
+	}
+	s.publish(ctx, domain.NewEvent(domain.NewDeviceLogin{
+		UserID:    user.ID,
+		Email:     user.Email,
+		DeviceID:  device.ID,
+		UserAgent: device.UserAgent,
+		IP:        device.LastIP,
+	}))
+}
+
+func (s *service) ListDevices(userID string) ([]*domain.Device, error) {
+	return s.deviceRepo.ListByUser(userID)
+}
+
+// ForgetDevice removes a device so the next login from it alerts again.
+func (s *service) ForgetDevice(userID, deviceID string) error {
+	return s.deviceRepo.Delete(userID, deviceID)
+}
@@ -0,0 +1,263 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/kanta/backend-challenge/internal/core/domain"
+	"github.com/kanta/backend-challenge/internal/core/ports"
+	"go.uber.org/zap"
+)
+
+const (
+	// exportLease outlives building any one archive, so an export is only
+	// picked up again if the instance building it died.
+	exportLease     = 10 * time.Minute
+	exportBatchSize = 10
+	// exportArchiveVersion is bumped when the archive layout changes.
+	exportArchiveVersion = 1
+)
+
+type exportService struct {
+	repo     ports.ExportRepository
+	store    ports.ExportStore
+	users    ports.UserRepository
+	devices  ports.DeviceRepository
+	sessions ports.SessionStore
+	history  ports.LoginHistoryRepository
+	audit    ports.AuditRepository
+	// secret signs download links.
+	secret []byte
+	policy domain.ExportPolicy
+}
+
+func NewExportService(
+	repo ports.ExportRepository,
+	store ports.ExportStore,
+	users ports.UserRepository,
+	devices ports.DeviceRepository,
+	sessions ports.SessionStore,
+	history ports.LoginHistoryRepository,
+	audit ports.AuditRepository,
+	secret []byte,
+	policy domain.ExportPolicy,
+) ports.ExportService {
+	return &exportService{
+		repo:     repo,
+		store:    store,
+		users:    users,
+		devices:  devices,
+		sessions: sessions,
+		history:  history,
+		audit:    audit,
+		secret:   secret,
+		policy:   policy,
+	}
+}
+
+func (s *exportService) Request(ctx context.Context, userID string) (*domain.DataExport, error) {
+	now := time.Now().UTC()
+	export := &domain.DataExport{
+		UserID:    userID,
+		Status:    domain.ExportPending,
+		CreatedAt: now,
+	}
+	if err := s.repo.Create(export, now.Add(-s.policy.Interval)); err != nil {
+		return nil, err
+	}
+	return export, nil
+}
+
+func (s *exportService) Get(ctx context.Context, userID, id string) (*domain.DataExport, error) {
+	export, err := s.repo.Find(id)
+	if err != nil {
+		return nil, err
+	}
+	if export.UserID != userID {
+		return nil, domain.ErrExportNotFound
+	}
+
+	if export.Status == domain.ExportReady {
+		expiresAt := time.Now().Add(s.policy.URLTTL).UTC()
+		if export.ExpiresAt != nil && export.ExpiresAt.Before(expiresAt) {
+			expiresAt = *export.ExpiresAt
+		}
+		expires := expiresAt.Unix()
+		query := url.Values{}
+		query.Set("expires", strconv.FormatInt(expires, 10))
+		query.Set("signature", s.sign(export.ID, expires))
+		export.DownloadURL = s.policy.BaseURL + "/api/v1/exports/" + export.ID + "/download?" + query.Encode()
+		export.DownloadExpiresAt = &expiresAt
+	}
+	return export, nil
+}
+
+func (s *exportService) Open(ctx context.Context, id string, expires int64, signature string) (io.ReadCloser, error) {
+	if time.Now().Unix() > expires || !hmac.Equal([]byte(signature), []byte(s.sign(id, expires))) {
+		return nil, domain.ErrInvalidExportLink
+	}
+
+	export, err := s.repo.Find(id)
+	if err != nil {
+		return nil, err
+	}
+	// Links are only handed out for ready exports, so anything else has
+	// expired since.
+	if export.Status != domain.ExportReady {
+		return nil, domain.ErrExportExpired
+	}
+	return s.store.Open(id)
+}
+
+// sign authenticates a download link, so the archive can be fetched without
+// a bearer token, e.g. straight from a browser.
+func (s *exportService) sign(id string, expires int64) string {
+	mac := hmac.New(sha256.New, s.secret)
+	fmt.Fprintf(mac, "%s.%d", id, expires)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (s *exportService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.policy.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		exports, err := s.repo.ClaimPending(time.Now().UTC(), exportLease, exportBatchSize)
+		if err != nil {
+			zap.L().Error("failed to claim data exports", zap.Error(err))
+		}
+		for _, export := range exports {
+			if ctx.Err() != nil {
+				return
+			}
+			s.complete(ctx, export)
+		}
+		s.expire()
+	}
+}
+
+// complete builds the archive of export and records the outcome.
+func (s *exportService) complete(ctx context.Context, export *domain.DataExport) {
+	now := time.Now().UTC()
+	export.CompletedAt = &now
+
+	size, err := s.build(ctx, export)
+	if err != nil {
+		zap.L().Error("failed to build data export", zap.String("export_id", export.ID), zap.Error(err))
+		export.Status = domain.ExportFailed
+		export.Error = "failed to build export"
+	} else {
+		expiresAt := now.Add(s.policy.Retention)
+		export.Status = domain.ExportReady
+		export.Size = size
+		export.ExpiresAt = &expiresAt
+	}
+
+	if err := s.repo.Update(export); err != nil {
+		zap.L().Error("failed to update data export", zap.String("export_id", export.ID), zap.Error(err))
+	}
+}
+
+func (s *exportService) build(ctx context.Context, export *domain.DataExport) (int64, error) {
+	user, err := s.users.FindByID(export.UserID)
+	if err != nil {
+		return 0, err
+	}
+
+	archive := domain.UserDataArchive{
+		Version:     exportArchiveVersion,
+		GeneratedAt: time.Now().UTC(),
+		Profile: domain.ExportedProfile{
+			ID:        user.ID,
+			Name:      user.Name,
+			Email:     user.Email,
+			Role:      user.Role,
+			CreatedAt: user.CreatedAt,
+		},
+		Sessions:         []*domain.Session{},
+		LinkedIdentities: []any{},
+	}
+	// A user has at most one session, and none when signed out.
+	if session, err := s.sessions.Get(ctx, user.ID); err == nil {
+		archive.Sessions = append(archive.Sessions, session)
+	}
+	if archive.Devices, err = s.devices.ListByUser(user.ID); err != nil {
+		return 0, err
+	}
+	if archive.LoginHistory, err = s.history.ListByUser(user.ID); err != nil {
+		return 0, err
+	}
+	if archive.AuditEntries, err = s.auditEntries(user); err != nil {
+		return 0, err
+	}
+
+	return s.store.Save(export.ID, func(w io.Writer) error {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(archive)
+	})
+}
+
+// auditEntries collects the entries the user performed or was the subject
+// of, failed logins included. Entries that merely carry the user's email are
+// left out: they may come from anyone, e.g. logins before the account
+// existed, and hold that client's IP and user agent.
+func (s *exportService) auditEntries(user *domain.User) ([]*domain.AuditEntry, error) {
+	seen := make(map[string]bool)
+	entries := []*domain.AuditEntry{}
+	for _, filter := range []domain.AuditFilter{
+		{ActorID: user.ID},
+		{SubjectID: user.ID},
+	} {
+		err := s.audit.Each(filter, func(entry *domain.AuditEntry) error {
+			if !seen[entry.ID] {
+				seen[entry.ID] = true
+				entries = append(entries, entry)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	slices.SortStableFunc(entries, func(a, b *domain.AuditEntry) int {
+		return a.OccurredAt.Compare(b.OccurredAt)
+	})
+	return entries, nil
+}
+
+// expire deletes archives past their retention. The export itself is kept,
+// so that it still counts against the request limit.
+func (s *exportService) expire() {
+	exports, err := s.repo.FindExpired(time.Now().UTC(), exportBatchSize)
+	if err != nil {
+		zap.L().Error("failed to find expired data exports", zap.Error(err))
+		return
+	}
+	for _, export := range exports {
+		if err := s.store.Delete(export.ID); err != nil {
+			zap.L().Error("failed to delete data export", zap.String("export_id", export.ID), zap.Error(err))
+			continue
+		}
+		export.Status = domain.ExportExpired
+		if err := s.repo.Update(export); err != nil {
+			zap.L().Error("failed to update data export", zap.String("export_id", export.ID), zap.Error(err))
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package services
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/kanta/backend-challenge/internal/core/domain"
+	"github.com/kanta/backend-challenge/internal/core/ports"
+)
+
+var ErrUnsupportedHash = errors.New("unsupported password hash format")
+
+type importService struct {
+	userRepo ports.UserRepository
+	hasher   ports.PasswordHasher
+}
+
+func NewUserImportService(
+	userRepo ports.UserRepository,
+	hasher ports.PasswordHasher,
+) ports.UserImportService {
+	return &importService{
+		userRepo: userRepo,
+		hasher:   hasher,
+	}
+}
+
+// ImportUser stores a user with the password hash from their previous auth
+// provider. The hash is upgraded to the current algorithm on first login.
+func (s *importService) ImportUser(user *domain.User) error {
+	user.Email = strings.TrimSpace(user.Email)
+	if user.Email == "" {
+		return errors.New("email is required")
+	}
+	if !s.hasher.Supports(user.Password) {
+		return ErrUnsupportedHash
+	}
+
+	// Roles are never carried over from another system.
+	user.Role = ""
+	return s.userRepo.Create(user)
+}
@@ -0,0 +1,144 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/kanta/backend-challenge/internal/core/domain"
+	"go.uber.org/zap"
+)
+
+func accountKey(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
+
+// checkLockout rejects the attempt if the account or IP is locked and
+// otherwise applies a progressive delay based on earlier failures. Counter
+// errors are logged and ignored so a Redis outage does not block logins.
+func (s *service) checkLockout(ctx context.Context, email, ip string) error {
+	keys := []string{accountKey(email)}
+	if ip != "" {
+		keys = append(keys, ipKey(ip))
+	}
+
+	for _, key := range keys {
+		remaining, err := s.attempts.LockedFor(ctx, key)
+		if err != nil {
+			zap.L().Warn("failed to read login lock", zap.String("key", key), zap.Error(err))
+			continue
+		}
+		if remaining > 0 {
+			return domain.ErrLoginLocked
+		}
+	}
+
+	failures, err := s.attempts.GetFailures(ctx, accountKey(email))
+	if err != nil {
+		zap.L().Warn("failed to read login failures", zap.Error(err))
+		return nil
+	}
+	return sleep(ctx, s.progressiveDelay(failures))
+}
+
+func (s *service) progressiveDelay(failures int64) time.Duration {
+	if failures <= 0 || s.lockout.DelayBase <= 0 {
+		return 0
+	}
+	delay := s.lockout.DelayBase
+	for i := int64(1); i < failures; i++ {
+		delay *= 2
+		if s.lockout.DelayMax > 0 && delay >= s.lockout.DelayMax {
+			return s.lockout.DelayMax
+		}
+	}
+	return delay
+}
+
+// recordFailure bumps the account and IP counters, locks whichever crossed
+// its threshold and always returns an error suitable for the caller.
+func (s *service) recordFailure(ctx context.Context, email, ip string) error {
+	locked := false
+
+	n, err := s.attempts.RegisterFailure(ctx, accountKey(email), s.lockout.FailureWindow)
+	if err != nil {
+		zap.L().Warn("failed to record login failure", zap.Error(err))
+	} else if s.lockout.MaxAccountFailures > 0 && n >= int64(s.lockout.MaxAccountFailures) {
+		if until, ok := s.lock(ctx, accountKey(email)); ok {
+			s.publish(ctx, domain.NewEvent(domain.AccountLocked{
+				Email:       email,
+				IP:          ip,
+				Failures:    n,
+				LockedUntil: until,
+			}))
+			locked = true
+		}
+	}
+
+	if ip != "" {
+		n, err := s.attempts.RegisterFailure(ctx, ipKey(ip), s.lockout.FailureWindow)
+		if err != nil {
+			zap.L().Warn("failed to record login failure", zap.Error(err))
+		} else if s.lockout.MaxIPFailures > 0 && n >= int64(s.lockout.MaxIPFailures) {
+			if until, ok := s.lock(ctx, ipKey(ip)); ok {
+				s.publish(ctx, domain.NewEvent(domain.IPLocked{
+					IP:          ip,
+					Failures:    n,
+					LockedUntil: until,
+				}))
+				locked = true
+			}
+		}
+	}
+
+	if locked {
+		return domain.ErrLoginLocked
+	}
+	return domain.ErrInvalidCredentials
+}
+
+// lock locks key and reports until when, or false if locking failed.
+func (s *service) lock(ctx context.Context, key string) (time.Time, bool) {
+	if err := s.attempts.Lock(ctx, key, s.lockout.Duration); err != nil {
+		zap.L().Warn("failed to lock login", zap.String("key", key), zap.Error(err))
+		return time.Time{}, false
+	}
+	if err := s.attempts.ResetFailures(ctx, key); err != nil {
+		zap.L().Warn("failed to reset login failures", zap.String("key", key), zap.Error(err))
+	}
+
+	return time.Now().Add(s.lockout.Duration).UTC(), true
+}
+
+func (s *service) recordSuccess(ctx context.Context, email string) {
+	if err := s.attempts.ResetFailures(ctx, accountKey(email)); err != nil {
+		zap.L().Warn("failed to reset login failures", zap.Error(err))
+	}
+}
+
+// UnlockAccount clears the lock and failure counter for the user's account.
+func (s *service) UnlockAccount(ctx context.Context, userID string) error {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return err
+	}
+	return s.attempts.Unlock(ctx, accountKey(user.Email))
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
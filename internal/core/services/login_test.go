@@ -0,0 +1,171 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kanta/backend-challenge/internal/core/domain"
+	"github.com/kanta/backend-challenge/internal/core/ports"
+)
+
+type memoryUserRepository struct {
+	ports.UserRepository
+	users []*domain.User
+}
+
+func (r *memoryUserRepository) FindOne(filter map[string]interface{}) (*domain.User, error) {
+	for _, user := range r.users {
+		if user.Email == filter["email"] {
+			copied := *user
+			return &copied, nil
+		}
+	}
+	return nil, errors.New("record not found")
+}
+
+// memoryLoginAttempts counts failures and never locks.
+type memoryLoginAttempts struct {
+	ports.LoginAttemptPort
+	mu       sync.Mutex
+	failures map[string]int64
+}
+
+func (a *memoryLoginAttempts) RegisterFailure(_ context.Context, key string, _ time.Duration) (int64, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.failures[key]++
+	return a.failures[key], nil
+}
+
+func (a *memoryLoginAttempts) GetFailures(_ context.Context, key string) (int64, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.failures[key], nil
+}
+
+func (a *memoryLoginAttempts) ResetFailures(_ context.Context, key string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.failures, key)
+	return nil
+}
+
+func (a *memoryLoginAttempts) LockedFor(context.Context, string) (time.Duration, error) {
+	return 0, nil
+}
+
+// plainHasher stores passwords with a prefix, so tests skip the key
+// derivation.
+type plainHasher struct {
+	ports.PasswordHasher
+}
+
+func (plainHasher) Hash(password string) (string, error) {
+	return "plain:" + password, nil
+}
+
+func (plainHasher) Verify(encoded, password string) (bool, error) {
+	return encoded == "plain:"+password, nil
+}
+
+func (plainHasher) NeedsRehash(string) bool {
+	return false
+}
+
+type noGeoIP struct{}
+
+func (noGeoIP) Lookup(string) (domain.GeoLocation, error) {
+	return domain.GeoLocation{}, nil
+}
+
+// listedIP puts every IP on one reputation list with score.
+type listedIP struct {
+	score int
+}
+
+func (r listedIP) Lookup(string) []domain.ReputationHit {
+	return []domain.ReputationHit{{List: "tor", Score: r.score}}
+}
+
+type emptyLoginHistory struct {
+	ports.LoginHistoryRepository
+}
+
+func (emptyLoginHistory) Recent(string, int) ([]*domain.LoginRecord, error) {
+	return nil, nil
+}
+
+func newTestLoginService(reputationScore int) (*service, *memoryLoginAttempts) {
+	attempts := &memoryLoginAttempts{failures: map[string]int64{}}
+	risk := NewRiskEngine(noGeoIP{}, listedIP{score: reputationScore}, emptyLoginHistory{}, domain.RiskPolicy{
+		AlertScore:     30,
+		ChallengeScore: 50,
+		BlockScore:     100,
+	})
+	users := &memoryUserRepository{users: []*domain.User{
+		{ID: "user-1", Email: "known@example.com", Password: "plain:correct-password"},
+	}}
+	s := NewBackEndService(users, nil, nil, nil, nil, attempts, &recordingProducer{}, nil, nil, plainHasher{}, risk, domain.LockoutPolicy{}, domain.PasswordPolicy{})
+	return s.(*service), attempts
+}
+
+func TestAuthenticateAnswersKnownAndUnknownEmailsAlike(t *testing.T) {
+	tests := []struct {
+		name            string
+		reputationScore int
+		password        string
+		challengePassed bool
+		wantErr         error
+	}{
+		{
+			name:            "risky client is challenged before the account is looked up",
+			reputationScore: 60,
+			password:        "correct-password",
+			wantErr:         domain.ErrChallengeRequired,
+		},
+		{
+			name:            "wrong password after a solved challenge",
+			reputationScore: 60,
+			password:        "wrong-password",
+			challengePassed: true,
+			wantErr:         domain.ErrInvalidCredentials,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := newTestLoginService(tt.reputationScore)
+			client := domain.ClientInfo{IP: "203.0.113.7", UserAgent: "curl/8.0", ChallengePassed: tt.challengePassed}
+
+			_, knownRisk, knownErr := s.Authenticate(context.Background(), "known@example.com", tt.password, client)
+			_, unknownRisk, unknownErr := s.Authenticate(context.Background(), "unknown@example.com", tt.password, client)
+
+			if !errors.Is(knownErr, tt.wantErr) || !errors.Is(unknownErr, tt.wantErr) {
+				t.Fatalf("errors = %v and %v, want %v for both", knownErr, unknownErr, tt.wantErr)
+			}
+			if !reflect.DeepEqual(knownRisk, unknownRisk) {
+				t.Fatalf("assessments differ: %+v and %+v", knownRisk, unknownRisk)
+			}
+		})
+	}
+}
+
+func TestAuthenticateBlockDoesNotCountAsFailure(t *testing.T) {
+	s, attempts := newTestLoginService(120)
+	client := domain.ClientInfo{IP: "203.0.113.7", UserAgent: "curl/8.0"}
+
+	user, risk, err := s.Authenticate(context.Background(), "known@example.com", "correct-password", client)
+	if !errors.Is(err, domain.ErrLoginBlocked) {
+		t.Fatalf("Authenticate() error = %v, want %v", err, domain.ErrLoginBlocked)
+	}
+	if user == nil || risk == nil || risk.Decision != domain.RiskBlock {
+		t.Fatalf("Authenticate() = %v, %+v, want the user with a block decision", user, risk)
+	}
+	if n, _ := attempts.GetFailures(context.Background(), accountKey("known@example.com")); n != 0 {
+		t.Fatalf("blocked login counted as %d failures", n)
+	}
+}
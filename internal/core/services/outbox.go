@@ -0,0 +1,133 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/kanta/backend-challenge/internal/core/domain"
+	"github.com/kanta/backend-challenge/internal/core/ports"
+	"go.uber.org/zap"
+)
+
+type outboxRelay struct {
+	repo   ports.OutboxRepository
+	sinks  []ports.OutboxSink
+	policy domain.OutboxPolicy
+}
+
+// NewOutboxRelay publishes outbox messages to every sink. Delivery is at
+// least once: a message published just before a crash is published again,
+// with the same event ID. A message one sink rejects is retried on that sink
+// only.
+func NewOutboxRelay(repo ports.OutboxRepository, sinks []ports.OutboxSink, policy domain.OutboxPolicy) ports.OutboxRelay {
+	return &outboxRelay{
+		repo:   repo,
+		sinks:  sinks,
+		policy: policy,
+	}
+}
+
+func (r *outboxRelay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.policy.PollInterval)
+	defer ticker.Stop()
+	cleanup := time.NewTicker(r.policy.CleanupInterval)
+	defer cleanup.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-cleanup.C:
+			r.cleanup()
+			continue
+		case <-ticker.C:
+		}
+
+		// Keep relaying while full batches come back, so a backlog drains
+		// faster than one batch per tick.
+		for ctx.Err() == nil {
+			n, err := r.Relay(ctx)
+			if err != nil {
+				zap.L().Error("failed to relay outbox", zap.Error(err))
+				break
+			}
+			if n < r.policy.BatchSize {
+				break
+			}
+		}
+	}
+}
+
+func (r *outboxRelay) Relay(ctx context.Context) (int, error) {
+	claimedAt := time.Now().UTC()
+	messages, err := r.repo.ClaimDue(claimedAt, r.policy.Lease, r.policy.BatchSize)
+	if err != nil || len(messages) == 0 {
+		return 0, err
+	}
+
+	// Publishing stops when the lease runs out, as another relay may claim
+	// the messages from then on.
+	publishCtx, cancel := context.WithDeadline(ctx, claimedAt.Add(r.policy.Lease))
+	defer cancel()
+
+	// Once a message fails, later ones of its aggregate wait for it so
+	// that consumers see each aggregate's events in order.
+	blocked := make(map[string]bool)
+	for _, message := range messages {
+		aggregateID := message.Event.AggregateID
+		if publishCtx.Err() != nil || blocked[aggregateID] {
+			// Give the claim back; the message is due again at once.
+			message.NextAttemptAt = claimedAt
+			continue
+		}
+
+		if err := r.publish(publishCtx, message); err != nil {
+			blocked[aggregateID] = true
+			message.Attempts++
+			message.LastError = truncate(err.Error(), 1000)
+			message.NextAttemptAt = time.Now().UTC().Add(retryBackoff(r.policy.BackoffBase, r.policy.BackoffMax, message.Attempts))
+			zap.L().Warn("failed to publish outbox message",
+				zap.Int64("id", message.ID),
+				zap.String("type", message.Event.Type),
+				zap.Int("attempts", message.Attempts),
+				zap.Strings("published_to", message.PublishedTo),
+				zap.Error(err),
+			)
+			continue
+		}
+		publishedAt := time.Now().UTC()
+		message.PublishedAt = &publishedAt
+	}
+	return len(messages), r.repo.Update(messages...)
+}
+
+// publish hands message to each sink that has not accepted it yet and adds
+// the ones that do to PublishedTo.
+func (r *outboxRelay) publish(ctx context.Context, message *domain.OutboxMessage) error {
+	var errs []error
+	for _, sink := range r.sinks {
+		if slices.Contains(message.PublishedTo, sink.Name) {
+			continue
+		}
+		if err := sink.Producer.Publish(ctx, message.Event); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", sink.Name, err))
+			continue
+		}
+		message.PublishedTo = append(message.PublishedTo, sink.Name)
+	}
+	return errors.Join(errs...)
+}
+
+func (r *outboxRelay) cleanup() {
+	n, err := r.repo.DeletePublished(time.Now().UTC().Add(-r.policy.Retention))
+	if err != nil {
+		zap.L().Error("failed to delete published outbox messages", zap.Error(err))
+		return
+	}
+	if n > 0 {
+		zap.L().Info("deleted published outbox messages", zap.Int64("count", n))
+	}
+}
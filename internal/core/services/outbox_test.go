@@ -0,0 +1,174 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kanta/backend-challenge/internal/core/domain"
+	"github.com/kanta/backend-challenge/internal/core/ports"
+)
+
+// memoryOutboxRepository claims messages like the Postgres repository, minus
+// the lock.
+type memoryOutboxRepository struct {
+	ports.OutboxRepository
+	mu       sync.Mutex
+	messages []*domain.OutboxMessage
+}
+
+func (r *memoryOutboxRepository) ClaimDue(now time.Time, lease time.Duration, limit int) ([]*domain.OutboxMessage, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	waiting := make(map[string]bool)
+	var claimed []*domain.OutboxMessage
+	for _, message := range r.messages {
+		if message.PublishedAt != nil {
+			continue
+		}
+		aggregateID := message.Event.AggregateID
+		if message.NextAttemptAt.After(now) {
+			waiting[aggregateID] = true
+			continue
+		}
+		if waiting[aggregateID] || len(claimed) == limit {
+			continue
+		}
+		message.NextAttemptAt = now.Add(lease)
+		copied := *message
+		copied.PublishedTo = slices.Clone(message.PublishedTo)
+		claimed = append(claimed, &copied)
+	}
+	return claimed, nil
+}
+
+func (r *memoryOutboxRepository) Update(messages ...*domain.OutboxMessage) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, message := range messages {
+		for i, stored := range r.messages {
+			if stored.ID == message.ID {
+				copied := *message
+				r.messages[i] = &copied
+			}
+		}
+	}
+	return nil
+}
+
+// recordingProducer records the event IDs it accepts and fails while err is
+// set.
+type recordingProducer struct {
+	mu        sync.Mutex
+	err       error
+	published []string
+}
+
+func (p *recordingProducer) Publish(ctx context.Context, event domain.Event) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err != nil {
+		return p.err
+	}
+	p.published = append(p.published, event.ID)
+	return nil
+}
+
+func (p *recordingProducer) fail(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.err = err
+}
+
+func (p *recordingProducer) events() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return slices.Clone(p.published)
+}
+
+func outboxMessage(id int64, aggregateID string) *domain.OutboxMessage {
+	return &domain.OutboxMessage{
+		ID:            id,
+		Event:         domain.Event{ID: aggregateID + "-" + strconv.FormatInt(id, 10), AggregateID: aggregateID},
+		NextAttemptAt: time.Now().UTC().Add(-time.Second),
+	}
+}
+
+func TestOutboxRelayRetriesOnlyFailedSinks(t *testing.T) {
+	repo := &memoryOutboxRepository{messages: []*domain.OutboxMessage{
+		outboxMessage(1, "a"),
+		outboxMessage(2, "a"),
+		outboxMessage(3, "b"),
+	}}
+	events, webhooks := &recordingProducer{}, &recordingProducer{}
+	webhooks.fail(errors.New("database is down"))
+	relay := NewOutboxRelay(repo, []ports.OutboxSink{
+		{Name: "events", Producer: events},
+		{Name: "webhooks", Producer: webhooks},
+	}, domain.OutboxPolicy{
+		BatchSize: 10,
+		Lease:     time.Minute,
+		// A zero backoff makes failed messages due again at once.
+		BackoffBase: 0,
+		BackoffMax:  0,
+	})
+
+	if n, err := relay.Relay(context.Background()); err != nil || n != 3 {
+		t.Fatalf("Relay() = %d, %v, want 3, nil", n, err)
+	}
+	// a-2 waits behind a-1, which only the events sink accepted.
+	if got, want := events.events(), []string{"a-1", "b-3"}; !slices.Equal(got, want) {
+		t.Fatalf("events sink got %v, want %v", got, want)
+	}
+	if got := repo.messages[0].PublishedTo; !slices.Equal(got, []string{"events"}) {
+		t.Fatalf("published to %v, want [events]", got)
+	}
+	if repo.messages[0].PublishedAt != nil || repo.messages[0].Attempts != 1 {
+		t.Fatalf("failed message published at %v with %d attempts", repo.messages[0].PublishedAt, repo.messages[0].Attempts)
+	}
+	if repo.messages[1].Attempts != 0 || repo.messages[1].NextAttemptAt.After(time.Now()) {
+		t.Fatalf("blocked message still claimed until %s", repo.messages[1].NextAttemptAt)
+	}
+
+	webhooks.fail(nil)
+	if _, err := relay.Relay(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := events.events(), []string{"a-1", "b-3", "a-2"}; !slices.Equal(got, want) {
+		t.Fatalf("events sink got %v, want %v", got, want)
+	}
+	if got, want := webhooks.events(), []string{"a-1", "a-2", "b-3"}; !slices.Equal(got, want) {
+		t.Fatalf("webhooks sink got %v, want %v", got, want)
+	}
+	for _, message := range repo.messages {
+		if message.PublishedAt == nil {
+			t.Errorf("message %d is not published", message.ID)
+		}
+	}
+}
+
+func TestOutboxRelayReleasesClaimOnShutdown(t *testing.T) {
+	repo := &memoryOutboxRepository{messages: []*domain.OutboxMessage{outboxMessage(1, "a")}}
+	events := &recordingProducer{}
+	relay := NewOutboxRelay(repo, []ports.OutboxSink{{Name: "events", Producer: events}}, domain.OutboxPolicy{
+		BatchSize: 10,
+		Lease:     time.Minute,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := relay.Relay(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := events.events(); len(got) != 0 {
+		t.Fatalf("published %v after shutdown", got)
+	}
+	message := repo.messages[0]
+	if message.Attempts != 0 || message.NextAttemptAt.After(time.Now()) {
+		t.Fatalf("message claimed until %s with %d attempts, want it released", message.NextAttemptAt, message.Attempts)
+	}
+}
@@ -0,0 +1,223 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/kanta/backend-challenge/internal/core/domain"
+	"github.com/kanta/backend-challenge/internal/core/ports"
+	"go.uber.org/zap"
+)
+
+// validatePassword checks password against the configured policy and returns
+// a *domain.ValidationError listing every rule that failed.
+func (s *service) validatePassword(ctx context.Context, field, password string, user *domain.User) error {
+	var (
+		policy = s.passwordPolicy
+		errs   []domain.FieldError
+	)
+	fail := func(rule, message string) {
+		errs = append(errs, domain.FieldError{Field: field, Rule: rule, Message: message})
+	}
+
+	if utf8.RuneCountInString(password) < policy.MinLength {
+		fail("min_length", fmt.Sprintf("password must be at least %d characters", policy.MinLength))
+	}
+	// bcrypt ignores everything after the 72nd byte, so long passwords
+	// would silently lose entropy if we ever fall back to it.
+	if policy.MaxBytes > 0 && len(password) > policy.MaxBytes {
+		fail("max_length", fmt.Sprintf("password must be at most %d bytes", policy.MaxBytes))
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			symbol = true
+		}
+	}
+	if policy.RequireUpper && !upper {
+		fail("uppercase", "password must contain an uppercase letter")
+	}
+	if policy.RequireLower && !lower {
+		fail("lowercase", "password must contain a lowercase letter")
+	}
+	if policy.RequireDigit && !digit {
+		fail("digit", "password must contain a digit")
+	}
+	if policy.RequireSymbol && !symbol {
+		fail("symbol", "password must contain a symbol")
+	}
+
+	if policy.RejectPersonal && user != nil && containsPersonalInfo(password, user) {
+		fail("personal_info", "password must not contain your name or email")
+	}
+
+	if policy.CheckBreached && len(errs) == 0 {
+		breached, err := s.breached.IsBreached(ctx, password)
+		if err != nil {
+			zap.L().Warn("failed to check breached passwords", zap.Error(err))
+		} else if breached {
+			fail("breached", "password has appeared in a data breach, choose a different one")
+		}
+	}
+
+	if len(errs) > 0 {
+		return &domain.ValidationError{Errors: errs}
+	}
+	return nil
+}
+
+func containsPersonalInfo(password string, user *domain.User) bool {
+	password = strings.ToLower(password)
+
+	candidates := strings.Fields(strings.ToLower(user.Name))
+	if local, _, ok := strings.Cut(strings.ToLower(user.Email), "@"); ok {
+		candidates = append(candidates, local)
+	}
+
+	for _, candidate := range candidates {
+		// Very short fragments would reject too many unrelated passwords.
+		if len(candidate) >= 3 && strings.Contains(password, candidate) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *service) ChangePassword(ctx context.Context, userID, currentPassword, newPassword string) error {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return err
+	}
+
+	if ok, _ := s.hasher.Verify(user.Password, currentPassword); !ok {
+		return domain.ErrInvalidCredentials
+	}
+
+	if err := s.validatePassword(ctx, "new_password", newPassword, user); err != nil {
+		return err
+	}
+	return s.setPassword(ctx, newPassword, user, "change")
+}
+
+// RequestPasswordReset creates a single-use reset token and mails it to the
+// user. It succeeds for unknown emails too so that callers cannot probe which
+// accounts exist.
+func (s *service) RequestPasswordReset(ctx context.Context, email string) error {
+	user, err := s.userRepo.FindOne(map[string]interface{}{"email": email})
+	if err != nil {
+		return nil
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return err
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+
+	ttl := s.passwordPolicy.ResetTokenTTL
+	if err := s.cache.SetToken(ctx, resetTokenKey(token), user.ID, ttl); err != nil {
+		return err
+	}
+
+	expiresAt := time.Now().Add(ttl).UTC()
+	if err := s.mailer.SendPasswordReset(ctx, user.Email, token, expiresAt); err != nil {
+		// Reporting this would tell the caller the account exists.
+		zap.L().Error("failed to send password reset email", zap.String("user_id", user.ID), zap.Error(err))
+		return nil
+	}
+
+	s.publish(ctx, domain.NewEvent(domain.PasswordResetRequested{
+		UserID:    user.ID,
+		ExpiresAt: expiresAt,
+	}))
+	return nil
+}
+
+func (s *service) ResetPassword(ctx context.Context, token, newPassword string) error {
+	key := resetTokenKey(token)
+	userID, err := s.cache.GetToken(ctx, key)
+	if err != nil {
+		return domain.ErrInvalidResetToken
+	}
+
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return domain.ErrInvalidResetToken
+	}
+
+	// Validate before using up the token, so a rejected password can be
+	// retried with the same link.
+	if err := s.validatePassword(ctx, "new_password", newPassword, user); err != nil {
+		return err
+	}
+	// Of two concurrent resets with the same token only one takes it.
+	if taken, err := s.cache.TakeToken(ctx, key); err != nil || taken != userID {
+		return domain.ErrInvalidResetToken
+	}
+
+	if err := s.setPassword(ctx, newPassword, user, "reset"); err != nil {
+		return err
+	}
+	// Proving control of the mailbox is enough to lift a lockout.
+	if err := s.attempts.Unlock(ctx, accountKey(user.Email)); err != nil {
+		zap.L().Warn("failed to unlock account after reset", zap.Error(err))
+	}
+	return nil
+}
+
+// setPassword stores the new, already validated password and signs the user
+// out everywhere in one transaction. reason is "change" or "reset".
+func (s *service) setPassword(ctx context.Context, password string, user *domain.User, reason string) error {
+	hashed, err := s.hasher.Hash(password)
+	if err != nil {
+		return err
+	}
+
+	var version int
+	err = s.tx.WithinTransaction(func(repos ports.Repositories) error {
+		if err := repos.Users.UpdatePassword(user.ID, hashed); err != nil {
+			return err
+		}
+		if err := repos.Outbox.Append(domain.NewEvent(domain.PasswordChanged{
+			UserID: user.ID,
+			Reason: reason,
+		})); err != nil {
+			return err
+		}
+		version, err = revokeSessions(repos, user.ID, "password_"+reason)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	s.tokenVersionBumped(ctx, user.ID, version)
+	return nil
+}
+
+// resetTokenKey stores only a hash of the reset token in the cache.
+func resetTokenKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return "reset:" + hex.EncodeToString(sum[:])
+}
+
+func (s *service) publish(ctx context.Context, event domain.Event) {
+	if err := s.producer.Publish(ctx, event); err != nil {
+		zap.L().Warn("failed to publish event", zap.String("type", event.Type), zap.Error(err))
+	}
+}
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/kanta/backend-challenge/internal/core/domain"
+	"github.com/kanta/backend-challenge/internal/core/ports"
+	"go.uber.org/zap"
+)
+
+type riskEngine struct {
+	geo        ports.GeoIPLookup
+	reputation ports.IPReputation
+	history    ports.LoginHistoryRepository
+	policy     domain.RiskPolicy
+}
+
+func NewRiskEngine(geo ports.GeoIPLookup, reputation ports.IPReputation, history ports.LoginHistoryRepository, policy domain.RiskPolicy) ports.RiskEngine {
+	return &riskEngine{
+		geo:        geo,
+		reputation: reputation,
+		history:    history,
+		policy:     policy,
+	}
+}
+
+func (e *riskEngine) Assess(ctx context.Context, user *domain.User, client domain.ClientInfo) *domain.RiskAssessment {
+	assessment := &domain.RiskAssessment{ChallengePassed: client.ChallengePassed}
+
+	location, err := e.geo.Lookup(client.IP)
+	if err != nil {
+		zap.L().Warn("failed to look up login location", zap.String("ip", client.IP), zap.Error(err))
+	}
+	assessment.Location = location
+
+	for _, hit := range e.reputation.Lookup(client.IP) {
+		assessment.AddSignal(hit.Score, domain.RiskSignalReputation+":"+hit.List)
+	}
+
+	// Before the account is looked up there is no history, and a user
+	// without history has no usual places to depart from.
+	if user == nil {
+		assessment.Decision = e.policy.Decide(assessment.Score)
+		return assessment
+	}
+	history, err := e.history.Recent(user.ID, e.policy.HistorySize)
+	if err != nil {
+		zap.L().Warn("failed to load login history", zap.String("user_id", user.ID), zap.Error(err))
+	}
+	if len(history) > 0 {
+		e.scoreHistory(assessment, history)
+	}
+
+	assessment.Decision = e.policy.Decide(assessment.Score)
+	return assessment
+}
+
+func (e *riskEngine) scoreHistory(assessment *domain.RiskAssessment, history []*domain.LoginRecord) {
+	location := assessment.Location
+
+	// Only compare against history that has the field at all, e.g. not
+	// logins from before a database was configured.
+	var knownCountry, seenCountry, knownASN, seenASN bool
+	for _, record := range history {
+		if record.Location.Country != "" {
+			knownCountry = true
+			seenCountry = seenCountry || record.Location.Country == location.Country
+		}
+		if record.Location.ASN != 0 {
+			knownASN = true
+			seenASN = seenASN || record.Location.ASN == location.ASN
+		}
+	}
+	if location.Country != "" && knownCountry && !seenCountry {
+		assessment.AddSignal(e.policy.NewCountryScore, domain.RiskSignalNewCountry)
+	}
+	if location.ASN != 0 && knownASN && !seenASN {
+		assessment.AddSignal(e.policy.NewASNScore, domain.RiskSignalNewASN)
+	}
+
+	if !location.HasCoordinates || e.policy.ImpossibleTravelKmh <= 0 {
+		return
+	}
+	for _, record := range history {
+		if !record.Location.HasCoordinates {
+			continue
+		}
+		km := distanceKm(record.Location, location)
+		hours := time.Since(record.OccurredAt).Hours()
+		if km >= e.policy.ImpossibleTravelMinKm && (hours <= 0 || km/hours > e.policy.ImpossibleTravelKmh) {
+			assessment.AddSignal(e.policy.ImpossibleTravelScore, domain.RiskSignalImpossibleTravel)
+		}
+		// Only the latest located login tells where the user was last.
+		return
+	}
+}
+
+func (e *riskEngine) RecordLogin(ctx context.Context, user *domain.User, client domain.ClientInfo, assessment *domain.RiskAssessment) {
+	err := e.history.Record(&domain.LoginRecord{
+		UserID:     user.ID,
+		IP:         client.IP,
+		Location:   assessment.Location,
+		Score:      assessment.Score,
+		Decision:   assessment.Decision,
+		OccurredAt: time.Now().UTC(),
+	})
+	if err != nil {
+		zap.L().Warn("failed to record login history", zap.String("user_id", user.ID), zap.Error(err))
+	}
+}
+
+// distanceKm is the great-circle distance between a and b.
+func distanceKm(a, b domain.GeoLocation) float64 {
+	const earthRadiusKm = 6371.0
+	lat1, lat2 := a.Latitude*math.Pi/180, b.Latitude*math.Pi/180
+	dLat := lat2 - lat1
+	dLon := (b.Longitude - a.Longitude) * math.Pi / 180
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(h))
+}
@@ -1,57 +1,223 @@
 package services
 
 import (
-	"errors"
+	"context"
 	"time"
 
 	"github.com/kanta/backend-challenge/internal/core/domain"
 	"github.com/kanta/backend-challenge/internal/core/ports"
-	"golang.org/x/crypto/bcrypt"
+	"go.uber.org/zap"
 )
 
 type service struct {
-	userRepo ports.UserRepository
+	userRepo       ports.UserRepository
+	tx             ports.Transactor
+	deviceRepo     ports.DeviceRepository
+	cache          ports.CachePort
+	revocations    ports.RevocationBus
+	attempts       ports.LoginAttemptPort
+	producer       ports.EventProducer
+	mailer         ports.Mailer
+	breached       ports.BreachedPasswordChecker
+	hasher         ports.PasswordHasher
+	risk           ports.RiskEngine
+	lockout        domain.LockoutPolicy
+	passwordPolicy domain.PasswordPolicy
+	// dummyHash is verified against when the email is unknown so those
+	// attempts take as long as real ones.
+	dummyHash string
 }
 
 func NewBackEndService(
 	userRepo ports.UserRepository,
+	tx ports.Transactor,
+	deviceRepo ports.DeviceRepository,
+	cache ports.CachePort,
+	revocations ports.RevocationBus,
+	attempts ports.LoginAttemptPort,
+	producer ports.EventProducer,
+	mailer ports.Mailer,
+	breached ports.BreachedPasswordChecker,
+	hasher ports.PasswordHasher,
+	risk ports.RiskEngine,
+	lockout domain.LockoutPolicy,
+	passwordPolicy domain.PasswordPolicy,
 ) ports.Service {
+	dummyHash, err := hasher.Hash("dummy-password")
+	if err != nil {
+		zap.L().Fatal("failed to create dummy password hash", zap.Error(err))
+	}
+
 	return &service{
-		userRepo,
+		userRepo:       userRepo,
+		tx:             tx,
+		deviceRepo:     deviceRepo,
+		cache:          cache,
+		revocations:    revocations,
+		attempts:       attempts,
+		producer:       producer,
+		mailer:         mailer,
+		breached:       breached,
+		hasher:         hasher,
+		risk:           risk,
+		lockout:        lockout,
+		passwordPolicy: passwordPolicy,
+		dummyHash:      dummyHash,
 	}
 }
 
-func (s *service) Register(name, email, password string) error {
-	hashed, _ := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+func (s *service) Register(ctx context.Context, name, email, password string) error {
+	if err := s.validatePassword(ctx, "password", password, &domain.User{Name: name, Email: email}); err != nil {
+		return err
+	}
+
+	hashed, err := s.hasher.Hash(password)
+	if err != nil {
+		return err
+	}
 	user := &domain.User{
 		Name:     name,
 		Email:    email,
-		Password: string(hashed),
+		Password: hashed,
 	}
-	return s.userRepo.Create(user)
+	return s.tx.WithinTransaction(func(repos ports.Repositories) error {
+		if err := repos.Users.Create(user); err != nil {
+			return err
+		}
+		return repos.Outbox.Append(domain.NewEvent(domain.UserRegistered{
+			UserID: user.ID,
+			Name:   user.Name,
+			Email:  user.Email,
+		}))
+	})
 }
 
-func (s *service) Authenticate(email, password string) (*domain.User, error) {
+// Authenticate scores the risk of the login and then checks the
+// credentials. The risk decision is taken from the account and client alone,
+// so that neither a challenge nor a block tells whether the password was
+// right: a challenge is asked for before the password is checked, and a
+// blocked login fails exactly like a wrong password. The assessment is
+// returned, also with the error, once the account is known.
+func (s *service) Authenticate(ctx context.Context, email, password string, client domain.ClientInfo) (*domain.User, *domain.RiskAssessment, error) {
+	if err := s.checkLockout(ctx, email, client.IP); err != nil {
+		return nil, nil, err
+	}
+
+	// The challenge is decided before the account is looked up, from the
+	// client alone, so that a 428 does not tell whether the email exists.
+	assessment := s.risk.Assess(ctx, nil, client)
+	if assessment.Decision == domain.RiskChallenge && !client.ChallengePassed {
+		return nil, assessment, domain.ErrChallengeRequired
+	}
+
 	filter := map[string]interface{}{"email": email}
 	user, err := s.userRepo.FindOne(filter)
 	if err != nil {
-		return nil, errors.New("invalid credentials")
+		s.hasher.Verify(s.dummyHash, password)
+		return nil, assessment, s.recordFailure(ctx, email, client.IP)
+	}
+
+	if ok, err := s.hasher.Verify(user.Password, password); !ok {
+		if err != nil {
+			zap.L().Warn("failed to verify password hash", zap.String("user_id", user.ID), zap.Error(err))
+		}
+		return user, assessment, s.recordFailure(ctx, email, client.IP)
+	}
+
+	// The user's history only counts once the password is known to be
+	// right. It can alert or block, but not ask for a challenge, which
+	// would confirm the password.
+	assessment = s.risk.Assess(ctx, user, client)
+	if assessment.Decision == domain.RiskChallenge && !client.ChallengePassed {
+		assessment.Decision = domain.RiskAlert
+	}
+	if assessment.Decision != domain.RiskAllow {
+		s.publishSuspiciousLogin(ctx, user, client, assessment)
+	}
+	if assessment.Decision == domain.RiskBlock {
+		return user, assessment, domain.ErrLoginBlocked
+	}
+
+	s.recordSuccess(ctx, email)
+	s.rehashIfNeeded(user, password)
+	s.trackDevice(ctx, user, client)
+	s.publish(ctx, domain.NewEvent(domain.UserLoggedIn{
+		UserID:    user.ID,
+		Email:     user.Email,
+		IP:        client.IP,
+		UserAgent: client.UserAgent,
+		DeviceID:  client.DeviceID,
+	}))
+	s.risk.RecordLogin(ctx, user, client, assessment)
+	return user, assessment, nil
+}
+
+// publishSuspiciousLogin reports a risky login with valid credentials, once
+// it is known whether it gets through.
+func (s *service) publishSuspiciousLogin(ctx context.Context, user *domain.User, client domain.ClientInfo, assessment *domain.RiskAssessment) {
+	s.publish(ctx, domain.NewEvent(domain.SuspiciousLogin{
+		UserID:          user.ID,
+		Email:           user.Email,
+		IP:              client.IP,
+		Country:         assessment.Location.Country,
+		ASN:             assessment.Location.ASN,
+		Score:           assessment.Score,
+		Decision:        assessment.Decision,
+		Signals:         assessment.Signals,
+		ChallengePassed: assessment.ChallengePassed,
+	}))
+}
+
+// Reauthenticate re-checks the password of an already signed in user before
+// a sensitive operation. Failures count towards the login lockout.
+func (s *service) Reauthenticate(ctx context.Context, userID, password string, client domain.ClientInfo) (*domain.User, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.checkLockout(ctx, user.Email, client.IP); err != nil {
+		return nil, err
 	}
 
-	if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)) != nil {
-		return nil, errors.New("invalid credentials")
+	if ok, _ := s.hasher.Verify(user.Password, password); !ok {
+		return nil, s.recordFailure(ctx, user.Email, client.IP)
 	}
 
+	s.recordSuccess(ctx, user.Email)
 	return user, nil
 }
 
 func (s *service) CreateUser(user *domain.User) error {
 	user.CreatedAt = time.Now()
-	hashed, _ := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
-	user.Password = string(hashed)
+	hashed, err := s.hasher.Hash(user.Password)
+	if err != nil {
+		return err
+	}
+	user.Password = hashed
 	return s.userRepo.Create(user)
 }
 
 func (s *service) GetUserByID(id string) (*domain.User, error) {
 	return s.userRepo.FindByID(id)
 }
+
+// rehashIfNeeded upgrades a stored hash to the current algorithm and
+// parameters once the plaintext is known. Failures only cost us the upgrade,
+// so they are logged rather than failing the login.
+func (s *service) rehashIfNeeded(user *domain.User, password string) {
+	if !s.hasher.NeedsRehash(user.Password) {
+		return
+	}
+
+	hashed, err := s.hasher.Hash(password)
+	if err != nil {
+		zap.L().Warn("failed to rehash password", zap.String("user_id", user.ID), zap.Error(err))
+		return
+	}
+	if err := s.userRepo.UpdatePassword(user.ID, hashed); err != nil {
+		zap.L().Warn("failed to store rehashed password", zap.String("user_id", user.ID), zap.Error(err))
+		return
+	}
+	user.Password = hashed
+}
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/kanta/backend-challenge/internal/core/domain"
+	"github.com/kanta/backend-challenge/internal/core/ports"
+	"go.uber.org/zap"
+)
+
+// tokenVersionTTL bounds how long a cached token version may lag behind the
+// database if updating the cache after a bump fails.
+const tokenVersionTTL = 10 * time.Minute
+
+func tokenVersionKey(userID string) string {
+	return "token_version:" + userID
+}
+
+// TokenVersion returns the user's current token version, read through the
+// cache.
+func (s *service) TokenVersion(ctx context.Context, userID string) (int, error) {
+	if cached, err := s.cache.GetToken(ctx, tokenVersionKey(userID)); err == nil {
+		if version, err := strconv.Atoi(cached); err == nil {
+			return version, nil
+		}
+	}
+
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return 0, err
+	}
+	s.cacheTokenVersion(ctx, userID, user.TokenVersion)
+	return user.TokenVersion, nil
+}
+
+// RevokeAllSessions signs the user out everywhere by bumping their token
+// version, which invalidates every token issued before.
+func (s *service) RevokeAllSessions(ctx context.Context, userID, reason string) error {
+	var version int
+	err := s.tx.WithinTransaction(func(repos ports.Repositories) error {
+		var err error
+		version, err = revokeSessions(repos, userID, reason)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	s.tokenVersionBumped(ctx, userID, version)
+	return nil
+}
+
+// revokeSessions bumps the token version within a transaction and returns
+// the new version.
+func revokeSessions(repos ports.Repositories, userID, reason string) (int, error) {
+	version, err := repos.Users.IncrementTokenVersion(userID)
+	if err != nil {
+		return 0, err
+	}
+	return version, repos.Outbox.Append(domain.NewEvent(domain.SessionsRevoked{
+		UserID:       userID,
+		Reason:       reason,
+		TokenVersion: version,
+	}))
+}
+
+// RecordLogout publishes the end of a single session whose tokens the caller
+// has already revoked.
+func (s *service) RecordLogout(ctx context.Context, userID, tokenID string) {
+	s.publish(ctx, domain.NewEvent(domain.SessionRevoked{
+		UserID:  userID,
+		TokenID: tokenID,
+		Reason:  "logout",
+	}))
+}
+
+// tokenVersionBumped caches the new version and tells every instance to drop
+// the user's cached token validations, which were checked against the old
+// one.
+func (s *service) tokenVersionBumped(ctx context.Context, userID string, version int) {
+	s.cacheTokenVersion(ctx, userID, version)
+	if err := s.revocations.PublishRevocation(ctx, userID); err != nil {
+		zap.L().Warn("failed to publish token revocation", zap.String("user_id", userID), zap.Error(err))
+	}
+}
+
+func (s *service) cacheTokenVersion(ctx context.Context, userID string, version int) {
+	if err := s.cache.SetToken(ctx, tokenVersionKey(userID), strconv.Itoa(version), tokenVersionTTL); err != nil {
+		zap.L().Warn("failed to cache token version", zap.String("user_id", userID), zap.Error(err))
+	}
+}
@@ -0,0 +1,335 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/kanta/backend-challenge/internal/core/domain"
+	"github.com/kanta/backend-challenge/internal/core/ports"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultDeliveryPageSize = 50
+	maxDeliveryPageSize     = 500
+	// maxWebhookError bounds the error text kept in the delivery log.
+	maxWebhookError = 500
+)
+
+type webhookService struct {
+	repo   ports.WebhookRepository
+	sender ports.WebhookSender
+	policy domain.WebhookPolicy
+}
+
+func NewWebhookService(repo ports.WebhookRepository, sender ports.WebhookSender, policy domain.WebhookPolicy) ports.WebhookService {
+	return &webhookService{
+		repo:   repo,
+		sender: sender,
+		policy: policy,
+	}
+}
+
+func (s *webhookService) Publish(ctx context.Context, event domain.Event) error {
+	if !slices.Contains(domain.WebhookEventTypes, event.Type) {
+		return nil
+	}
+	subscriptions, err := s.repo.ListSubscriptions()
+	if err != nil {
+		return err
+	}
+
+	// The body is the event envelope, as on every other producer.
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	var deliveries []*domain.WebhookDelivery
+	for _, subscription := range subscriptions {
+		if !subscription.Subscribes(event.Type) {
+			continue
+		}
+		deliveries = append(deliveries, &domain.WebhookDelivery{
+			SubscriptionID: subscription.ID,
+			EventID:        event.ID,
+			EventType:      event.Type,
+			Payload:        payload,
+			Status:         domain.DeliveryPending,
+			NextAttemptAt:  now,
+			CreatedAt:      now,
+		})
+	}
+	return s.repo.CreateDeliveries(deliveries)
+}
+
+func (s *webhookService) RunDispatcher(ctx context.Context) {
+	ticker := time.NewTicker(s.policy.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		// Keep claiming while full batches come back, so a backlog drains
+		// faster than one batch per tick.
+		for ctx.Err() == nil {
+			n, err := s.dispatch(ctx)
+			if err != nil {
+				zap.L().Error("failed to dispatch webhooks", zap.Error(err))
+				break
+			}
+			if n < s.policy.BatchSize {
+				break
+			}
+		}
+	}
+}
+
+// dispatch sends one batch of due deliveries concurrently. A delivery whose
+// subscription cannot be loaded stays claimed and is retried once its lease
+// runs out; the lookup error is returned after the rest have been sent.
+func (s *webhookService) dispatch(ctx context.Context) (int, error) {
+	// The lease outlives the request timeout, so a delivery is only picked
+	// up again if this instance died while sending it.
+	deliveries, err := s.repo.ClaimDueDeliveries(time.Now().UTC(), 2*s.policy.RequestTimeout, s.policy.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	subscriptions := make(map[string]*domain.WebhookSubscription)
+	var (
+		wg        sync.WaitGroup
+		lookupErr error
+	)
+	for _, delivery := range deliveries {
+		subscription, ok := subscriptions[delivery.SubscriptionID]
+		if !ok {
+			subscription, err = s.repo.FindSubscription(delivery.SubscriptionID)
+			if err != nil && !errors.Is(err, domain.ErrWebhookNotFound) {
+				lookupErr = err
+				continue
+			}
+			subscriptions[delivery.SubscriptionID] = subscription
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.deliver(ctx, subscription, delivery)
+		}()
+	}
+	wg.Wait()
+	return len(deliveries), lookupErr
+}
+
+func (s *webhookService) deliver(ctx context.Context, subscription *domain.WebhookSubscription, delivery *domain.WebhookDelivery) {
+	delivery.Attempts++
+
+	var (
+		status int
+		err    error
+	)
+	if subscription == nil || !subscription.Active {
+		err = errors.New("subscription is inactive")
+	} else {
+		header := http.Header{}
+		header.Set("Content-Type", "application/json")
+		header.Set(domain.WebhookIDHeader, delivery.EventID)
+		header.Set(domain.WebhookEventHeader, delivery.EventType)
+		header.Set(domain.WebhookSignatureHeader, domain.WebhookSignature(subscription.Secret, time.Now(), delivery.Payload))
+
+		status, err = s.sender.Send(ctx, subscription.URL, header, delivery.Payload)
+		if err == nil && (status < 200 || status > 299) {
+			err = fmt.Errorf("receiver responded with status %d", status)
+		}
+	}
+
+	now := time.Now().UTC()
+	delivery.LastStatusCode = status
+	switch {
+	case err == nil:
+		delivery.Status = domain.DeliverySucceeded
+		delivery.LastError = ""
+		delivery.DeliveredAt = &now
+	case delivery.Attempts >= s.policy.MaxAttempts || subscription == nil || !subscription.Active:
+		delivery.Status = domain.DeliveryDead
+		delivery.LastError = truncate(err.Error(), maxWebhookError)
+	default:
+		delivery.LastError = truncate(err.Error(), maxWebhookError)
+		delivery.NextAttemptAt = now.Add(s.backoff(delivery.Attempts))
+	}
+
+	if err := s.repo.UpdateDelivery(delivery); err != nil {
+		zap.L().Error("failed to update webhook delivery", zap.String("delivery_id", delivery.ID), zap.Error(err))
+	}
+}
+
+func (s *webhookService) backoff(attempts int) time.Duration {
+	return retryBackoff(s.policy.BackoffBase, s.policy.BackoffMax, attempts)
+}
+
+func (s *webhookService) CreateSubscription(ctx context.Context, req domain.WebhookSubscriptionRequest) (*domain.WebhookSubscription, error) {
+	if err := s.validate(req.URL, req.EventTypes); err != nil {
+		return nil, err
+	}
+	secret := req.Secret
+	if secret == "" {
+		var err error
+		if secret, err = newWebhookSecret(); err != nil {
+			return nil, err
+		}
+	}
+
+	subscription := &domain.WebhookSubscription{
+		URL:        req.URL,
+		Secret:     secret,
+		EventTypes: req.EventTypes,
+		Active:     req.Active == nil || *req.Active,
+	}
+	if err := s.repo.CreateSubscription(subscription); err != nil {
+		return nil, err
+	}
+	return subscription, nil
+}
+
+func (s *webhookService) UpdateSubscription(ctx context.Context, id string, req domain.WebhookSubscriptionRequest) (*domain.WebhookSubscription, error) {
+	subscription, err := s.repo.FindSubscription(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.URL != "" {
+		subscription.URL = req.URL
+	}
+	if req.EventTypes != nil {
+		subscription.EventTypes = req.EventTypes
+	}
+	if req.Active != nil {
+		subscription.Active = *req.Active
+	}
+	if err := s.validate(subscription.URL, subscription.EventTypes); err != nil {
+		return nil, err
+	}
+	rotated := req.Secret != ""
+	if rotated {
+		subscription.Secret = req.Secret
+	}
+
+	if err := s.repo.UpdateSubscription(subscription); err != nil {
+		return nil, err
+	}
+	if !rotated {
+		subscription.Secret = ""
+	}
+	return subscription, nil
+}
+
+func (s *webhookService) DeleteSubscription(ctx context.Context, id string) error {
+	return s.repo.DeleteSubscription(id)
+}
+
+func (s *webhookService) GetSubscription(ctx context.Context, id string) (*domain.WebhookSubscription, error) {
+	subscription, err := s.repo.FindSubscription(id)
+	if err != nil {
+		return nil, err
+	}
+	subscription.Secret = ""
+	return subscription, nil
+}
+
+func (s *webhookService) ListSubscriptions(ctx context.Context) ([]*domain.WebhookSubscription, error) {
+	subscriptions, err := s.repo.ListSubscriptions()
+	if err != nil {
+		return nil, err
+	}
+	for _, subscription := range subscriptions {
+		subscription.Secret = ""
+	}
+	return subscriptions, nil
+}
+
+func (s *webhookService) ListDeliveries(ctx context.Context, filter domain.WebhookDeliveryFilter) (*domain.WebhookDeliveryPage, error) {
+	if _, err := s.repo.FindSubscription(filter.SubscriptionID); err != nil {
+		return nil, err
+	}
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.PageSize < 1 {
+		filter.PageSize = defaultDeliveryPageSize
+	}
+	filter.PageSize = min(filter.PageSize, maxDeliveryPageSize)
+
+	deliveries, total, err := s.repo.FindDeliveries(filter)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.WebhookDeliveryPage{
+		Deliveries: deliveries,
+		Total:      total,
+		Page:       filter.Page,
+		PageSize:   filter.PageSize,
+	}, nil
+}
+
+func (s *webhookService) Redeliver(ctx context.Context, subscriptionID, deliveryID string) (*domain.WebhookDelivery, error) {
+	delivery, err := s.repo.FindDelivery(subscriptionID, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	if delivery.Status != domain.DeliveryDead {
+		return nil, domain.ErrWebhookNotDead
+	}
+
+	delivery.Status = domain.DeliveryPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = time.Now().UTC()
+	if err := s.repo.UpdateDelivery(delivery); err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+func (s *webhookService) validate(rawURL string, eventTypes []string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" || !(u.Scheme == "https" || (s.policy.AllowHTTP && u.Scheme == "http")) {
+		return domain.ErrInvalidWebhookURL
+	}
+	if len(eventTypes) == 0 {
+		return domain.ErrUnknownWebhookEvent
+	}
+	for _, eventType := range eventTypes {
+		if !slices.Contains(domain.WebhookEventTypes, eventType) {
+			return fmt.Errorf("%w: %s", domain.ErrUnknownWebhookEvent, eventType)
+		}
+	}
+	return nil
+}
+
+func newWebhookSecret() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return "whsec_" + base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
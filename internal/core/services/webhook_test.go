@@ -0,0 +1,279 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kanta/backend-challenge/internal/adapters/webhook"
+	"github.com/kanta/backend-challenge/internal/core/domain"
+	"github.com/kanta/backend-challenge/internal/core/ports"
+)
+
+// memoryWebhookRepository keeps subscriptions and deliveries in memory.
+// Looking up a subscription in failing returns an error.
+type memoryWebhookRepository struct {
+	ports.WebhookRepository
+	mu            sync.Mutex
+	subscriptions map[string]*domain.WebhookSubscription
+	deliveries    []*domain.WebhookDelivery
+	failing       map[string]bool
+}
+
+func (r *memoryWebhookRepository) FindSubscription(id string) (*domain.WebhookSubscription, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.failing[id] {
+		return nil, errors.New("connection reset")
+	}
+	subscription, ok := r.subscriptions[id]
+	if !ok {
+		return nil, domain.ErrWebhookNotFound
+	}
+	copied := *subscription
+	return &copied, nil
+}
+
+func (r *memoryWebhookRepository) ClaimDueDeliveries(now time.Time, lease time.Duration, limit int) ([]*domain.WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var claimed []*domain.WebhookDelivery
+	for _, delivery := range r.deliveries {
+		if len(claimed) == limit {
+			break
+		}
+		if delivery.Status != domain.DeliveryPending || delivery.NextAttemptAt.After(now) {
+			continue
+		}
+		copied := *delivery
+		claimed = append(claimed, &copied)
+	}
+	return claimed, nil
+}
+
+func (r *memoryWebhookRepository) UpdateDelivery(delivery *domain.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, stored := range r.deliveries {
+		if stored.ID == delivery.ID {
+			copied := *delivery
+			r.deliveries[i] = &copied
+			return nil
+		}
+	}
+	return domain.ErrWebhookNotFound
+}
+
+func (r *memoryWebhookRepository) delivery(id string) *domain.WebhookDelivery {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, delivery := range r.deliveries {
+		if delivery.ID == id {
+			return delivery
+		}
+	}
+	return nil
+}
+
+func newTestWebhookService(repo ports.WebhookRepository, maxAttempts int) *webhookService {
+	return &webhookService{
+		repo:   repo,
+		sender: webhook.NewHTTPSender(time.Second, []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8")}),
+		policy: domain.WebhookPolicy{
+			AllowHTTP:      true,
+			MaxAttempts:    maxAttempts,
+			BackoffBase:    time.Minute,
+			BackoffMax:     time.Hour,
+			RequestTimeout: time.Second,
+			BatchSize:      10,
+		},
+	}
+}
+
+func pendingDelivery(id, subscriptionID string) *domain.WebhookDelivery {
+	return &domain.WebhookDelivery{
+		ID:             id,
+		SubscriptionID: subscriptionID,
+		EventID:        "event-" + id,
+		EventType:      domain.EventUserRegistered,
+		Payload:        []byte(`{"id":"event-` + id + `"}`),
+		Status:         domain.DeliveryPending,
+		NextAttemptAt:  time.Now().UTC().Add(-time.Second),
+	}
+}
+
+// verifySignature checks the Webhook-Signature header the way receivers are
+// told to.
+func verifySignature(r *http.Request, secret string, body []byte) bool {
+	header := r.Header.Get(domain.WebhookSignatureHeader)
+	t, _, ok := strings.Cut(strings.TrimPrefix(header, "t="), ",")
+	if !ok {
+		return false
+	}
+	unix, err := strconv.ParseInt(t, 10, 64)
+	if err != nil || time.Since(time.Unix(unix, 0)) > time.Minute {
+		return false
+	}
+	return header == domain.WebhookSignature(secret, time.Unix(unix, 0), body)
+}
+
+func TestWebhookDispatch(t *testing.T) {
+	const secret = "whsec_test"
+
+	tests := []struct {
+		name        string
+		handler     http.HandlerFunc
+		active      bool
+		maxAttempts int
+		wantStatus  string
+		wantCode    int
+		wantRetry   bool
+		wantCalls   int32
+	}{
+		{
+			name: "signed delivery succeeds",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				if !verifySignature(r, secret, body) || r.Header.Get(domain.WebhookIDHeader) != "event-1" {
+					w.WriteHeader(http.StatusUnauthorized)
+				}
+			},
+			active:      true,
+			maxAttempts: 3,
+			wantStatus:  domain.DeliverySucceeded,
+			wantCode:    http.StatusOK,
+			wantCalls:   1,
+		},
+		{
+			name:        "failure is retried later",
+			handler:     func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusServiceUnavailable) },
+			active:      true,
+			maxAttempts: 3,
+			wantStatus:  domain.DeliveryPending,
+			wantCode:    http.StatusServiceUnavailable,
+			wantRetry:   true,
+			wantCalls:   1,
+		},
+		{
+			name:        "last attempt is dead-lettered",
+			handler:     func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusInternalServerError) },
+			active:      true,
+			maxAttempts: 1,
+			wantStatus:  domain.DeliveryDead,
+			wantCode:    http.StatusInternalServerError,
+			wantCalls:   1,
+		},
+		{
+			name:        "redirect is a failure",
+			handler:     func(w http.ResponseWriter, r *http.Request) { http.Redirect(w, r, "/elsewhere", http.StatusFound) },
+			active:      true,
+			maxAttempts: 3,
+			wantStatus:  domain.DeliveryPending,
+			wantCode:    http.StatusFound,
+			wantRetry:   true,
+			wantCalls:   1,
+		},
+		{
+			name:        "inactive subscription is dead-lettered unsent",
+			handler:     func(w http.ResponseWriter, r *http.Request) {},
+			active:      false,
+			maxAttempts: 3,
+			wantStatus:  domain.DeliveryDead,
+			wantCalls:   0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				// Only the subscribed URL may be called; a followed
+				// redirect would show up as a second call.
+				calls.Add(1)
+				tt.handler(w, r)
+			}))
+			defer server.Close()
+
+			repo := &memoryWebhookRepository{
+				subscriptions: map[string]*domain.WebhookSubscription{
+					"sub": {ID: "sub", URL: server.URL, Secret: secret, Active: tt.active},
+				},
+				deliveries: []*domain.WebhookDelivery{pendingDelivery("1", "sub")},
+			}
+			service := newTestWebhookService(repo, tt.maxAttempts)
+
+			before := time.Now().UTC()
+			n, err := service.dispatch(context.Background())
+			if err != nil || n != 1 {
+				t.Fatalf("dispatch() = %d, %v, want 1, nil", n, err)
+			}
+
+			delivery := repo.delivery("1")
+			if delivery.Status != tt.wantStatus {
+				t.Errorf("status = %s, want %s (last error %q)", delivery.Status, tt.wantStatus, delivery.LastError)
+			}
+			if delivery.Attempts != 1 {
+				t.Errorf("attempts = %d, want 1", delivery.Attempts)
+			}
+			if delivery.LastStatusCode != tt.wantCode {
+				t.Errorf("last status code = %d, want %d", delivery.LastStatusCode, tt.wantCode)
+			}
+			if retry := delivery.NextAttemptAt.After(before.Add(time.Minute - time.Second)); retry != tt.wantRetry {
+				t.Errorf("next attempt at %s, want retry %v", delivery.NextAttemptAt, tt.wantRetry)
+			}
+			if n := calls.Load(); n != tt.wantCalls {
+				t.Errorf("receiver got %d requests, want %d", n, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestWebhookDispatchContinuesAfterLookupError(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	}))
+	defer server.Close()
+
+	repo := &memoryWebhookRepository{
+		subscriptions: map[string]*domain.WebhookSubscription{
+			"ok":     {ID: "ok", URL: server.URL, Secret: "a", Active: true},
+			"broken": {ID: "broken", URL: server.URL, Secret: "b", Active: true},
+		},
+		deliveries: []*domain.WebhookDelivery{
+			pendingDelivery("1", "broken"),
+			pendingDelivery("2", "ok"),
+			pendingDelivery("3", "ok"),
+		},
+		failing: map[string]bool{"broken": true},
+	}
+	service := newTestWebhookService(repo, 3)
+
+	n, err := service.dispatch(context.Background())
+	if err == nil {
+		t.Fatal("dispatch() returned no error for the failed lookup")
+	}
+	if n != 3 {
+		t.Fatalf("dispatch() = %d, want 3", n)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Fatalf("receiver got %d requests, want 2", got)
+	}
+	for id, want := range map[string]string{"1": domain.DeliveryPending, "2": domain.DeliverySucceeded, "3": domain.DeliverySucceeded} {
+		if got := repo.delivery(id).Status; got != want {
+			t.Errorf("delivery %s status = %s, want %s", id, got, want)
+		}
+	}
+	if attempts := repo.delivery("1").Attempts; attempts != 0 {
+		t.Errorf("unsent delivery has %d attempts, want 0", attempts)
+	}
+}
@@ -1,16 +1,30 @@
 package middlewares
 
 import (
+	"fmt"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/kanta/backend-challenge/infrastructure"
-	"github.com/kanta/backend-challenge/internal/core/ports"
+	"github.com/kanta/backend-challenge/internal/core/domain"
 )
 
-func JWTAuth(secret string, cache ports.CachePort) fiber.Handler {
+// JWTAuth authenticates the request with the bearer or DPoP token from the
+// Authorization header or, in cookie mode, from the access token cookie.
+// DPoP-bound tokens additionally need a valid DPoP proof header.
+func JWTAuth(tokens *infrastructure.TokenManager, cookies CookieConfig, dpop *infrastructure.DPoPVerifier) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		auth := c.Get("Authorization")
+		scheme := ""
+		if auth == "" && cookies.Enabled {
+			if cookie := c.Cookies(cookies.AccessName); cookie != "" {
+				auth = "Bearer " + cookie
+			}
+		} else {
+			scheme, _, _ = strings.Cut(auth, " ")
+		}
 		if auth == "" {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 				"error": "missing authorization header",
@@ -18,7 +32,7 @@ func JWTAuth(secret string, cache ports.CachePort) fiber.Handler {
 		}
 
 		parts := strings.Split(auth, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
+		if len(parts) != 2 || (parts[0] != "Bearer" && parts[0] != DPoPHeader) {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 				"error": "invalid authorization format",
 			})
@@ -26,23 +40,31 @@ func JWTAuth(secret string, cache ports.CachePort) fiber.Handler {
 
 		token := parts[1]
 
-		userID, err := infrastructure.ValidateAccessTokenWithCache(c.Context(), token, secret, cache)
+		claims, err := tokens.ValidateAccessToken(c.Context(), token)
 		if err != nil {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 				"error": "invalid or expired token",
 			})
 		}
 
-		claims, _ := infrastructure.ParseToken(token, secret)
+		if err := verifyDPoP(c, dpop, scheme, token, claims); err != nil {
+			return dpopUnauthorized(c, dpop, err)
+		}
 
-		c.Locals("user_id", userID)
+		if err := verifyCertificateBinding(c, claims); err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		c.Locals("user_id", claims.UserID)
 		c.Locals("claims", claims)
 
 		return c.Next()
 	}
 }
 
-func RefreshTokenAuth(secret string, cache ports.CachePort) fiber.Handler {
+func RefreshTokenAuth(tokens *infrastructure.TokenManager) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		auth := c.Get("Authorization")
 		if auth == "" {
@@ -60,44 +82,116 @@ func RefreshTokenAuth(secret string, cache ports.CachePort) fiber.Handler {
 
 		token := parts[1]
 
-		userID, err := infrastructure.ValidateRefreshTokenWithCache(c.Context(), token, secret, cache)
+		claims, err := tokens.ValidateRefreshToken(c.Context(), token)
 		if err != nil {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 				"error": "invalid or expired refresh token",
 			})
 		}
 
-		claims, _ := infrastructure.ParseToken(token, secret)
-
-		c.Locals("user_id", userID)
+		c.Locals("user_id", claims.UserID)
 		c.Locals("claims", claims)
 
 		return c.Next()
 	}
 }
 
-func OptionalAuth(secret string, cache ports.CachePort) fiber.Handler {
+func OptionalAuth(tokens *infrastructure.TokenManager, cookies CookieConfig, dpop *infrastructure.DPoPVerifier) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		auth := c.Get("Authorization")
+		scheme := ""
+		if auth == "" && cookies.Enabled {
+			if cookie := c.Cookies(cookies.AccessName); cookie != "" {
+				auth = "Bearer " + cookie
+			}
+		} else {
+			scheme, _, _ = strings.Cut(auth, " ")
+		}
 
 		if auth == "" {
 			return c.Next()
 		}
 
 		parts := strings.Split(auth, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
+		if len(parts) != 2 || (parts[0] != "Bearer" && parts[0] != DPoPHeader) {
 			return c.Next()
 		}
 
 		token := parts[1]
 
-		userID, err := infrastructure.ValidateAccessTokenWithCache(c.Context(), token, secret, cache)
+		claims, err := tokens.ValidateAccessToken(c.Context(), token)
 		if err == nil {
-			claims, _ := infrastructure.ParseToken(token, secret)
-			c.Locals("user_id", userID)
+			err = verifyDPoP(c, dpop, scheme, token, claims)
+		}
+		if err == nil {
+			err = verifyCertificateBinding(c, claims)
+		}
+		if err == nil {
+			c.Locals("user_id", claims.UserID)
 			c.Locals("claims", claims)
 		}
 
 		return c.Next()
 	}
 }
+
+// RequireRole must run after JWTAuth. It rejects requests whose token does not
+// carry one of the given roles.
+func RequireRole(roles ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		claims, ok := c.Locals("claims").(*domain.Claims)
+		if !ok || claims == nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "unauthorized",
+			})
+		}
+
+		for _, role := range roles {
+			if claims.Role == role {
+				return c.Next()
+			}
+		}
+
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "forbidden",
+		})
+	}
+}
+
+// RequireRecentAuth must run after JWTAuth. It rejects tokens whose user last
+// authenticated more than maxAge ago or, when methods are given, without any
+// of those methods. Clients recover by calling POST /auth/reauthenticate.
+func RequireRecentAuth(maxAge time.Duration, methods ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		claims, ok := c.Locals("claims").(*domain.Claims)
+		if !ok || claims == nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "unauthorized",
+			})
+		}
+
+		if claims.AuthTime == nil || time.Since(claims.AuthTime.Time) > maxAge || !hasAnyMethod(claims.AMR, methods) {
+			// RFC 9470 step-up challenge.
+			c.Set(fiber.HeaderWWWAuthenticate, fmt.Sprintf(
+				`Bearer error="insufficient_user_authentication", error_description="%s", max_age=%d`,
+				domain.ErrReauthRequired.Error(), int(maxAge.Seconds())))
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": domain.ErrReauthRequired.Error(),
+			})
+		}
+
+		return c.Next()
+	}
+}
+
+func hasAnyMethod(amr, methods []string) bool {
+	if len(methods) == 0 {
+		return true
+	}
+	for _, method := range methods {
+		if slices.Contains(amr, method) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,104 @@
+package middlewares
+
+import (
+	"errors"
+	"net/http"
+	"net/netip"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/kanta/backend-challenge/infrastructure"
+	"github.com/kanta/backend-challenge/internal/core/domain"
+	"github.com/kanta/backend-challenge/internal/core/ports"
+	"github.com/kanta/backend-challenge/middlewares/meta"
+	"go.uber.org/zap"
+)
+
+// ChallengeResponseHeader carries the solution to the challenge sent with a
+// 428 response.
+const ChallengeResponseHeader = "X-Challenge-Response"
+
+type ChallengeConfig struct {
+	// TrustedNetworks are never challenged, e.g. internal networks.
+	TrustedNetworks []netip.Prefix
+	// Services are never challenged when they present a client
+	// certificate that identifies them.
+	Services *infrastructure.ServiceIdentities
+	// VerifyOnly checks solutions that are sent but never asks for one,
+	// leaving that to the login risk check.
+	VerifyOnly bool
+}
+
+func (cfg ChallengeConfig) trusted(c *fiber.Ctx) bool {
+	if addr, err := netip.ParseAddr(c.IP()); err == nil {
+		addr = addr.Unmap()
+		for _, network := range cfg.TrustedNetworks {
+			if network.Contains(addr) {
+				return true
+			}
+		}
+	}
+	if cfg.Services != nil {
+		if cert := ClientCertificate(c); cert != nil {
+			if _, err := cfg.Services.Identify(cert); err == nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// RequireChallenge makes risky requests solve a challenge first. Requests
+// that must and did not send a valid solution get a 428 response with a new
+// challenge in its details. A valid solution is always checked and noted in
+// the "challenge_passed" local, so that handlers can demand one as well.
+// Risk checks fail open; verification failures other than a wrong solution
+// fail closed.
+func RequireChallenge(challenges ports.ChallengeService, cfg ChallengeConfig) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if cfg.trusted(c) {
+			c.Locals("challenge_passed", true)
+			return c.Next()
+		}
+
+		ctx := c.Context()
+		client := domain.ClientInfo{IP: c.IP(), UserAgent: c.Get(fiber.HeaderUserAgent)}
+		if solution := c.Get(ChallengeResponseHeader); solution != "" {
+			err := challenges.Verify(ctx, solution, client)
+			if err == nil {
+				c.Locals("challenge_passed", true)
+				return c.Next()
+			}
+			if !errors.Is(err, domain.ErrChallengeFailed) {
+				zap.L().Error("failed to verify challenge", zap.Error(err))
+				return c.Status(http.StatusServiceUnavailable).JSON(meta.NewMetaError(http.StatusServiceUnavailable, "service temporarily unavailable",
+					meta.WithMetaErrorOptionsHttpStatus(http.StatusServiceUnavailable)))
+			}
+		}
+
+		if cfg.VerifyOnly {
+			return c.Next()
+		}
+		required, err := challenges.Required(ctx, client.IP)
+		if err != nil {
+			zap.L().Warn("challenge risk check unavailable", zap.Error(err))
+			return c.Next()
+		}
+		if !required {
+			return c.Next()
+		}
+		return RespondWithChallenge(c, challenges, client)
+	}
+}
+
+// RespondWithChallenge sends a 428 response with a new challenge for client.
+func RespondWithChallenge(c *fiber.Ctx, challenges ports.ChallengeService, client domain.ClientInfo) error {
+	challenge, err := challenges.Issue(c.Context(), client)
+	if err != nil {
+		zap.L().Error("failed to issue challenge", zap.Error(err))
+		return c.Status(http.StatusInternalServerError).JSON(meta.NewMetaError(http.StatusInternalServerError, "failed to issue challenge",
+			meta.WithMetaErrorOptionsHttpStatus(http.StatusInternalServerError)))
+	}
+	return c.Status(http.StatusPreconditionRequired).JSON(meta.NewMetaError(http.StatusPreconditionRequired, domain.ErrChallengeRequired.Error(),
+		meta.WithMetaErrorOptionsHttpStatus(http.StatusPreconditionRequired),
+		meta.WithMetaErrorOptionsDetails(challenge)))
+}
@@ -0,0 +1,125 @@
+package middlewares
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/kanta/backend-challenge/internal/core/domain"
+)
+
+// CookieConfig controls the browser cookie session mode, where tokens are
+// kept in HttpOnly cookies instead of being handed to JavaScript.
+type CookieConfig struct {
+	Enabled bool
+	Domain  string
+	Path    string
+	// RefreshPath limits where the browser sends the refresh cookie.
+	RefreshPath string
+	Secure      bool
+	SameSite    string
+
+	AccessName  string
+	RefreshName string
+	CSRFName    string
+	CSRFHeader  string
+}
+
+// UsesCookies reports whether tokens for policy are delivered as cookies.
+// Only browser clients use cookie mode.
+func (cfg CookieConfig) UsesCookies(policy *domain.TokenPolicy) bool {
+	return cfg.Enabled && policy != nil && policy.ClientType == domain.ClientTypeWeb
+}
+
+// SetAuthCookies stores the token pair in HttpOnly cookies and issues a fresh
+// CSRF token readable by JavaScript for the double-submit check.
+func SetAuthCookies(c *fiber.Ctx, cfg CookieConfig, pair *domain.TokenPair, policy *domain.TokenPolicy) error {
+	SetAccessCookie(c, cfg, pair.AccessToken)
+
+	if pair.RefreshToken != "" {
+		c.Cookie(cfg.cookie(cfg.RefreshName, pair.RefreshToken, cfg.RefreshPath, policy.AbsoluteTimeout, true))
+	}
+
+	csrfToken, err := newCSRFToken()
+	if err != nil {
+		return err
+	}
+	c.Cookie(cfg.cookie(cfg.CSRFName, csrfToken, cfg.Path, policy.AbsoluteTimeout, false))
+	return nil
+}
+
+// SetAccessCookie stores the access token in a session cookie. The token's
+// own expiry limits its lifetime; the persistent refresh cookie restores it
+// after the browser restarts.
+func SetAccessCookie(c *fiber.Ctx, cfg CookieConfig, accessToken string) {
+	c.Cookie(cfg.cookie(cfg.AccessName, accessToken, cfg.Path, 0, true))
+}
+
+func ClearAuthCookies(c *fiber.Ctx, cfg CookieConfig) {
+	c.Cookie(cfg.cookie(cfg.AccessName, "", cfg.Path, -1, true))
+	c.Cookie(cfg.cookie(cfg.RefreshName, "", cfg.RefreshPath, -1, true))
+	c.Cookie(cfg.cookie(cfg.CSRFName, "", cfg.Path, -1, false))
+}
+
+func (cfg CookieConfig) cookie(name, value, path string, maxAge time.Duration, httpOnly bool) *fiber.Cookie {
+	cookie := &fiber.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		Domain:   cfg.Domain,
+		Secure:   cfg.Secure,
+		HTTPOnly: httpOnly,
+		SameSite: cfg.SameSite,
+	}
+	if maxAge < 0 {
+		cookie.Expires = time.Unix(0, 0)
+		cookie.MaxAge = -1
+	} else {
+		cookie.MaxAge = int(maxAge.Seconds())
+	}
+	return cookie
+}
+
+func newCSRFToken() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// CSRF enforces the double-submit cookie pattern on state-changing requests
+// that are authenticated by cookie: the CSRF header must echo the CSRF
+// cookie. Requests carrying an Authorization header or no auth cookies are
+// not exposed to CSRF and pass through.
+func CSRF(cfg CookieConfig) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if !cfg.Enabled {
+			return c.Next()
+		}
+
+		switch c.Method() {
+		case fiber.MethodGet, fiber.MethodHead, fiber.MethodOptions:
+			return c.Next()
+		}
+
+		if c.Get("Authorization") != "" {
+			return c.Next()
+		}
+		if c.Cookies(cfg.AccessName) == "" && c.Cookies(cfg.RefreshName) == "" {
+			return c.Next()
+		}
+
+		cookie := c.Cookies(cfg.CSRFName)
+		header := c.Get(cfg.CSRFHeader)
+		if cookie == "" || subtle.ConstantTimeCompare([]byte(cookie), []byte(header)) != 1 {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "invalid csrf token",
+			})
+		}
+
+		return c.Next()
+	}
+}
@@ -0,0 +1,65 @@
+package middlewares
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/kanta/backend-challenge/infrastructure"
+	"github.com/kanta/backend-challenge/internal/core/domain"
+)
+
+const (
+	DPoPHeader      = "DPoP"
+	DPoPNonceHeader = "DPoP-Nonce"
+)
+
+// RequestURL is the URL a DPoP proof's htu claim must match.
+func RequestURL(c *fiber.Ctx) string {
+	return c.BaseURL() + c.Path()
+}
+
+// SetDPoPNonce sends a fresh nonce when the verifier requires one, so the
+// client can include it in its next proof.
+func SetDPoPNonce(c *fiber.Ctx, dpop *infrastructure.DPoPVerifier) {
+	if dpop != nil && dpop.RequiresNonce() {
+		c.Set(DPoPNonceHeader, dpop.Nonce())
+	}
+}
+
+// verifyDPoP checks the proof of possession for a DPoP-bound access token.
+// scheme is the Authorization scheme, or empty for a token read from the
+// cookie. Bound tokens must come with a proof signed by the bound key and may
+// not be sent as Bearer tokens; unbound tokens must not use the DPoP scheme.
+func verifyDPoP(c *fiber.Ctx, dpop *infrastructure.DPoPVerifier, scheme, token string, claims *domain.Claims) error {
+	if claims.Confirmation == nil || claims.Confirmation.JKT == "" {
+		if scheme == DPoPHeader {
+			return domain.ErrInvalidDPoPProof
+		}
+		return nil
+	}
+	if dpop == nil || scheme == "Bearer" {
+		return domain.ErrInvalidDPoPProof
+	}
+
+	jkt, err := dpop.Verify(c.Context(), c.Get(DPoPHeader), c.Method(), RequestURL(c), token)
+	if err != nil {
+		return err
+	}
+	if jkt != claims.Confirmation.JKT {
+		return domain.ErrInvalidDPoPProof
+	}
+	return nil
+}
+
+func dpopUnauthorized(c *fiber.Ctx, dpop *infrastructure.DPoPVerifier, err error) error {
+	code := "invalid_dpop_proof"
+	if errors.Is(err, domain.ErrUseDPoPNonce) {
+		code = "use_dpop_nonce"
+	}
+	SetDPoPNonce(c, dpop)
+	c.Set(fiber.HeaderWWWAuthenticate, fmt.Sprintf(`DPoP error="%s", algs="ES256 RS256 EdDSA"`, code))
+	return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+		"error": code,
+	})
+}
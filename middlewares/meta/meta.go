@@ -9,8 +9,11 @@ type MetaOK struct {
 }
 
 type MetaError struct {
-	Code       int    `json:"code"`
-	Message    string `json:"message"`
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	// Details carries structured information about the error, such as
+	// per-field validation failures.
+	Details    any `json:"details,omitempty"`
 	httpStatus int
 	err        error
 }
@@ -65,6 +68,7 @@ func NewMetaError(code int, message string, optionFuncs ...func(*MetaErrorOption
 	return &MetaError{
 		Code:       code,
 		Message:    message,
+		Details:    options.details,
 		httpStatus: options.httpStatus,
 	}
 }
@@ -2,6 +2,7 @@ package meta
 
 type MetaErrorOptions struct {
 	httpStatus int
+	details    any
 }
 
 func getDefaultMetaErrorOptions() MetaErrorOptions {
@@ -23,6 +24,19 @@ func WithMetaErrorOptionsHttpStatus(status int) func(*MetaErrorOptions) {
 	}
 }
 
+// WithMetaErrorOptionsDetails attaches structured details to the MetaError.
+//
+// Parameters:
+// - details: A JSON-serializable value describing the error, for example a list of field errors.
+//
+// Returns:
+// - A function that takes a pointer to MetaErrorOptions and sets its details field to the provided value.
+func WithMetaErrorOptionsDetails(details any) func(*MetaErrorOptions) {
+	return func(options *MetaErrorOptions) {
+		options.details = details
+	}
+}
+
 type MetaErrorHandlerOptions struct {
 	isLog bool
 }
@@ -0,0 +1,32 @@
+package middlewares
+
+import (
+	"crypto/x509"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/kanta/backend-challenge/infrastructure"
+	"github.com/kanta/backend-challenge/internal/core/domain"
+)
+
+// ClientCertificate returns the verified TLS client certificate of the
+// request, or nil when none was presented.
+func ClientCertificate(c *fiber.Ctx) *x509.Certificate {
+	state := c.Context().TLSConnectionState()
+	if state == nil || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return nil
+	}
+	return state.VerifiedChains[0][0]
+}
+
+// verifyCertificateBinding checks that a certificate-bound access token is
+// presented over a connection using the same client certificate.
+func verifyCertificateBinding(c *fiber.Ctx, claims *domain.Claims) error {
+	if claims.Confirmation == nil || claims.Confirmation.X5TS256 == "" {
+		return nil
+	}
+	cert := ClientCertificate(c)
+	if cert == nil || infrastructure.CertificateThumbprint(cert) != claims.Confirmation.X5TS256 {
+		return domain.ErrCertificateMismatch
+	}
+	return nil
+}
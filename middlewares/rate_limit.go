@@ -0,0 +1,94 @@
+package middlewares
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/kanta/backend-challenge/internal/core/ports"
+	"github.com/kanta/backend-challenge/middlewares/meta"
+	"go.uber.org/zap"
+)
+
+type RateLimitConfig struct {
+	// Name separates the counters of different route groups.
+	Name   string
+	Limit  int
+	Window time.Duration
+	// KeyFunc identifies the caller. Defaults to RateLimitByIP.
+	KeyFunc func(c *fiber.Ctx) string
+	// FailOpen lets requests through when the limiter backend is unavailable.
+	FailOpen bool
+}
+
+func RateLimitByIP(c *fiber.Ctx) string {
+	return "ip:" + c.IP()
+}
+
+// RateLimitByUser must run after JWTAuth. It falls back to the client IP for
+// anonymous requests.
+func RateLimitByUser(c *fiber.Ctx) string {
+	if userID, ok := c.Locals("user_id").(string); ok && userID != "" {
+		return "user:" + userID
+	}
+	return RateLimitByIP(c)
+}
+
+// RateLimitByAPIKey keys on a hash of the X-API-Key header so raw keys never
+// reach Redis. It falls back to the client IP when the header is missing.
+func RateLimitByAPIKey(c *fiber.Ctx) string {
+	apiKey := c.Get("X-API-Key")
+	if apiKey == "" {
+		return RateLimitByIP(c)
+	}
+	sum := sha256.Sum256([]byte(apiKey))
+	return "key:" + hex.EncodeToString(sum[:])
+}
+
+// RateLimitByRoute shares one budget between all callers of a route.
+func RateLimitByRoute(c *fiber.Ctx) string {
+	return "route:" + c.Method() + ":" + c.Route().Path
+}
+
+func RateLimit(limiter ports.RateLimiterPort, cfg RateLimitConfig) fiber.Handler {
+	if cfg.KeyFunc == nil {
+		cfg.KeyFunc = RateLimitByIP
+	}
+
+	return func(c *fiber.Ctx) error {
+		if cfg.Limit <= 0 || cfg.Window <= 0 {
+			return c.Next()
+		}
+
+		key := cfg.Name + ":" + cfg.KeyFunc(c)
+		result, err := limiter.Allow(c.Context(), key, cfg.Limit, cfg.Window)
+		if err != nil {
+			zap.L().Warn("rate limiter unavailable", zap.String("key", key), zap.Error(err))
+			if cfg.FailOpen {
+				return c.Next()
+			}
+			return c.Status(http.StatusServiceUnavailable).JSON(meta.NewMetaError(http.StatusServiceUnavailable, "service temporarily unavailable",
+				meta.WithMetaErrorOptionsHttpStatus(http.StatusServiceUnavailable)))
+		}
+
+		c.Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+		c.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Set("RateLimit-Reset", seconds(result.ResetAfter))
+
+		if !result.Allowed {
+			c.Set(fiber.HeaderRetryAfter, seconds(result.RetryAfter))
+			return c.Status(http.StatusTooManyRequests).JSON(meta.NewMetaError(http.StatusTooManyRequests, "too many requests",
+				meta.WithMetaErrorOptionsHttpStatus(http.StatusTooManyRequests)))
+		}
+
+		return c.Next()
+	}
+}
+
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}